@@ -0,0 +1,376 @@
+package gatews
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+// CostMethod how the cost of closed amounts is matched for realised PnL
+type CostMethod int
+
+const (
+	// CostFIFO matches the oldest open lots first
+	CostFIFO CostMethod = iota
+	// CostAverage uses the average price of the open amount
+	CostAverage
+)
+
+const (
+	FeeCurrencyPoint = "POINT"
+	FeeCurrencyGT    = "GT"
+)
+
+// AccountTrade a user trade of spot.usertrades or futures.usertrades in a common shape
+type AccountTrade struct {
+	Id      string
+	Market  string
+	Futures bool
+	Time    time.Time
+	Side    model.Side
+	// Amount is always positive, in base currency for spot and contracts for futures
+	Amount decimal.Decimal
+	Price  decimal.Decimal
+	Role   model.Role
+	// Fees by currency, negative for rebates
+	Fees map[string]decimal.Decimal
+}
+
+// DailySummary trading summary of a market in a UTC day
+type DailySummary struct {
+	Date        string                     `json:"date"`
+	Market      string                     `json:"market"`
+	Trades      int                        `json:"trades"`
+	MakerTrades int                        `json:"maker_trades"`
+	TakerTrades int                        `json:"taker_trades"`
+	Volume      decimal.Decimal            `json:"volume"`
+	MakerVolume decimal.Decimal            `json:"maker_volume"`
+	TakerVolume decimal.Decimal            `json:"taker_volume"`
+	Turnover    decimal.Decimal            `json:"turnover"`
+	RealisedPnl decimal.Decimal            `json:"realised_pnl"`
+	Fees        map[string]decimal.Decimal `json:"fees"`
+}
+
+type costLot struct {
+	amount decimal.Decimal
+	price  decimal.Decimal
+}
+
+// inventory open amount of a market, lots are all long or all short
+type inventory struct {
+	long bool
+	lots []costLot
+}
+
+type summaryKey struct {
+	date   string
+	market string
+}
+
+// Accounting aggregates realised PnL, fees and maker/taker volumes of user trades by market and day.
+// PnL of spot is in quote currency, of futures in settle currency
+type Accounting struct {
+	mu          sync.Mutex
+	method      CostMethod
+	instruments *Instruments
+	inventories map[string]*inventory
+	summaries   map[summaryKey]*DailySummary
+	seen        map[string]*recentIDs // by market
+}
+
+// NewAccounting instruments provides multipliers of contracts, contracts not found are linear with multiplier 1
+func NewAccounting(method CostMethod, instruments *Instruments) *Accounting {
+	return &Accounting{
+		method:      method,
+		instruments: instruments,
+		inventories: make(map[string]*inventory),
+		summaries:   make(map[summaryKey]*DailySummary),
+		seen:        make(map[string]*recentIDs),
+	}
+}
+
+// AddSpotTrade adds a trade of spot.usertrades
+func (a *Accounting) AddSpotTrade(trade *SpotUserTradesMsg) bool {
+	fees := make(map[string]decimal.Decimal)
+	addFee(fees, trade.FeeCurrency, trade.Fee)
+	addFee(fees, FeeCurrencyPoint, trade.PointFee)
+	addFee(fees, FeeCurrencyGT, trade.GtFee)
+	return a.Add(AccountTrade{
+		Id:     strconv.FormatUint(trade.Id, 10),
+		Market: trade.CurrencyPair,
+		Time:   tradeTime(trade.CreateTimeMs, trade.CreateTime),
+		Side:   trade.Side,
+		Amount: parseDecimal(trade.Amount).Abs(),
+		Price:  parseDecimal(trade.Price),
+		Role:   trade.Role,
+		Fees:   fees,
+	})
+}
+
+// AddFuturesTrade adds a trade of futures.usertrades, fees are in the settle currency
+func (a *Accounting) AddFuturesTrade(trade *FuturesUserTrade) bool {
+	fees := make(map[string]decimal.Decimal)
+	addFee(fees, a.settle(trade.Contract), strconv.FormatFloat(trade.Fee, 'f', -1, 64))
+	addFee(fees, FeeCurrencyPoint, strconv.FormatFloat(trade.PointFee, 'f', -1, 64))
+	return a.Add(AccountTrade{
+		Id:      trade.Id,
+		Market:  trade.Contract,
+		Futures: true,
+		Time:    tradeTime(trade.CreateTimeMs, trade.CreateTime),
+		Side:    sizeSide(trade.Size),
+		Amount:  decimal.NewFromInt(trade.Size).Abs(),
+		Price:   parseDecimal(trade.Price),
+		Role:    trade.Role,
+		Fees:    fees,
+	})
+}
+
+func addFee(fees map[string]decimal.Decimal, currency, amount string) {
+	fee := parseDecimal(amount)
+	if currency == "" || fee.IsZero() {
+		return
+	}
+	fees[currency] = fees[currency].Add(fee)
+}
+
+func (a *Accounting) contract(name string) (ContractInfo, bool) {
+	if a.instruments == nil {
+		return ContractInfo{}, false
+	}
+	return a.instruments.Contract(name)
+}
+
+// settle currency of contract, the base currency for inverse contracts and the quote currency otherwise
+func (a *Accounting) settle(contract string) string {
+	base, quote, _ := strings.Cut(contract, "_")
+	if c, ok := a.contract(contract); ok && c.inverse() {
+		return base
+	}
+	return quote
+}
+
+// pnl of amount opened at entry and closed at price, amount is negative for short
+func (a *Accounting) pnl(trade *AccountTrade, amount, entry, price decimal.Decimal) decimal.Decimal {
+	if !trade.Futures {
+		return amount.Mul(price.Sub(entry))
+	}
+	c, _ := a.contract(trade.Market)
+	size := amount.Mul(c.multiplier())
+	if c.inverse() {
+		if entry.IsZero() || price.IsZero() {
+			return decimal.Zero
+		}
+		return size.Div(entry).Sub(size.Div(price))
+	}
+	return size.Mul(price.Sub(entry))
+}
+
+// turnover of trade in quote currency for spot, in settle currency for futures
+func (a *Accounting) turnover(trade *AccountTrade) decimal.Decimal {
+	if !trade.Futures {
+		return trade.Amount.Mul(trade.Price)
+	}
+	c, _ := a.contract(trade.Market)
+	size := trade.Amount.Mul(c.multiplier())
+	if c.inverse() {
+		if trade.Price.IsZero() {
+			return decimal.Zero
+		}
+		return size.Div(trade.Price)
+	}
+	return size.Mul(trade.Price)
+}
+
+// Add adds a trade, the last trades added to a market are ignored if added again
+func (a *Accounting) Add(trade AccountTrade) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if trade.Id != "" {
+		ids, ok := a.seen[trade.Market]
+		if !ok {
+			ids = newRecentIDs(maxSeenTrades)
+			a.seen[trade.Market] = ids
+		}
+		if !ids.add(trade.Id) {
+			return false
+		}
+	}
+
+	inv, ok := a.inventories[trade.Market]
+	if !ok {
+		inv = &inventory{}
+		a.inventories[trade.Market] = inv
+	}
+	pnl := a.match(inv, &trade)
+
+	key := summaryKey{trade.Time.UTC().Format("2006-01-02"), trade.Market}
+	s, ok := a.summaries[key]
+	if !ok {
+		s = &DailySummary{Date: key.date, Market: key.market, Fees: make(map[string]decimal.Decimal)}
+		a.summaries[key] = s
+	}
+	s.Trades++
+	s.Volume = s.Volume.Add(trade.Amount)
+	s.Turnover = s.Turnover.Add(a.turnover(&trade))
+	switch trade.Role {
+	case model.RoleMaker:
+		s.MakerTrades++
+		s.MakerVolume = s.MakerVolume.Add(trade.Amount)
+	case model.RoleTaker:
+		s.TakerTrades++
+		s.TakerVolume = s.TakerVolume.Add(trade.Amount)
+	}
+	s.RealisedPnl = s.RealisedPnl.Add(pnl)
+	for currency, fee := range trade.Fees {
+		s.Fees[currency] = s.Fees[currency].Add(fee)
+	}
+	return true
+}
+
+// match closes open lots against trade and opens the rest, returns realised PnL
+func (a *Accounting) match(inv *inventory, trade *AccountTrade) decimal.Decimal {
+	buy := trade.Side == model.SideBuy
+	left := trade.Amount
+	var pnl decimal.Decimal
+
+	if len(inv.lots) > 0 && inv.long != buy {
+		sign := decimal.One
+		if !inv.long {
+			sign = sign.Neg()
+		}
+		for len(inv.lots) > 0 && left.IsPositive() {
+			lot := &inv.lots[0]
+			closed := decimal.Min(lot.amount, left)
+			pnl = pnl.Add(a.pnl(trade, closed.Mul(sign), lot.price, trade.Price))
+			lot.amount = lot.amount.Sub(closed)
+			left = left.Sub(closed)
+			if lot.amount.IsZero() {
+				inv.lots = inv.lots[1:]
+			}
+		}
+	}
+
+	if left.IsPositive() {
+		if len(inv.lots) == 0 {
+			inv.long = buy
+		}
+		inv.lots = append(inv.lots, costLot{amount: left, price: trade.Price})
+		if a.method == CostAverage && len(inv.lots) > 1 {
+			inv.lots = []costLot{averageLot(inv.lots)}
+		}
+	}
+	return pnl
+}
+
+func averageLot(lots []costLot) costLot {
+	var amount, cost decimal.Decimal
+	for _, lot := range lots {
+		amount = amount.Add(lot.amount)
+		cost = cost.Add(lot.amount.Mul(lot.price))
+	}
+	return costLot{amount: amount, price: cost.Div(amount)}
+}
+
+// OpenAmount returns the open amount of market, negative for short, and its average cost
+func (a *Accounting) OpenAmount(market string) (amount, price decimal.Decimal) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	inv, ok := a.inventories[market]
+	if !ok || len(inv.lots) == 0 {
+		return decimal.Zero, decimal.Zero
+	}
+	lot := averageLot(inv.lots)
+	if !inv.long {
+		return lot.amount.Neg(), lot.price
+	}
+	return lot.amount, lot.price
+}
+
+// Summaries returns daily summaries ordered by date and market
+func (a *Accounting) Summaries() []DailySummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	summaries := make([]DailySummary, 0, len(a.summaries))
+	for _, s := range a.summaries {
+		c := *s
+		c.Fees = make(map[string]decimal.Decimal, len(s.Fees))
+		for currency, fee := range s.Fees {
+			c.Fees[currency] = fee
+		}
+		summaries = append(summaries, c)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Date != summaries[j].Date {
+			return summaries[i].Date < summaries[j].Date
+		}
+		return summaries[i].Market < summaries[j].Market
+	})
+	return summaries
+}
+
+// WriteJSON writes daily summaries as a JSON array
+func (a *Accounting) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a.Summaries())
+}
+
+// WriteCSV writes daily summaries as CSV with a header, fees are listed as currency=amount separated by semicolons
+func (a *Accounting) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"date", "market", "trades", "maker_trades", "taker_trades", "volume", "maker_volume", "taker_volume", "turnover", "realised_pnl", "fees"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, s := range a.Summaries() {
+		currencies := make([]string, 0, len(s.Fees))
+		for currency := range s.Fees {
+			currencies = append(currencies, currency)
+		}
+		sort.Strings(currencies)
+		fees := make([]string, 0, len(currencies))
+		for _, currency := range currencies {
+			fees = append(fees, currency+"="+s.Fees[currency].String())
+		}
+
+		record := []string{
+			s.Date, s.Market,
+			strconv.Itoa(s.Trades), strconv.Itoa(s.MakerTrades), strconv.Itoa(s.TakerTrades),
+			s.Volume.String(), s.MakerVolume.String(), s.TakerVolume.String(),
+			s.Turnover.String(), s.RealisedPnl.String(), strings.Join(fees, ";"),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// CallBack returns callback to set on spot.usertrades and futures.usertrades
+func (a *Accounting) CallBack() CallBack {
+	return NewCallBack(func(msg *UpdateMsg) {
+		if msg.Event != model.EventUpdate {
+			return
+		}
+
+		switch msg.GetChannel() {
+		case ChannelSpotUserTrade:
+			decodeEach(msg.Result, func(v *SpotUserTradesMsg) { a.AddSpotTrade(v) })
+		case ChannelFutureUserTrade:
+			decodeEach(msg.Result, func(v *FuturesUserTrade) { a.AddFuturesTrade(v) })
+		}
+	})
+}
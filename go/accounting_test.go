@@ -0,0 +1,94 @@
+package gatews
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+func TestAccountingSpot(t *testing.T) {
+	trades := []SpotUserTradesMsg{
+		{Id: 1, CurrencyPair: "BTC_USDT", CreateTime: 1700000000 * 1e6, Side: "buy", Amount: "1", Price: "100", Role: "maker", Fee: "0.001", FeeCurrency: "BTC"},
+		{Id: 2, CurrencyPair: "BTC_USDT", CreateTime: 1700000001 * 1e6, Side: "buy", Amount: "1", Price: "200", Role: "taker", GtFee: "0.01"},
+		{Id: 3, CurrencyPair: "BTC_USDT", CreateTime: 1700000002 * 1e6, Side: "sell", Amount: "1.5", Price: "300", Role: "taker", Fee: "0.45", FeeCurrency: "USDT"},
+	}
+
+	for method, want := range map[CostMethod]string{CostFIFO: "250", CostAverage: "225"} {
+		acc := NewAccounting(method, nil)
+		for i := range trades {
+			acc.AddSpotTrade(&trades[i])
+		}
+		acc.AddSpotTrade(&trades[2])
+
+		summaries := acc.Summaries()
+		if len(summaries) != 1 {
+			t.Fatalf("unexpected summaries %+v", summaries)
+		}
+		s := summaries[0]
+		if s.RealisedPnl.String() != want || s.Trades != 3 || s.MakerTrades != 1 || s.TakerVolume.String() != "2.5" {
+			t.Fatalf("method %d: unexpected summary %+v", method, s)
+		}
+		if s.Fees["BTC"].String() != "0.001" || s.Fees[FeeCurrencyGT].String() != "0.01" || s.Fees["USDT"].String() != "0.45" {
+			t.Fatalf("unexpected fees %v", s.Fees)
+		}
+		if amount, _ := acc.OpenAmount("BTC_USDT"); amount.String() != "0.5" {
+			t.Fatalf("open amount = %s", amount)
+		}
+	}
+}
+
+func TestAccountingFuturesExport(t *testing.T) {
+	acc := NewAccounting(CostFIFO, nil)
+	call := acc.CallBack()
+	call(&UpdateMsg{
+		Channel: ChannelFutureUserTrade,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"id":"1","contract":"ETH_USDT","create_time_ms":1700000000000,"size":-2,"price":"2000","role":"taker","fee":0.5},{"id":"2","contract":"ETH_USDT","create_time_ms":1700000100000,"size":3,"price":"1900","role":"maker","fee":-0.1}]`),
+	})
+
+	if amount, price := acc.OpenAmount("ETH_USDT"); amount.String() != "1" || price.String() != "1900" {
+		t.Fatalf("unexpected open amount %s at %s", amount, price)
+	}
+
+	var buf bytes.Buffer
+	if err := acc.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[1] != "2023-11-14,ETH_USDT,2,1,1,5,3,2,9700,200,USDT=0.4" {
+		t.Fatalf("unexpected csv %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := acc.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var summaries []DailySummary
+	if err := json.Unmarshal(buf.Bytes(), &summaries); err != nil || len(summaries) != 1 || summaries[0].RealisedPnl.String() != "200" {
+		t.Fatalf("unexpected json %s: %v", buf.String(), err)
+	}
+}
+
+func TestAccountingFuturesTurnover(t *testing.T) {
+	instruments := NewInstruments()
+	instruments.Set(nil, []ContractInfo{
+		{Name: "BTC_USDT", QuantoMultiplier: decimal.RequireFromString("0.0001")},
+		{Name: "BTC_USD", Type: "inverse", QuantoMultiplier: decimal.NewFromInt(1)},
+	})
+	acc := NewAccounting(CostFIFO, instruments)
+	acc.AddFuturesTrade(&FuturesUserTrade{Id: "1", Contract: "BTC_USDT", Size: 100, Price: "30000", CreateTimeMs: model.TimestampFromMilli(1700000000000)})
+	acc.AddFuturesTrade(&FuturesUserTrade{Id: "1", Contract: "BTC_USD", Size: -300, Price: "30000", CreateTimeMs: model.TimestampFromMilli(1700000000000)})
+
+	// 100 * 0.0001 * 30000 USDT, 300 * 1 / 30000 BTC
+	turnovers := map[string]string{}
+	for _, s := range acc.Summaries() {
+		turnovers[s.Market] = s.Turnover.String()
+	}
+	if turnovers["BTC_USDT"] != "300" || turnovers["BTC_USD"] != "0.01" {
+		t.Fatalf("unexpected turnovers %v", turnovers)
+	}
+}
@@ -1,16 +1,18 @@
 package gatews
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"github.com/gateio/gatews/go/model"
 )
 
 type SubscribeOptions struct {
@@ -66,7 +68,7 @@ func (ws *WsService) newBaseChannel(channel string, payload any, bch chan *Updat
 }
 
 func (ws *WsService) baseSubscribe(event, channel string, payload any, op *SubscribeOptions) error {
-	ts := time.Now().Unix()
+	ts := ws.clock().Now().Unix()
 	hash := hmac.New(sha512.New, []byte(ws.conf.Secret))
 	hash.Write([]byte(fmt.Sprintf("channel=%s&event=%s&time=%d", channel, Subscribe, ts)))
 	req := Request{
@@ -87,15 +89,17 @@ func (ws *WsService) baseSubscribe(event, channel string, payload any, op *Subsc
 
 	byteReq, err := json.Marshal(req)
 	if err != nil {
-		ws.Logger.Printf("req Marshal err:%s", err.Error())
+		ws.log().Error("marshal request failed", "channel", channel, "err", err)
 		return err
 	}
-	ws.mu.Lock()
-	defer ws.mu.Unlock()
 
-	err = ws.Client.WriteMessage(websocket.TextMessage, byteReq)
+	priority := PrioritySubscribe
+	if strings.HasSuffix(channel, "ping") {
+		priority = PriorityPing
+	}
+	err = ws.write(priority, byteReq)
 	if err != nil {
-		ws.Logger.Printf("wsWrite [%s] err:%s", channel, err.Error())
+		ws.log().Error("write failed", "channel", channel, "err", err)
 		return err
 	}
 
@@ -103,6 +107,9 @@ func (ws *WsService) baseSubscribe(event, channel string, payload any, op *Subsc
 		return nil
 	}
 
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
 	if v, ok := ws.conf.subscribeMsg.Load(channel); ok {
 		if op != nil && op.IsReConnect {
 			return nil
@@ -134,35 +141,67 @@ func (ws *WsService) baseSubscribe(event, channel string, payload any, op *Subsc
 func (ws *WsService) readMsg() {
 	ws.once.Do(func() {
 		go func() {
-			defer ws.Client.Close()
+			defer func() { ws.GetConn().Close() }()
 
 			for {
 				select {
 				case <-ws.Ctx.Done():
-					ws.Logger.Printf("closing reader")
+					ws.log().Debug("closing reader", "url", ws.conf.URL)
 					return
 
 				default:
-					_, rawMsg, err := ws.Client.ReadMessage()
+					_, rawMsg, err := ws.GetConn().ReadMessage()
+					received := time.Now()
 					if err != nil {
-						ws.Logger.Printf("websocket err: %s", err.Error())
+						if ws.Ctx.Err() != nil {
+							return
+						}
+						ws.log().Warn("read failed, reconnecting", "url", ws.conf.URL, "err", err)
+						ws.endOutstandingRequests(errConnectionLost)
+						ws.onDisconnect()
 						if e := ws.reconnect(); e != nil {
-							ws.Logger.Printf("reconnect err:%s", err.Error())
+							if ws.Ctx.Err() != nil {
+								return
+							}
+							ws.log().Error("reconnect failed", "url", ws.conf.URL, "err", e)
+							if ws.conf.CancelOnDisconnect {
+								ws.cancelAllOnce("reconnect failed")
+							}
 							return
 						}
-						ws.Logger.Println("reconnect success, continue read message")
+						ws.log().Info("reconnected, continue reading", "url", ws.conf.URL)
 						continue
 					}
 
+					if ws.conf.Recorder != nil {
+						ws.conf.Recorder.Record(FrameIn, received, rawMsg)
+					}
+
 					var msg UpdateMsg
 					if err := json.Unmarshal(rawMsg, &msg); err != nil {
+						ws.metrics().DecodeError("")
 						continue
 					}
+					msg.ReceivedAt = received
 
 					channel := msg.GetChannel()
 					if channel == "" {
-						ws.Logger.Printf("channel is empty in message %v", msg)
-						return
+						ws.log().Warn("channel is empty in message", "message", string(rawMsg))
+						ws.metrics().DecodeError("")
+						continue
+					}
+
+					ws.metrics().MessageReceived(channel, len(rawMsg))
+					ws.observeAPIResponse(&msg)
+					if msg.RequestId != "" {
+						ws.log().Debug("api response", "channel", channel, "req_id", msg.RequestId, "latency", msg.Latency(received))
+					}
+					ws.trackClientOrders(&msg)
+					if ws.conf.RateLimiter != nil {
+						ws.conf.RateLimiter.observe(&msg)
+					}
+					if ws.conf.Latency != nil {
+						ws.conf.Latency.observePong(&msg)
 					}
 
 					if bch, ok := ws.msgChs.Load(channel); ok {
@@ -200,20 +239,46 @@ func (ws *WsService) receiveCallMsg(channel string, msgCh chan *UpdateMsg) {
 	for {
 		select {
 		case <-ws.Ctx.Done():
-			ws.Logger.Printf("received parent context exit")
+			ws.log().Debug("context done, stop receiving", "channel", channel)
 			return
 		case msg := <-msgCh:
 			if call, ok := ws.calls.Load(channel); ok {
+				start := time.Now()
+				if ws.conf.Latency != nil {
+					ws.conf.Latency.ObserveMessage(msg, start)
+				}
 				call.(CallBack)(msg)
+				ws.metrics().CallbackDuration(channel, time.Since(start))
 			}
 		}
 	}
 }
 
+// APIRequest sends payload to the api channel, the response is pushed to the callback of channel.
+// Orders without text get a generated client order id if ClientOrderIDs is set.
+// Payloads with a Validate method, e.g. model.Order, are validated before sending unless SkipValidation is set.
+// Requests are limited by RateLimiter if set
 func (ws *WsService) APIRequest(channel string, payload any, keyVals map[string]any) error {
+	return ws.APIRequestContext(context.Background(), channel, payload, keyVals)
+}
+
+// APIRequestContext is APIRequest with ctx as the parent of its trace, see ConfOptions.Tracer
+func (ws *WsService) APIRequestContext(ctx context.Context, channel string, payload any, keyVals map[string]any) error {
+	if !ws.conf.SkipValidation {
+		if err := validatePayload(channel, payload); err != nil {
+			return err
+		}
+		if ws.conf.Instruments != nil {
+			if err := ws.conf.Instruments.validatePayload(channel, payload); err != nil {
+				return err
+			}
+		}
+	}
+
 	var err error
 	ws.loginOnce.Do(func() {
-		err = ws.login()
+		err = ws.login(ctx)
+		ws.tracer().Event(ctx, EventLogin, err)
 	})
 
 	if err != nil {
@@ -236,10 +301,20 @@ func (ws *WsService) APIRequest(channel string, payload any, keyVals map[string]
 
 	ws.readMsg()
 
-	return ws.apiRequest(channel, payload, keyVals)
+	if err := ws.waitRateLimit(ctx, channel); err != nil {
+		return err
+	}
+
+	payload, keyVals = ws.attachClientOrderIDs(channel, payload, keyVals)
+	if err := ws.apiRequest(ctx, channel, payload, keyVals); err != nil {
+		ws.rejectClientOrders(keyVals, err)
+		return err
+	}
+	ws.trackMarkets(channel, payload)
+	return nil
 }
 
-func (ws *WsService) login() error {
+func (ws *WsService) login(ctx context.Context) error {
 	if ws.conf.Key == "" || ws.conf.Secret == "" {
 		return newAuthEmptyErr()
 	}
@@ -259,26 +334,34 @@ func (ws *WsService) login() error {
 
 	ws.readMsg()
 
-	return ws.apiRequest(channel, nil, nil)
+	return ws.apiRequest(ctx, channel, nil, nil)
 }
 
-func (ws *WsService) apiRequest(channel string, payload any, keyVals map[string]any) error {
+func (ws *WsService) apiRequest(ctx context.Context, channel string, payload any, keyVals map[string]any) error {
+	apiReq := ws.generateAPIRequest(channel, payload, keyVals)
 	req := Request{
-		Time:    time.Now().Unix(),
+		Time:    ws.clock().Now().Unix(),
 		Channel: channel,
 		Event:   API,
-		Payload: ws.generateAPIRequest(channel, payload, keyVals),
+		Payload: apiReq,
 	}
 
 	byteReq, err := json.Marshal(req)
 	if err != nil {
-		ws.Logger.Printf("req Marshal err:%s", err.Error())
+		ws.log().Error("marshal request failed", "channel", channel, "err", err)
 		return err
 	}
-	ws.mu.Lock()
-	defer ws.mu.Unlock()
-
-	return ws.Client.WriteMessage(websocket.TextMessage, byteReq)
+	reqID := ""
+	if r, ok := apiReq.(APIReq); ok {
+		reqID = r.ReqId
+	}
+	span := ws.tracer().StartRequest(ctx, channel, reqID)
+	if err := ws.write(channelPriority(channel), byteReq); err != nil {
+		span.End(nil, err)
+		return err
+	}
+	ws.observeAPIRequest(channel, reqID, span)
+	return nil
 }
 
 func (ws *WsService) generateAPIRequest(channel string, placeParam any, keyVals map[string]any) any {
@@ -293,7 +376,7 @@ func (ws *WsService) generateAPIRequest(channel string, placeParam any, keyVals
 		gateChannelID, _ = v.(string)
 	}
 
-	now := time.Now().Unix()
+	now := ws.clock().Now().Unix()
 
 	reqParam, _ := json.Marshal(placeParam)
 
@@ -309,6 +392,68 @@ func (ws *WsService) generateAPIRequest(channel string, placeParam any, keyVals
 	}
 }
 
+type validator interface {
+	Validate() error
+}
+
+// validatePayload validates payload, or each element of it for batch channels
+func validatePayload(channel string, payload any) error {
+	check := func(v any) error {
+		if v, ok := v.(validator); ok {
+			return v.Validate()
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(payload)
+	if rv.Kind() == reflect.Slice {
+		if channel != ChannelSpotOrderPlace && channel != ChannelFutureOrderBatchPlace {
+			return nil
+		}
+		return validateElems(rv, check)
+	}
+	return check(pointerTo(rv))
+}
+
+// pointerTo returns a pointer to a copy of rv if it's not a pointer, so that methods with pointer receivers can be called
+func pointerTo(rv reflect.Value) any {
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Kind() == reflect.Ptr {
+		return rv.Interface()
+	}
+	ptr := reflect.New(rv.Type())
+	ptr.Elem().Set(rv)
+	return ptr.Interface()
+}
+
+// validateElems calls check on each element of slice, and prefixes fields of model.ValidationErrors with the index
+func validateElems(slice reflect.Value, check func(any) error) error {
+	var errs model.ValidationErrors
+	for i := 0; i < slice.Len(); i++ {
+		err := check(pointerTo(slice.Index(i)))
+		if err == nil {
+			continue
+		}
+		fieldErrs, ok := err.(model.ValidationErrors)
+		if !ok {
+			return err
+		}
+		for _, fe := range fieldErrs {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("[%d].%s", i, fe.Field), Reason: fe.Reason})
+		}
+	}
+	return validationErr(errs)
+}
+
+func validationErr(errs model.ValidationErrors) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
 func calculateSignature(secret string, message string) string {
 	h := hmac.New(sha512.New, []byte(secret))
 	h.Write([]byte(message))
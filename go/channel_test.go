@@ -1,110 +1,188 @@
 package gatews
 
 import (
-	"fmt"
-	"log"
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/gateio/gatews/go/gatewstest"
+	"github.com/gateio/gatews/go/model"
 )
 
 func TestNilCallBack(t *testing.T) {
-	ws, err := NewWsService(nil, nil, nil)
-	if err != nil {
-		log.Fatal(err)
-	}
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	ws := newTestService(t, srv, nil)
 
 	ws.SetCallBack(ChannelSpotPublicTrade, nil)
 	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BCH_USDT"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("Subscribe err:%s", err.Error())
 	}
+	if !srv.WaitSubscribed(ChannelSpotPublicTrade, testTimeout) {
+		t.Fatal("not subscribed")
+	}
+	srv.Push(ChannelSpotPublicTrade, map[string]any{"id": 1, "currency_pair": "BCH_USDT"})
 
-	ch := make(chan bool)
-	defer close(ch)
-
-	for {
-		select {
-		case <-ch:
-			log.Printf("manual done")
-		case <-time.After(time.Second * 1000):
-			log.Printf("auto done")
-			return
-		}
+	// updates without a callback are dropped, the connection keeps working
+	if len(ws.GetChannels()) != 0 {
+		t.Fatal("nil callback is set")
+	}
+	if err := ws.Subscribe(ChannelSpotCandleStick, []string{"10s", "BCH_USDT"}); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.WaitSubscribed(ChannelSpotCandleStick, testTimeout) {
+		t.Fatal("not subscribed after dropped update")
 	}
 }
 
 func TestSubscribeFutures(t *testing.T) {
-	ws, err := NewWsService(nil, nil, NewConnConfFromOption(&ConfOptions{
-		URL: FuturesUsdtUrl, Key: "", Secret: "", MaxRetryConn: 10,
-	}))
-	if err != nil {
-		log.Fatal(err)
-	}
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	ws := newTestService(t, srv, &ConfOptions{MaxRetryConn: 10})
 
+	updates := make(chan *UpdateMsg, 1)
 	call := NewCallBack(func(msg *UpdateMsg) {
-		fmt.Println(string(msg.Result))
+		if msg.Event == "update" {
+			updates <- msg
+		}
 	})
 	ws.SetCallBack(ChannelFutureCandleStick, call)
 	if err := ws.Subscribe(ChannelFutureCandleStick, []string{"1m", "BTC_USDT"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("Subscribe err:%s", err.Error())
 	}
+	if !srv.WaitSubscribed(ChannelFutureCandleStick, testTimeout) {
+		t.Fatal("not subscribed")
+	}
+	srv.Push(ChannelFutureCandleStick, []map[string]any{{"t": 1700000000, "n": "1m_BTC_USDT", "o": "30000", "c": "30010"}})
 
-	ch := make(chan bool)
-	defer close(ch)
-
-	for {
-		select {
-		case <-ch:
-			log.Printf("manual done")
-		case <-time.After(time.Second * 1000):
-			log.Printf("auto done")
-			return
+	select {
+	case msg := <-updates:
+		if msg.Channel != ChannelFutureCandleStick || len(msg.Result) == 0 || msg.ReceivedAt.IsZero() {
+			t.Fatalf("unexpected update %+v", msg)
 		}
+	case <-time.After(testTimeout):
+		t.Fatal("no update")
 	}
 }
 
 func TestSubscribeFuturesWithOptions(t *testing.T) {
-	ws, err := NewWsService(nil, nil, NewConnConfFromOption(&ConfOptions{
-		URL: FuturesUsdtUrl,
-	}))
-	if err != nil {
-		log.Fatal(err)
-	}
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	ws := newTestService(t, srv, nil)
 
+	acks := make(chan *UpdateMsg, 1)
 	call := NewCallBack(func(msg *UpdateMsg) {
-		fmt.Printf("%+v\n", msg)
+		if msg.Event == "subscribe" {
+			acks <- msg
+		}
 	})
 	ws.SetCallBack(ChannelFutureTrade, call)
 	if err := ws.SubscribeWithOption(ChannelFutureTrade, []string{"BTC_USDT"}, &SubscribeOptions{
 		ID: 123456,
 	}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("Subscribe err:%s", err.Error())
+	}
+
+	f, ok := srv.WaitFrame(testTimeout, func(f gatewstest.Frame) bool { return f.Channel == ChannelFutureTrade })
+	if !ok || f.Id == nil || *f.Id != 123456 {
+		t.Fatalf("unexpected frame %+v", f)
+	}
+	select {
+	case msg := <-acks:
+		if msg.Id == nil || *msg.Id != 123456 || msg.Error != nil {
+			t.Fatalf("unexpected ack %+v", msg)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("no ack")
+	}
+}
+
+func TestSubscribeAuthChannel(t *testing.T) {
+	srv := gatewstest.NewServer("KEY", "SECRET")
+	defer srv.Close()
+
+	ws := newTestService(t, srv, nil)
+	if err := ws.Subscribe(ChannelSpotOrder, []string{"BCH_USDT"}); err == nil {
+		t.Fatal("subscribed auth channel without key")
 	}
 
-	ch := make(chan bool)
-	defer close(ch)
+	for _, secret := range []string{"SECRET", "WRONG"} {
+		ws := newTestService(t, srv, &ConfOptions{Key: "KEY", Secret: secret})
+		acks := make(chan *UpdateMsg, 1)
+		ws.SetCallBack(ChannelSpotOrder, NewCallBack(func(msg *UpdateMsg) { acks <- msg }))
+		if err := ws.Subscribe(ChannelSpotOrder, []string{"BCH_USDT"}); err != nil {
+			t.Fatalf("Subscribe err:%s", err.Error())
+		}
 
-	for {
 		select {
-		case <-ch:
-			log.Printf("manual done")
-		case <-time.After(time.Second * 1000):
-			log.Printf("auto done")
-			return
+		case msg := <-acks:
+			if failed := msg.Error != nil; failed != (secret == "WRONG") {
+				t.Fatalf("secret %s: unexpected ack %+v", secret, msg.Error)
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("no ack")
 		}
 	}
 }
 
-func TestSubscribeAuthChannel(t *testing.T) {
-	ws, err := NewWsService(nil, nil, nil)
-	if err != nil {
-		log.Fatal(err)
+func TestAPIRequestMockServer(t *testing.T) {
+	srv := gatewstest.NewServer("KEY", "SECRET")
+	defer srv.Close()
+	srv.Handle(ChannelSpotOrderPlace, func(req gatewstest.APIRequest) gatewstest.APIResult {
+		return gatewstest.APIResult{Ack: true, Result: map[string]any{"id": "1", "text": "t-1"}}
+	})
+	ws := newTestService(t, srv, &ConfOptions{Key: "KEY", Secret: "SECRET", App: "spot"})
+
+	responses := make(chan *UpdateMsg, 4)
+	ws.SetCallBack(ChannelSpotLogin, NewCallBack(func(msg *UpdateMsg) { responses <- msg }))
+	ws.SetCallBack(ChannelSpotOrderPlace, NewCallBack(func(msg *UpdateMsg) { responses <- msg }))
+	order := &model.Order{CurrencyPair: "BTC_USDT", Side: model.SideBuy, Type: "limit", Price: "30000", Amount: "0.001"}
+	if err := ws.APIRequest(ChannelSpotOrderPlace, order, map[string]any{"req_id": "r1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case msg := <-responses:
+			if msg.Data.Errs != nil {
+				t.Fatalf("%s failed: %s", msg.Header.Channel, msg.Data.Errs.Message)
+			}
+			kind := msg.Header.Channel
+			if msg.Ack {
+				kind += " ack"
+			}
+			got = append(got, kind)
+		case <-time.After(testTimeout):
+			t.Fatalf("responses %v", got)
+		}
+	}
+	// callbacks of different channels run concurrently, only the order within a channel is kept
+	place := make([]string, 0, 2)
+	for _, kind := range got {
+		if kind != ChannelSpotLogin {
+			place = append(place, kind)
+		}
+	}
+	if len(place) != 2 || place[0] != ChannelSpotOrderPlace+" ack" || place[1] != ChannelSpotOrderPlace {
+		t.Fatalf("responses %v", got)
+	}
+}
+
+func TestValidatePayload(t *testing.T) {
+	order := model.FuturesOrder{Contract: "BTC_USDT", Size: 1, Price: "30000"}
+	if err := validatePayload(ChannelFutureOrderPlace, &order); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := []model.FuturesOrder{order, {Contract: "BTC_USDT", Price: "30000"}}
+	var errs model.ValidationErrors
+	if err := validatePayload(ChannelFutureOrderBatchPlace, batch); !errors.As(err, &errs) || !errs.Has("[1].size") {
+		t.Fatalf("expected error of [1].size, got %v", err)
 	}
 
-	if err := ws.Subscribe(ChannelSpotOrder, []string{"BCH_USDT"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+	// orders with id only to cancel
+	if err := validatePayload(ChannelSpotOrderCancelIds, []*model.Order{{Id: "1", CurrencyPair: "BTC_USDT"}}); err != nil {
+		t.Fatal(err)
 	}
 }
@@ -2,18 +2,18 @@ package gatews
 
 import (
 	"context"
-	"crypto/tls"
 	"log"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/gorilla/websocket"
 )
 
-type status int
+type status int32
 
 const (
 	disconnected status = iota
@@ -23,9 +23,10 @@ const (
 
 type WsService struct {
 	mu        *sync.Mutex
-	Logger    *log.Logger
+	Logger    *log.Logger // used if ConnConf.Logger is nil
 	Ctx       context.Context
-	Client    *websocket.Conn
+	Client    *websocket.Conn // nil if not dialed by gorilla/websocket, use GetConn
+	conn      atomic.Value    // activeConn, replaced on reconnect
 	once      *sync.Once
 	loginOnce *sync.Once
 	msgChs    *sync.Map // business chan
@@ -33,6 +34,12 @@ type WsService struct {
 	conf      *ConnConf
 	status    status
 	clientMu  *sync.Mutex
+
+	clientOrders *clientOrderBook
+	writes       *writeQueue
+	safety       *safetyState
+	apiRequests  *apiRequests
+	cancel       context.CancelFunc
 }
 
 // ConnConf default URL is spot websocket
@@ -46,6 +53,40 @@ type ConnConf struct {
 	SkipTlsVerify    bool
 	ShowReconnectMsg bool
 	PingInterval     string
+	// SkipValidation disables validating payloads of APIRequest before sending
+	SkipValidation bool
+	// Instruments validates orders against precision and limits of markets if set
+	Instruments *Instruments
+	// ClientOrderIDs generates text of orders placed without text if set, the orders are tracked by text
+	// and their status are queried if no response in OrderAckTimeout
+	ClientOrderIDs  *ClientOrderIDGenerator
+	OrderAckTimeout time.Duration
+	// RateLimiter limits requests of api channels if set, APIRequest waits for the limit
+	// or fails with RateLimitError if RateLimitFailFast
+	RateLimiter       *RateLimiter
+	RateLimitFailFast bool
+	// WriteTimeout deadline of writing a message, DefaultWriteTimeout if 0
+	WriteTimeout time.Duration
+	// CancelOnDisconnect cancels all orders of markets placed in by this connection when it is closed,
+	// or lost and not restored in CancelOnDisconnectGrace. Results are reported to OnCancelAll
+	CancelOnDisconnect        bool
+	CancelOnDisconnectGrace   time.Duration
+	CancelOnDisconnectTimeout time.Duration
+	OnCancelAll               func(CancelAllReport)
+	// Logger structured logger, e.g. *slog.Logger, the *log.Logger of NewWsService is used if nil
+	Logger Logger
+	// Metrics receives measurements of messages, callbacks, reconnects and api requests if set
+	Metrics Metrics
+	// Tracer traces api requests, login and reconnects if set
+	Tracer Tracer
+	// Latency tracks latency of messages with callbacks and the clock offset from pings if set
+	Latency *LatencyTracker
+	// Recorder records messages read and written if set, with api keys and signatures redacted, flushed by Close
+	Recorder *Recorder
+	// Transport dials connections, GorillaTransport if nil
+	Transport Transport
+	// Clock drives signing, reconnect backoff, pings and timeouts, the real clock if nil
+	Clock Clock
 }
 
 type ConfOptions struct {
@@ -57,6 +98,40 @@ type ConfOptions struct {
 	SkipTlsVerify    bool
 	ShowReconnectMsg bool
 	PingInterval     string
+	// SkipValidation disables validating payloads of APIRequest before sending
+	SkipValidation bool
+	// Instruments validates orders against precision and limits of markets if set
+	Instruments *Instruments
+	// ClientOrderIDs generates text of orders placed without text if set, the orders are tracked by text
+	// and their status are queried if no response in OrderAckTimeout
+	ClientOrderIDs  *ClientOrderIDGenerator
+	OrderAckTimeout time.Duration
+	// RateLimiter limits requests of api channels if set, APIRequest waits for the limit
+	// or fails with RateLimitError if RateLimitFailFast
+	RateLimiter       *RateLimiter
+	RateLimitFailFast bool
+	// WriteTimeout deadline of writing a message, DefaultWriteTimeout if 0
+	WriteTimeout time.Duration
+	// CancelOnDisconnect cancels all orders of markets placed in by this connection when it is closed,
+	// or lost and not restored in CancelOnDisconnectGrace. Results are reported to OnCancelAll
+	CancelOnDisconnect        bool
+	CancelOnDisconnectGrace   time.Duration
+	CancelOnDisconnectTimeout time.Duration
+	OnCancelAll               func(CancelAllReport)
+	// Logger structured logger, e.g. *slog.Logger, the *log.Logger of NewWsService is used if nil
+	Logger Logger
+	// Metrics receives measurements of messages, callbacks, reconnects and api requests if set
+	Metrics Metrics
+	// Tracer traces api requests, login and reconnects if set
+	Tracer Tracer
+	// Latency tracks latency of messages with callbacks and the clock offset from pings if set
+	Latency *LatencyTracker
+	// Recorder records messages read and written if set, with api keys and signatures redacted, flushed by Close
+	Recorder *Recorder
+	// Transport dials connections, GorillaTransport if nil
+	Transport Transport
+	// Clock drives signing, reconnect backoff, pings and timeouts, the real clock if nil
+	Clock Clock
 }
 
 func NewWsService(ctx context.Context, logger *log.Logger, conf *ConnConf) (*WsService, error) {
@@ -73,24 +148,30 @@ func NewWsService(ctx context.Context, logger *log.Logger, conf *ConnConf) (*WsS
 	} else {
 		conf = defaultConf
 	}
+	lg := conf.Logger
+	if lg == nil {
+		lg = NewStdLogger(logger)
+	}
+	if conf.RateLimiter != nil && conf.Clock != nil {
+		conf.RateLimiter.SetClock(conf.Clock)
+	}
 
 	stop := false
 	retry := 0
-	var conn *websocket.Conn
+	var conn Conn
+	transport := conf.transport()
 	for !stop {
-		dialer := websocket.DefaultDialer
-		if conf.SkipTlsVerify {
-			dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-		}
-		c, _, err := dialer.Dial(conf.URL, nil)
+		c, err := transport.Dial(ctx, conf.URL)
 		if err != nil {
 			if retry >= conf.MaxRetryConn {
-				log.Printf("max reconnect time %d reached, give it up", conf.MaxRetryConn)
+				lg.Error("max connect attempts reached, give it up", "url", conf.URL, "attempt", retry)
 				return nil, err
 			}
 			retry++
-			log.Printf("failed to connect to server for the %d time, try again later", retry)
-			time.Sleep(time.Millisecond * (time.Duration(retry) * 500))
+			lg.Warn("failed to connect to server, try again later", "url", conf.URL, "attempt", retry, "err", err)
+			if err := sleep(ctx, conf.clock(), time.Millisecond*(time.Duration(retry)*500)); err != nil {
+				return nil, err
+			}
 			continue
 		} else {
 			stop = true
@@ -99,23 +180,32 @@ func NewWsService(ctx context.Context, logger *log.Logger, conf *ConnConf) (*WsS
 	}
 
 	if retry > 0 {
-		log.Printf("reconnect succeeded after retrying %d times", retry)
+		lg.Info("connected after retrying", "url", conf.URL, "attempt", retry)
 	}
 
+	// canceled by Close
+	ctx, cancel := context.WithCancel(ctx)
 	ws := &WsService{
 		mu:        new(sync.Mutex),
 		conf:      conf,
 		Logger:    logger,
 		Ctx:       ctx,
-		Client:    conn,
 		calls:     new(sync.Map),
 		msgChs:    new(sync.Map),
 		once:      new(sync.Once),
 		loginOnce: new(sync.Once),
 		status:    connected,
 		clientMu:  new(sync.Mutex),
+
+		clientOrders: newClientOrderBook(),
+		writes:       newWriteQueue(),
+		safety:       newSafetyState(),
+		apiRequests:  newAPIRequests(),
+		cancel:       cancel,
 	}
+	ws.setConn(conn)
 
+	go ws.writeLoop()
 	go ws.activePing()
 
 	return ws, nil
@@ -164,15 +254,34 @@ func NewConnConfFromOption(op *ConfOptions) *ConnConf {
 		op.MaxRetryConn = MaxRetryConn
 	}
 	return &ConnConf{
-		App:              op.App,
-		subscribeMsg:     new(sync.Map),
-		MaxRetryConn:     op.MaxRetryConn,
-		Key:              op.Key,
-		Secret:           op.Secret,
-		URL:              op.URL,
-		SkipTlsVerify:    op.SkipTlsVerify,
-		ShowReconnectMsg: op.ShowReconnectMsg,
-		PingInterval:     op.PingInterval,
+		App:               op.App,
+		subscribeMsg:      new(sync.Map),
+		MaxRetryConn:      op.MaxRetryConn,
+		Key:               op.Key,
+		Secret:            op.Secret,
+		URL:               op.URL,
+		SkipTlsVerify:     op.SkipTlsVerify,
+		ShowReconnectMsg:  op.ShowReconnectMsg,
+		PingInterval:      op.PingInterval,
+		SkipValidation:    op.SkipValidation,
+		Instruments:       op.Instruments,
+		ClientOrderIDs:    op.ClientOrderIDs,
+		OrderAckTimeout:   op.OrderAckTimeout,
+		RateLimiter:       op.RateLimiter,
+		RateLimitFailFast: op.RateLimitFailFast,
+		WriteTimeout:      op.WriteTimeout,
+
+		CancelOnDisconnect:        op.CancelOnDisconnect,
+		CancelOnDisconnectGrace:   op.CancelOnDisconnectGrace,
+		CancelOnDisconnectTimeout: op.CancelOnDisconnectTimeout,
+		OnCancelAll:               op.OnCancelAll,
+		Logger:                    op.Logger,
+		Metrics:                   op.Metrics,
+		Tracer:                    op.Tracer,
+		Latency:                   op.Latency,
+		Recorder:                  op.Recorder,
+		Transport:                 op.Transport,
+		Clock:                     op.Clock,
 	}
 }
 
@@ -182,39 +291,47 @@ func (ws *WsService) GetConnConf() *ConnConf {
 
 func (ws *WsService) reconnect() error {
 	// avoid repeated reconnection
-	if ws.status == reconnecting {
+	if ws.getStatus() == reconnecting {
 		return nil
 	}
 
 	ws.clientMu.Lock()
 	defer ws.clientMu.Unlock()
 
-	if ws.Client != nil {
-		ws.Client.Close()
+	if conn := ws.GetConn(); conn != nil {
+		conn.Close()
 	}
 
-	ws.status = reconnecting
+	ws.setStatus(reconnecting)
 
 	stop := false
 	retry := 0
+	transport := ws.conf.transport()
 	for !stop {
-		c, _, err := websocket.DefaultDialer.Dial(ws.conf.URL, nil)
+		c, err := transport.Dial(ws.Ctx, ws.conf.URL)
 		if err != nil {
 			if retry >= ws.conf.MaxRetryConn {
-				ws.Logger.Printf("max reconnect time %d reached, give it up", ws.conf.MaxRetryConn)
+				ws.log().Error("max reconnect attempts reached, give it up", "url", ws.conf.URL, "attempt", retry)
+				ws.tracer().Event(ws.Ctx, EventReconnect, err)
 				return err
 			}
 			retry++
-			log.Printf("failed to connect to server for the %d time, try again later", retry)
-			time.Sleep(time.Millisecond * (time.Duration(retry) * 500))
+			ws.log().Warn("failed to connect to server, try again later", "url", ws.conf.URL, "attempt", retry, "err", err)
+			if err := sleep(ws.Ctx, ws.clock(), time.Millisecond*(time.Duration(retry)*500)); err != nil {
+				ws.tracer().Event(ws.Ctx, EventReconnect, err)
+				return err
+			}
 			continue
 		} else {
 			stop = true
-			ws.Client = c
+			ws.setConn(c)
 		}
 	}
 
-	ws.status = connected
+	ws.setStatus(connected)
+	ws.safety.reconnected()
+	ws.metrics().Reconnect()
+	ws.tracer().Event(ws.Ctx, EventReconnect, nil)
 
 	// resubscribe after reconnect
 	ws.conf.subscribeMsg.Range(func(key, value interface{}) bool {
@@ -229,10 +346,11 @@ func (ws *WsService) reconnect() error {
 					req.op.IsReConnect = true
 				}
 				if err := ws.baseSubscribe(req.Event, req.Channel, req.Payload, req.op); err != nil {
-					ws.Logger.Printf("after reconnect, subscribe channel[%s] err:%s", key.(string), err.Error())
+					ws.log().Error("resubscribe after reconnect failed", "channel", key.(string), "err", err)
+					ws.metrics().ResubscribeFailure(key.(string))
 				} else {
 					if ws.conf.ShowReconnectMsg {
-						ws.Logger.Printf("reconnect channel[%s] with payload[%v] success", key.(string), req.Payload)
+						ws.log().Info("resubscribed after reconnect", "channel", key.(string), "payload", req.Payload)
 					}
 				}
 			}
@@ -309,28 +427,31 @@ func (ws *WsService) GetChannels() []string {
 	return channels
 }
 
+// GetConnection returns the connection if dialed by gorilla/websocket.
+// Deprecated: use GetConn
 func (ws *WsService) GetConnection() *websocket.Conn {
-	return ws.Client
+	active, _ := ws.conn.Load().(activeConn)
+	return active.client
 }
 
 func (ws *WsService) activePing() {
 	du, err := time.ParseDuration(ws.conf.PingInterval)
 	if err != nil {
-		ws.Logger.Printf("failed to parse ping interval: %s, use default ping interval 10s instead", ws.conf.PingInterval)
+		ws.log().Warn("failed to parse ping interval, use default ping interval 10s instead", "interval", ws.conf.PingInterval, "err", err)
 		du, err = time.ParseDuration(DefaultPingInterval)
 		if err != nil {
 			du = time.Second * 10
 		}
 	}
 
-	ticker := time.NewTicker(du)
+	ticker := ws.clock().NewTicker(du)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ws.Ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			subscribeMap := map[string]int{}
 			ws.conf.subscribeMsg.Range(func(key, value interface{}) bool {
 				splits := strings.Split(key.(string), ".")
@@ -340,14 +461,17 @@ func (ws *WsService) activePing() {
 				return true
 			})
 
-			if ws.status != connected {
+			if ws.getStatus() != connected {
 				continue
 			}
 
 			for app := range subscribeMap {
 				channel := app + ".ping"
+				if ws.conf.Latency != nil {
+					ws.conf.Latency.pingSent(app, time.Now())
+				}
 				if err := ws.Subscribe(channel, nil); err != nil {
-					ws.Logger.Printf("subscribe channel[%s] failed: %v", channel, err)
+					ws.log().Warn("ping failed", "channel", channel, "err", err)
 				}
 			}
 		}
@@ -361,5 +485,14 @@ var statusString = map[status]string{
 }
 
 func (ws *WsService) Status() string {
-	return statusString[ws.status]
+	return statusString[ws.getStatus()]
+}
+
+// getStatus status is replaced by reconnect while read by other goroutines
+func (ws *WsService) getStatus() status {
+	return status(atomic.LoadInt32((*int32)(&ws.status)))
+}
+
+func (ws *WsService) setStatus(s status) {
+	atomic.StoreInt32((*int32)(&ws.status), int32(s))
 }
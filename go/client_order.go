@@ -0,0 +1,414 @@
+package gatews
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gateio/gatews/go/model"
+)
+
+const (
+	// MaxClientOrderTagLength max length of the strategy tag of client order ids
+	MaxClientOrderTagLength = 8
+
+	DefaultOrderAckTimeout = 5 * time.Second
+
+	// max times to query status of an order without response before giving up
+	maxClientOrderQueries = 3
+	// finished client orders are forgotten after this duration
+	clientOrderRetention = 24 * time.Hour
+
+	clientOrderTimeWidth = 9 // base36 milliseconds, enough until 5188
+	clientOrderSeqWidth  = 3
+	clientOrderInstWidth = 4
+)
+
+// ClientOrderIDGenerator generates user defined order texts like t-<tag>.<time><seq><instance>,
+// which are monotonic within a generator, and unique across generators by a random instance part
+type ClientOrderIDGenerator struct {
+	mu       sync.Mutex
+	tag      string
+	instance string
+	lastMs   int64
+	seq      int64
+	now      func() time.Time
+}
+
+// NewClientOrderIDGenerator tag identifies the strategy, up to 8 characters of 0-9, A-Z, a-z, underscore(_) or hyphen(-)
+func NewClientOrderIDGenerator(tag string) (*ClientOrderIDGenerator, error) {
+	if len(tag) > MaxClientOrderTagLength {
+		return nil, fmt.Errorf("client order tag %q is longer than %d", tag, MaxClientOrderTagLength)
+	}
+	if strings.Contains(tag, ".") {
+		return nil, fmt.Errorf("client order tag %q must not contain dot", tag)
+	}
+	if err := model.ValidateText("t-" + tag + ".0"); err != nil {
+		return nil, fmt.Errorf("invalid client order tag %q: %w", tag, err)
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(36*36*36*36))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClientOrderIDGenerator{
+		tag:      tag,
+		instance: padBase36(n.Int64(), clientOrderInstWidth),
+		now:      time.Now,
+	}, nil
+}
+
+// Next returns a new client order id
+func (g *ClientOrderIDGenerator) Next() string {
+	return g.next(g.now())
+}
+
+// next returns a new client order id generated at now, WsService passes the time of its Clock
+func (g *ClientOrderIDGenerator) next(now time.Time) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := now.UnixMilli()
+	if ms > g.lastMs {
+		g.lastMs, g.seq = ms, 0
+	} else {
+		g.seq++
+		// borrow the next millisecond if the sequence overflows
+		if g.seq >= 36*36*36 {
+			g.lastMs, g.seq = g.lastMs+1, 0
+		}
+	}
+
+	return "t-" + g.tag + "." + padBase36(g.lastMs, clientOrderTimeWidth) + padBase36(g.seq, clientOrderSeqWidth) + g.instance
+}
+
+// ParseClientOrderID returns the tag and time of an id generated by ClientOrderIDGenerator
+func ParseClientOrderID(text string) (tag string, t time.Time, ok bool) {
+	if !strings.HasPrefix(text, "t-") {
+		return "", time.Time{}, false
+	}
+	i := strings.LastIndexByte(text, '.')
+	if i < 2 || len(text)-i-1 != clientOrderTimeWidth+clientOrderSeqWidth+clientOrderInstWidth {
+		return "", time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(text[i+1:i+1+clientOrderTimeWidth], 36, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return text[2:i], time.UnixMilli(ms), true
+}
+
+func padBase36(n int64, width int) string {
+	s := strconv.FormatInt(n, 36)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
+
+type ClientOrderState int
+
+const (
+	// ClientOrderPending sent and waiting for the response
+	ClientOrderPending ClientOrderState = iota
+	// ClientOrderAccepted accepted by the exchange, OrderId is set
+	ClientOrderAccepted
+	// ClientOrderRejected the exchange responded with an error
+	ClientOrderRejected
+	// ClientOrderUnknown no response even after querying status by text
+	ClientOrderUnknown
+)
+
+var clientOrderStateString = map[ClientOrderState]string{
+	ClientOrderPending:  "pending",
+	ClientOrderAccepted: "accepted",
+	ClientOrderRejected: "rejected",
+	ClientOrderUnknown:  "unknown",
+}
+
+func (s ClientOrderState) String() string {
+	return clientOrderStateString[s]
+}
+
+// ClientOrder an order placed with a client order id
+type ClientOrder struct {
+	Text    string
+	Channel string
+	Market  string
+	// OrderId exchange order id, set once accepted
+	OrderId string
+	State   ClientOrderState
+	// Err label and message of the error response if rejected
+	Err     string
+	SentAt  time.Time
+	Queries int
+
+	reqID       string
+	lastQueryAt time.Time
+}
+
+type clientOrderBook struct {
+	mu     sync.Mutex
+	orders map[string]*ClientOrder
+	once   sync.Once
+}
+
+func newClientOrderBook() *clientOrderBook {
+	return &clientOrderBook{orders: make(map[string]*ClientOrder)}
+}
+
+// attachClientOrderIDs sets generated texts on orders without text in payload of order placing channels,
+// and tracks them. It returns the payload to send, orders passed by pointer get their texts set,
+// value orders and slices of them are copied
+func (ws *WsService) attachClientOrderIDs(channel string, payload any, keyVals map[string]any) (any, map[string]any) {
+	gen := ws.conf.ClientOrderIDs
+	if gen == nil || (channel != ChannelSpotOrderPlace && channel != ChannelFutureOrderPlace && channel != ChannelFutureOrderBatchPlace) {
+		return payload, keyVals
+	}
+
+	var orders []*ClientOrder
+	attach := func(text *string, market string) {
+		if *text == "" {
+			*text = gen.next(ws.clock().Now())
+		}
+		orders = append(orders, &ClientOrder{Text: *text, Channel: channel, Market: market})
+	}
+
+	switch p := payload.(type) {
+	case *model.Order:
+		attach(&p.Text, p.CurrencyPair)
+	case model.Order:
+		attach(&p.Text, p.CurrencyPair)
+		payload = &p
+	case *model.FuturesOrder:
+		attach(&p.Text, p.Contract)
+	case model.FuturesOrder:
+		attach(&p.Text, p.Contract)
+		payload = &p
+	case []*model.Order:
+		for _, o := range p {
+			attach(&o.Text, o.CurrencyPair)
+		}
+	case []model.Order:
+		p = append([]model.Order(nil), p...)
+		for i := range p {
+			attach(&p[i].Text, p[i].CurrencyPair)
+		}
+		payload = p
+	case []*model.FuturesOrder:
+		for _, o := range p {
+			attach(&o.Text, o.Contract)
+		}
+	case []model.FuturesOrder:
+		p = append([]model.FuturesOrder(nil), p...)
+		for i := range p {
+			attach(&p[i].Text, p[i].Contract)
+		}
+		payload = p
+	}
+	if len(orders) == 0 {
+		return payload, keyVals
+	}
+
+	// the text of the first order is used as req_id to match the response
+	reqID, _ := keyVals["req_id"].(string)
+	if reqID == "" {
+		reqID = orders[0].Text
+		kv := make(map[string]any, len(keyVals)+1)
+		for k, v := range keyVals {
+			kv[k] = v
+		}
+		kv["req_id"] = reqID
+		keyVals = kv
+	}
+
+	now := ws.clock().Now()
+	ws.clientOrders.mu.Lock()
+	for _, o := range orders {
+		o.reqID, o.SentAt, o.lastQueryAt = reqID, now, now
+		ws.clientOrders.orders[o.Text] = o
+	}
+	ws.clientOrders.mu.Unlock()
+
+	ws.clientOrders.once.Do(func() {
+		go ws.watchClientOrders()
+	})
+
+	return payload, keyVals
+}
+
+// rejectClientOrders marks orders of a request failed to send as rejected
+func (ws *WsService) rejectClientOrders(keyVals map[string]any, err error) {
+	reqID, _ := keyVals["req_id"].(string)
+	if reqID == "" {
+		return
+	}
+
+	ws.clientOrders.mu.Lock()
+	defer ws.clientOrders.mu.Unlock()
+
+	for _, o := range ws.clientOrders.orders {
+		if o.reqID == reqID && o.State == ClientOrderPending {
+			o.State = ClientOrderRejected
+			o.Err = err.Error()
+		}
+	}
+}
+
+// ClientOrder returns the order placed with text as client order id
+func (ws *WsService) ClientOrder(text string) (ClientOrder, bool) {
+	ws.clientOrders.mu.Lock()
+	defer ws.clientOrders.mu.Unlock()
+
+	o, ok := ws.clientOrders.orders[text]
+	if !ok {
+		return ClientOrder{}, false
+	}
+	return *o, true
+}
+
+// ExchangeOrderID returns the exchange order id of the order placed with text as client order id
+func (ws *WsService) ExchangeOrderID(text string) (string, bool) {
+	o, ok := ws.ClientOrder(text)
+	if !ok || o.OrderId == "" {
+		return "", false
+	}
+	return o.OrderId, true
+}
+
+// ForgetClientOrder stops tracking the order placed with text
+func (ws *WsService) ForgetClientOrder(text string) {
+	ws.clientOrders.mu.Lock()
+	defer ws.clientOrders.mu.Unlock()
+
+	delete(ws.clientOrders.orders, text)
+}
+
+type apiOrderResult struct {
+	Id        json.RawMessage `json:"id"`
+	Text      string          `json:"text"`
+	Succeeded *bool           `json:"succeeded"`
+	Label     string          `json:"label"`
+	Message   string          `json:"message"`
+}
+
+// trackClientOrders updates tracked orders by responses of order placing and status channels
+func (ws *WsService) trackClientOrders(msg *UpdateMsg) {
+	channel := msg.GetChannel()
+	switch channel {
+	case ChannelSpotOrderPlace, ChannelFutureOrderPlace, ChannelFutureOrderBatchPlace,
+		ChannelSpotOrderStatus, ChannelFutureOrderStatus:
+	default:
+		return
+	}
+	if ws.conf.ClientOrderIDs == nil || msg.Ack {
+		return
+	}
+
+	book := ws.clientOrders
+	book.mu.Lock()
+	defer book.mu.Unlock()
+
+	if msg.Data.Errs != nil {
+		// a status query failing doesn't mean the order is rejected
+		if channel == ChannelSpotOrderStatus || channel == ChannelFutureOrderStatus {
+			return
+		}
+		for _, o := range book.orders {
+			if o.reqID == msg.RequestId && o.State == ClientOrderPending {
+				o.State = ClientOrderRejected
+				o.Err = msg.Data.Errs.Label + ": " + msg.Data.Errs.Message
+			}
+		}
+		return
+	}
+
+	var results []apiOrderResult
+	if err := json.Unmarshal(msg.Data.Result, &results); err != nil {
+		var result apiOrderResult
+		if err := json.Unmarshal(msg.Data.Result, &result); err != nil {
+			return
+		}
+		results = append(results, result)
+	}
+
+	for _, r := range results {
+		o, ok := book.orders[r.Text]
+		if !ok {
+			continue
+		}
+		if r.Succeeded != nil && !*r.Succeeded {
+			o.State = ClientOrderRejected
+			o.Err = r.Label + ": " + r.Message
+			continue
+		}
+		if id := strings.Trim(string(r.Id), `"`); id != "" && id != "null" {
+			o.OrderId = id
+			o.State = ClientOrderAccepted
+		}
+	}
+}
+
+// watchClientOrders queries status by text of orders without response after OrderAckTimeout,
+// instead of placing them again
+func (ws *WsService) watchClientOrders() {
+	timeout := ws.conf.OrderAckTimeout
+	if timeout <= 0 {
+		timeout = DefaultOrderAckTimeout
+	}
+
+	interval := timeout / 5
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	ticker := ws.clock().NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ws.Ctx.Done():
+			return
+		case now := <-ticker.C():
+			var queries []ClientOrder
+			ws.clientOrders.mu.Lock()
+			for text, o := range ws.clientOrders.orders {
+				if o.State != ClientOrderPending {
+					if now.Sub(o.SentAt) > clientOrderRetention {
+						delete(ws.clientOrders.orders, text)
+					}
+					continue
+				}
+				if now.Sub(o.lastQueryAt) < timeout {
+					continue
+				}
+				if o.Queries >= maxClientOrderQueries {
+					o.State = ClientOrderUnknown
+					continue
+				}
+				o.Queries++
+				o.lastQueryAt = now
+				queries = append(queries, *o)
+			}
+			ws.clientOrders.mu.Unlock()
+
+			for _, o := range queries {
+				if err := ws.queryClientOrder(o); err != nil {
+					ws.log().Warn("query order status failed", "channel", o.Channel, "text", o.Text, "err", err)
+				}
+			}
+		}
+	}
+}
+
+func (ws *WsService) queryClientOrder(o ClientOrder) error {
+	if o.Channel == ChannelSpotOrderPlace {
+		return ws.APIRequest(ChannelSpotOrderStatus, &model.StatusOrderParam{OrderId: o.Text, CurrencyPair: o.Market}, nil)
+	}
+	return ws.APIRequest(ChannelFutureOrderStatus, &model.StatusFuturesOrder{OrderId: o.Text}, nil)
+}
@@ -0,0 +1,105 @@
+package gatews
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/model"
+)
+
+func TestClientOrderIDGenerator(t *testing.T) {
+	gen, err := NewClientOrderIDGenerator("grid-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.UnixMilli(1700000000000)
+	gen.now = func() time.Time { return now }
+
+	prev := ""
+	for i := 0; i < 100; i++ {
+		id := gen.Next()
+		if err := model.ValidateText(id); err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		if id <= prev {
+			t.Fatalf("%s is not greater than %s", id, prev)
+		}
+		prev = id
+	}
+
+	tag, ts, ok := ParseClientOrderID(prev)
+	if !ok || tag != "grid-1" || !ts.Equal(now) {
+		t.Fatalf("unexpected %s %v %v", tag, ts, ok)
+	}
+
+	for _, tag := range []string{"too-long-tag", "a.b", "a b"} {
+		if _, err := NewClientOrderIDGenerator(tag); err == nil {
+			t.Errorf("%q: expected error", tag)
+		}
+	}
+}
+
+func TestAttachClientOrderIDsCopiesValues(t *testing.T) {
+	gen, _ := NewClientOrderIDGenerator("t1")
+	now := time.UnixMilli(1700000000000)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ws := &WsService{Ctx: ctx, conf: &ConnConf{ClientOrderIDs: gen, Clock: NewFakeClock(now)}, clientOrders: newClientOrderBook()}
+
+	orders := []model.Order{{CurrencyPair: "BTC_USDT"}, {CurrencyPair: "ETH_USDT"}}
+	payload, _ := ws.attachClientOrderIDs(ChannelSpotOrderPlace, orders, nil)
+	sent := payload.([]model.Order)
+	if orders[0].Text != "" || orders[1].Text != "" {
+		t.Fatalf("texts set on the orders of the caller: %+v", orders)
+	}
+	if _, ts, ok := ParseClientOrderID(sent[1].Text); !ok || !ts.Equal(now) || sent[0].Text == sent[1].Text {
+		t.Fatalf("texts %q %q not generated at %s", sent[0].Text, sent[1].Text, now)
+	}
+}
+
+func TestTrackClientOrders(t *testing.T) {
+	gen, _ := NewClientOrderIDGenerator("t1")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ws := &WsService{
+		Ctx:          ctx,
+		Logger:       log.New(os.Stdout, "", 0),
+		conf:         &ConnConf{ClientOrderIDs: gen},
+		clientOrders: newClientOrderBook(),
+	}
+
+	orders := []*model.FuturesOrder{
+		{Contract: "BTC_USDT", Size: 1, Price: "30000"},
+		{Contract: "BTC_USDT", Size: 1, Price: "30000", Text: "t-mine"},
+	}
+	_, keyVals := ws.attachClientOrderIDs(ChannelFutureOrderBatchPlace, orders, nil)
+	if orders[0].Text == "" || orders[1].Text != "t-mine" || keyVals["req_id"] != orders[0].Text {
+		t.Fatalf("unexpected texts %q %q, keyVals %v", orders[0].Text, orders[1].Text, keyVals)
+	}
+
+	ws.trackClientOrders(&UpdateMsg{
+		RequestId: orders[0].Text,
+		Header:    ResponseHeader{Channel: ChannelFutureOrderBatchPlace},
+		Data: struct {
+			Result json.RawMessage `json:"result"`
+			Errs   *struct {
+				Label   string `json:"label"`
+				Message string `json:"message"`
+			} `json:"errs"`
+		}{
+			Result: json.RawMessage(`[{"id":123,"text":"` + orders[0].Text + `","succeeded":true},{"text":"t-mine","succeeded":false,"label":"INVALID_PARAM_VALUE","message":"bad"}]`),
+		},
+	})
+
+	if id, ok := ws.ExchangeOrderID(orders[0].Text); !ok || id != "123" {
+		t.Fatalf("unexpected exchange order id %q", id)
+	}
+	if o, _ := ws.ClientOrder("t-mine"); o.State != ClientOrderRejected || o.Err == "" {
+		t.Fatalf("unexpected order %+v", o)
+	}
+}
@@ -1,141 +1,235 @@
 package gatews
 
 import (
-	"fmt"
-	"log"
-	"os"
-	"os/signal"
-	"syscall"
+	"context"
+	"errors"
+	"sort"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/gatewstest"
 )
 
-func TestGetChannelMarkets(t *testing.T) {
-	ws, err := NewWsService(nil, nil, nil)
+const testTimeout = 5 * time.Second
+
+// newTestService connects to srv, closed when the test ends
+func newTestService(t *testing.T, srv *gatewstest.Server, op *ConfOptions) *WsService {
+	t.Helper()
+	if op == nil {
+		op = &ConfOptions{}
+	}
+	op.URL = srv.URL
+	if op.MaxRetryConn == 0 {
+		op.MaxRetryConn = 1
+	}
+	ws, err := NewWsService(nil, nil, NewConnConfFromOption(op))
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func TestGetChannelMarkets(t *testing.T) {
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	ws := newTestService(t, srv, nil)
 
 	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BCH_USDT"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("Subscribe err:%s", err.Error())
 	}
 	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BTC_USDT"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("Subscribe err:%s", err.Error())
 	}
 	if err := ws.Subscribe(ChannelSpotOrderBookUpdate, []string{"BTC_USDT", "100ms"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("Subscribe err:%s", err.Error())
 	}
 	if err := ws.Subscribe(ChannelSpotOrderBookUpdate, []string{"ETH_USDT", "100ms"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("Subscribe err:%s", err.Error())
 	}
-	fmt.Println(ChannelSpotPublicTrade, " subscribed markets: ", ws.GetChannelMarkets(ChannelSpotPublicTrade))
-	fmt.Println(ChannelSpotOrderBookUpdate, " subscribed markets: ", ws.GetChannelMarkets(ChannelSpotOrderBookUpdate))
+	assertMarkets(t, ws.GetChannelMarkets(ChannelSpotPublicTrade), "BCH_USDT", "BTC_USDT")
+	assertMarkets(t, ws.GetChannelMarkets(ChannelSpotOrderBookUpdate), "BTC_USDT", "ETH_USDT")
 
 	if err := ws.UnSubscribe(ChannelSpotPublicTrade, []string{"BTC_USDT"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("UnSubscribe err:%s", err.Error())
 	}
 	if err := ws.UnSubscribe(ChannelSpotOrderBookUpdate, []string{"BTC_USDT", "100ms"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("UnSubscribe err:%s", err.Error())
+	}
+	assertMarkets(t, ws.GetChannelMarkets(ChannelSpotPublicTrade), "BCH_USDT")
+	assertMarkets(t, ws.GetChannelMarkets(ChannelSpotOrderBookUpdate), "ETH_USDT")
+
+	f, ok := srv.WaitFrame(testTimeout, func(f gatewstest.Frame) bool { return f.Event == UnSubscribe })
+	if !ok || f.Channel != ChannelSpotPublicTrade || f.Strings()[0] != "BTC_USDT" {
+		t.Fatalf("unexpected unsubscribe frame %+v", f)
 	}
-	fmt.Println("after unsubscribe")
-	fmt.Println(ChannelSpotPublicTrade, " subscribed markets: ", ws.GetChannelMarkets(ChannelSpotPublicTrade))
-	fmt.Println(ChannelSpotOrderBookUpdate, " subscribed markets: ", ws.GetChannelMarkets(ChannelSpotOrderBookUpdate))
 }
 
-func TestGetChannels(t *testing.T) {
-	ws, err := NewWsService(nil, nil, nil)
-	if err != nil {
-		log.Fatal(err)
+func assertMarkets(t *testing.T, markets []string, expected ...string) {
+	t.Helper()
+	sort.Strings(markets)
+	if len(markets) != len(expected) {
+		t.Fatalf("markets %v, expected %v", markets, expected)
+	}
+	for i := range markets {
+		if markets[i] != expected[i] {
+			t.Fatalf("markets %v, expected %v", markets, expected)
+		}
 	}
+}
+
+func TestGetChannels(t *testing.T) {
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	ws := newTestService(t, srv, nil)
 
 	call := NewCallBack(func(msg *UpdateMsg) {})
 	ws.SetCallBack(ChannelSpotPublicTrade, call)
 	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BCH_USDT"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("Subscribe err:%s", err.Error())
 	}
 	if err := ws.Subscribe(ChannelSpotCandleStick, []string{"BTC_USDT", "10ms"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+		t.Fatalf("Subscribe err:%s", err.Error())
 	}
 
-	fmt.Println(ws.GetChannels())
+	if channels := ws.GetChannels(); len(channels) != 1 || channels[0] != ChannelSpotPublicTrade {
+		t.Fatalf("channels with callbacks %v", channels)
+	}
 }
 
 func TestGetConf(t *testing.T) {
-	ws, err := NewWsService(nil, nil, NewConnConfFromOption(&ConfOptions{
-		URL: "", Key: "KEY", Secret: "SECRET", MaxRetryConn: 10,
-	}))
-	if err != nil {
-		log.Fatal(err)
-	}
+	srv := gatewstest.NewServer("KEY", "SECRET")
+	defer srv.Close()
+	ws := newTestService(t, srv, &ConfOptions{Key: "KEY", Secret: "SECRET", MaxRetryConn: 10})
 
-	call := NewCallBack(func(msg *UpdateMsg) {})
-	ws.SetCallBack(ChannelSpotPublicTrade, call)
-	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BCH_USDT"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+	ws.SetKey("KEY2")
+	ws.SetSecret("SECRET2")
+	ws.SetMaxRetryConn(20)
+	if ws.GetKey() != "KEY2" || ws.GetSecret() != "SECRET2" || ws.GetMaxRetryConn() != 20 {
+		t.Fatalf("unexpected conf %s %s %d", ws.GetKey(), ws.GetSecret(), ws.GetMaxRetryConn())
 	}
-
-	fmt.Println(ws.GetKey())
-	fmt.Println(ws.GetSecret())
-	fmt.Println(ws.GetMaxRetryConn())
 }
 
 func TestGetConfFromOption(t *testing.T) {
-	ws, err := NewWsService(nil, nil, NewConnConfFromOption(&ConfOptions{
-		URL: "", Key: "KEY", Secret: "SECRET", MaxRetryConn: 10,
-	}))
-	if err != nil {
-		log.Fatal(err)
-	}
+	srv := gatewstest.NewServer("KEY", "SECRET")
+	defer srv.Close()
+	ws := newTestService(t, srv, &ConfOptions{Key: "KEY", Secret: "SECRET", MaxRetryConn: 10})
 
-	call := NewCallBack(func(msg *UpdateMsg) {})
-	ws.SetCallBack(ChannelSpotPublicTrade, call)
-	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BCH_USDT"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
+	if ws.GetKey() != "KEY" || ws.GetSecret() != "SECRET" || ws.GetMaxRetryConn() != 10 {
+		t.Fatalf("unexpected conf %s %s %d", ws.GetKey(), ws.GetSecret(), ws.GetMaxRetryConn())
+	}
+	if conf := ws.GetConnConf(); conf.URL != srv.URL {
+		t.Fatalf("url %s", conf.URL)
 	}
-	fmt.Println(ws.GetKey())
-	fmt.Println(ws.GetSecret())
-	fmt.Println(ws.GetMaxRetryConn())
 }
 
 func TestMultiClients(t *testing.T) {
-	for i := 0; i < 100; i++ {
-		go connWs()
-	}
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+
+	const clients = 100
+	var received sync.WaitGroup
+	received.Add(clients)
+	for i := 0; i < clients; i++ {
+		ws := newTestService(t, srv, &ConfOptions{MaxRetryConn: 10, ShowReconnectMsg: true})
+		var once sync.Once
+		ws.SetCallBack(ChannelSpotBookTicker, NewCallBack(func(msg *UpdateMsg) {
+			if msg.Event == "update" {
+				once.Do(received.Done)
+			}
+		}))
+		if err := ws.Subscribe(ChannelSpotBookTicker, []string{"BTC_USDT"}); err != nil {
+			t.Fatalf("Subscribe err:%s", err.Error())
+		}
+	}
+
+	deadline := time.Now().Add(testTimeout)
+	for len(srv.Frames()) < clients {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d clients subscribed", len(srv.Frames()), clients)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := srv.Push(ChannelSpotBookTicker, map[string]any{"s": "BTC_USDT", "b": "30000", "a": "30001"}); n != clients {
+		t.Fatalf("pushed to %d clients", n)
+	}
+	waitGroup(t, &received)
+}
 
-	ch := make(chan os.Signal)
-	signal.Ignore(syscall.SIGPIPE, syscall.SIGALRM)
-	signal.Notify(ch, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT, syscall.SIGABRT, syscall.SIGKILL)
-	<-ch
+func waitGroup(t *testing.T, wg *sync.WaitGroup) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		t.Fatal("timeout")
+	}
 }
 
-func connWs() {
-	ws, err := NewWsService(nil, nil, NewConnConfFromOption(&ConfOptions{
-		URL: "", MaxRetryConn: 10, ShowReconnectMsg: true,
+func TestReconnectResubscribes(t *testing.T) {
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	ws := newTestService(t, srv, &ConfOptions{MaxRetryConn: 10})
+
+	updates := make(chan *UpdateMsg, 10)
+	ws.SetCallBack(ChannelSpotPublicTrade, NewCallBack(func(msg *UpdateMsg) {
+		if msg.Event == "update" {
+			updates <- msg
+		}
 	}))
-	if err != nil {
-		log.Fatal(err)
+	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BTC_USDT"}); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.WaitSubscribed(ChannelSpotPublicTrade, testTimeout) {
+		t.Fatal("not subscribed")
+	}
+
+	srv.Disconnect()
+	// the second subscribe frame is sent after reconnecting
+	deadline := time.Now().Add(testTimeout)
+	for {
+		n := 0
+		for _, f := range srv.Frames() {
+			if f.Channel == ChannelSpotPublicTrade {
+				n++
+			}
+		}
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("not resubscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	srv.Push(ChannelSpotPublicTrade, map[string]any{"id": 1, "currency_pair": "BTC_USDT"})
+	select {
+	case <-updates:
+	case <-time.After(testTimeout):
+		t.Fatal("no update after reconnect")
+	}
+	if ws.Status() != "connected" {
+		t.Fatalf("status %s", ws.Status())
 	}
+}
 
-	call := NewCallBack(func(msg *UpdateMsg) {
-		// fmt.Println(string(msg.Result))
-	})
-	ws.SetCallBack(ChannelSpotBookTicker, call)
-	if err := ws.Subscribe(ChannelSpotBookTicker, []string{"BTC_USDT"}); err != nil {
-		log.Fatalf("Subscribe err:%s", err.Error())
-		return
-	}
+func TestConnectCanceled(t *testing.T) {
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	srv.RejectConnections(true)
 
-	ch := make(chan os.Signal)
-	signal.Ignore(syscall.SIGPIPE, syscall.SIGALRM)
-	signal.Notify(ch, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT, syscall.SIGABRT, syscall.SIGKILL)
-	<-ch
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// retried without limit until ctx is done
+	_, err := NewWsService(ctx, nil, NewConnConfFromOption(&ConfOptions{URL: srv.URL}))
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > testTimeout {
+		t.Fatalf("err %v after %s", err, time.Since(start))
+	}
 }
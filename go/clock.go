@@ -0,0 +1,217 @@
+package gatews
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock source of time for signing requests, reconnect backoff, pings, client order ack timeouts,
+// rate limits and the cancel on disconnect grace, set by ConfOptions.Clock. Latency and durations of messages
+// are always measured by the real clock
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer fires once on C after its duration unless stopped
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Ticker fires on C every period until stopped, ticks are dropped for slow receivers
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type realClock struct{}
+
+type realTimer struct{ *time.Timer }
+
+type realTicker struct{ *time.Ticker }
+
+func (realClock) Now() time.Time                   { return time.Now() }
+func (realClock) NewTimer(d time.Duration) Timer   { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+func (t realTimer) C() <-chan time.Time            { return t.Timer.C }
+func (t realTicker) C() <-chan time.Time           { return t.Ticker.C }
+
+func (conf *ConnConf) clock() Clock {
+	if conf.Clock != nil {
+		return conf.Clock
+	}
+	return realClock{}
+}
+
+func (ws *WsService) clock() Clock {
+	return ws.conf.clock()
+}
+
+// sleep blocks for d by clock, or returns the error of ctx once done
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	t := clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C():
+		return nil
+	}
+}
+
+// FakeClock Clock advanced manually, e.g. in tests
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// closed and replaced when a timer or ticker is created
+	changed chan struct{}
+}
+
+// NewFakeClock starts at now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, changed: make(chan struct{})}
+}
+
+type fakeTimer struct {
+	clock  *FakeClock
+	c      chan time.Time
+	at     time.Time
+	period time.Duration // 0 for timers
+}
+
+type fakeTicker struct{ *fakeTimer }
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	return c.add(d, 0)
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return fakeTicker{c.add(d, d)}
+}
+
+func (c *FakeClock) add(d, period time.Duration) *fakeTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1), at: c.now.Add(d), period: period}
+	c.timers = append(c.timers, t)
+	close(c.changed)
+	c.changed = make(chan struct{})
+	if d <= 0 && period == 0 {
+		c.fire()
+	}
+	return t
+}
+
+// Advance moves the clock forward by d, firing timers and tickers due in order
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		next := c.next()
+		if next == nil || next.at.After(end) {
+			break
+		}
+		c.now = next.at
+		c.fire()
+	}
+	c.now = end
+}
+
+// next returns the timer due first
+func (c *FakeClock) next() *fakeTimer {
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+	if len(c.timers) == 0 {
+		return nil
+	}
+	return c.timers[0]
+}
+
+// fire fires timers due at now, rescheduling tickers
+func (c *FakeClock) fire() {
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		if t.period > 0 {
+			for !t.at.After(c.now) {
+				t.at = t.at.Add(t.period)
+			}
+			pending = append(pending, t)
+		}
+	}
+	c.timers = pending
+}
+
+// Timers returns the number of active timers and tickers
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// WaitTimers blocks until at least n timers and tickers are active or timeout, reports whether they are
+func (c *FakeClock) WaitTimers(n int, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		c.mu.Lock()
+		active, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+		if active >= n {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
+
+// Stop reports whether the timer is stopped before firing
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, active := range c.timers {
+		if active == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package gatews
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/gatewstest"
+	"github.com/gateio/gatews/go/model"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	c := NewFakeClock(start)
+	timer := c.NewTimer(time.Second)
+	ticker := c.NewTicker(400 * time.Millisecond)
+	stopped := c.NewTimer(time.Second)
+	if !stopped.Stop() || c.Timers() != 2 {
+		t.Fatalf("%d timers", c.Timers())
+	}
+
+	c.Advance(999 * time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	case at := <-ticker.C():
+		// ticks at 400ms and 800ms are coalesced
+		if !at.Equal(start.Add(400 * time.Millisecond)) {
+			t.Fatalf("tick at %s", at)
+		}
+	}
+	c.Advance(time.Millisecond)
+	if at := <-timer.C(); !at.Equal(start.Add(time.Second)) || !c.Now().Equal(at) {
+		t.Fatalf("timer fired at %s", at)
+	}
+	if timer.Stop() {
+		t.Fatal("fired timer stopped")
+	}
+	ticker.Stop()
+	if c.Timers() != 0 {
+		t.Fatalf("%d timers", c.Timers())
+	}
+}
+
+func TestClockSignsAndPings(t *testing.T) {
+	srv := gatewstest.NewServer("KEY", "SECRET")
+	defer srv.Close()
+	// far from the real time, the server checks signatures by the fake clock
+	now := time.Unix(1700000000, 0)
+	clock := NewFakeClock(now)
+	srv.SetClock(clock.Now)
+	ws := newTestService(t, srv, &ConfOptions{Key: "KEY", Secret: "SECRET", PingInterval: "10s", Clock: clock})
+
+	if err := ws.Subscribe(ChannelSpotBalance, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.WaitSubscribed(ChannelSpotBalance, testTimeout) {
+		t.Fatal("not subscribed")
+	}
+	f := srv.Frames()[0]
+	sign := calculateSignature("SECRET", fmt.Sprintf("channel=%s&event=subscribe&time=%d", ChannelSpotBalance, now.Unix()))
+	if f.Time != now.Unix() || f.Auth.Sign != sign {
+		t.Fatalf("frame %+v", f)
+	}
+
+	// the ping ticker
+	if !clock.WaitTimers(1, testTimeout) {
+		t.Fatal("ping ticker not started")
+	}
+	clock.Advance(10 * time.Second)
+	if _, ok := srv.WaitFrame(testTimeout, func(f gatewstest.Frame) bool { return f.Channel == "spot.ping" }); !ok {
+		t.Fatal("no ping")
+	}
+}
+
+func TestClockPlacesOrders(t *testing.T) {
+	srv := gatewstest.NewServer("KEY", "SECRET")
+	defer srv.Close()
+	engine := gatewstest.NewEngine(srv)
+	engine.SetBalance("USDT", decimal.NewFromInt(1000))
+	now := time.Unix(1700000000, 0)
+	clock := NewFakeClock(now)
+	srv.SetClock(clock.Now)
+	ws := newTestService(t, srv, &ConfOptions{Key: "KEY", Secret: "SECRET", Clock: clock})
+
+	placed := make(chan *UpdateMsg, 1)
+	ws.SetCallBack(ChannelSpotOrderPlace, NewCallBack(func(msg *UpdateMsg) {
+		if !msg.Ack {
+			placed <- msg
+		}
+	}))
+	order := model.Order{CurrencyPair: "BTC_USDT", Side: model.SideBuy, Amount: "1", Price: "100"}
+	if err := ws.APIRequest(ChannelSpotOrderPlace, order, nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-placed:
+		var result struct {
+			CreateTimeMs string `json:"create_time_ms"`
+		}
+		if msg.Data.Errs != nil {
+			t.Fatalf("place failed: %s", msg.Data.Errs.Message)
+		}
+		// stamped by the clock of the server
+		if err := json.Unmarshal(msg.Data.Result, &result); err != nil || result.CreateTimeMs != fmt.Sprint(now.UnixMilli()) {
+			t.Fatalf("placed %s: %v", msg.Data.Result, err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("order not placed")
+	}
+}
@@ -0,0 +1,393 @@
+// Command gatews subscribes to a channel of the Gate websocket API and prints its updates.
+//
+//	gatews [flags] channel [payload...]
+//
+// channel is a channel name like spot.tickers, or the name of its constant like ChannelSpotTicker,
+// payload are the arguments of the subscription, e.g. markets. Private channels are authenticated by
+// the GATE_API_KEY and GATE_API_SECRET environment variables. Updates are written to stdout,
+// connection status and reconnects to stderr.
+//
+//	gatews spot.tickers BTC_USDT ETH_USDT
+//	gatews -json -market BTC_USDT futures.trades BTC_USDT ETH_USDT
+//	GATE_API_KEY=... GATE_API_SECRET=... gatews ChannelFutureOrder 10001 '!all'
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	gate "github.com/gateio/gatews/go"
+)
+
+// channels subscribable channels by the names of their constants
+var channels = map[string]string{
+	"ChannelSpotBalance":            gate.ChannelSpotBalance,
+	"ChannelSpotCandleStick":        gate.ChannelSpotCandleStick,
+	"ChannelSpotOrder":              gate.ChannelSpotOrder,
+	"ChannelSpotOrderBook":          gate.ChannelSpotOrderBook,
+	"ChannelSpotBookTicker":         gate.ChannelSpotBookTicker,
+	"ChannelSpotOrderBookUpdate":    gate.ChannelSpotOrderBookUpdate,
+	"ChannelSpotTicker":             gate.ChannelSpotTicker,
+	"ChannelSpotUserTrade":          gate.ChannelSpotUserTrade,
+	"ChannelSpotPublicTrade":        gate.ChannelSpotPublicTrade,
+	"ChannelSpotFundingBalance":     gate.ChannelSpotFundingBalance,
+	"ChannelSpotMarginBalance":      gate.ChannelSpotMarginBalance,
+	"ChannelSpotCrossBalance":       gate.ChannelSpotCrossBalance,
+	"ChannelFutureTicker":           gate.ChannelFutureTicker,
+	"ChannelFutureTrade":            gate.ChannelFutureTrade,
+	"ChannelFutureOrderBook":        gate.ChannelFutureOrderBook,
+	"ChannelFutureBookTicker":       gate.ChannelFutureBookTicker,
+	"ChannelFutureOrderBookUpdate":  gate.ChannelFutureOrderBookUpdate,
+	"ChannelFutureCandleStick":      gate.ChannelFutureCandleStick,
+	"ChannelFutureOrder":            gate.ChannelFutureOrder,
+	"ChannelFutureUserTrade":        gate.ChannelFutureUserTrade,
+	"ChannelFutureLiquidates":       gate.ChannelFutureLiquidates,
+	"ChannelFutureAutoDeleverages":  gate.ChannelFutureAutoDeleverages,
+	"ChannelFuturePositionCloses":   gate.ChannelFuturePositionCloses,
+	"ChannelFutureBalance":          gate.ChannelFutureBalance,
+	"ChannelFutureReduceRiskLimits": gate.ChannelFutureReduceRiskLimits,
+	"ChannelFuturePositions":        gate.ChannelFuturePositions,
+	"ChannelFutureAutoOrders":       gate.ChannelFutureAutoOrders,
+}
+
+// errListed is returned by parse after listing channels, exiting successfully
+var errListed = errors.New("channels listed")
+
+// marketKeys keys of the market in results of channels
+var marketKeys = []string{"currency_pair", "contract", "s", "n"}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Getenv, os.Stdout, os.Stderr); err != nil {
+		if errors.Is(err, errListed) {
+			return
+		}
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "gatews:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+type options struct {
+	url      string
+	settle   string
+	jsonl    bool
+	markets  map[string]bool
+	count    int
+	status   time.Duration
+	channel  string
+	payload  []string
+	key      string
+	secret   string
+	insecure bool
+}
+
+func parse(args []string, getenv func(string) string, stderr io.Writer) (*options, error) {
+	op := &options{key: getenv("GATE_API_KEY"), secret: getenv("GATE_API_SECRET")}
+	fs := flag.NewFlagSet("gatews", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: gatews [flags] channel [payload...]")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&op.url, "url", "", "websocket url, by the channel and -settle if empty")
+	fs.StringVar(&op.settle, "settle", "usdt", "settle currency of futures channels, usdt or btc")
+	fs.BoolVar(&op.jsonl, "json", false, "print messages as JSON lines")
+	market := fs.String("market", "", "comma separated markets to print, all if empty")
+	fs.IntVar(&op.count, "n", 0, "exit after printing n messages, 0 for no limit")
+	fs.DurationVar(&op.status, "status", 0, "interval of printing connection status and counts, 0 to disable")
+	fs.BoolVar(&op.insecure, "insecure", false, "skip verifying TLS certificates")
+	list := fs.Bool("list", false, "list channels")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *list {
+		names := make([]string, 0, len(channels))
+		for name := range channels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stderr, "%-30s %s\n", name, channels[name])
+		}
+		return nil, errListed
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return nil, errors.New("channel required")
+	}
+
+	op.channel, op.payload = fs.Arg(0), fs.Args()[1:]
+	if channel, ok := channels[op.channel]; ok {
+		op.channel = channel
+	} else if !known(op.channel) {
+		return nil, fmt.Errorf("unknown channel %q, see -list", op.channel)
+	}
+	if *market != "" {
+		op.markets = make(map[string]bool)
+		for _, m := range strings.Split(*market, ",") {
+			op.markets[strings.TrimSpace(m)] = true
+		}
+	}
+	if op.url == "" {
+		switch {
+		case strings.HasPrefix(op.channel, "spot."):
+			op.url = gate.BaseUrl
+		case op.settle == "usdt":
+			op.url = gate.FuturesUsdtUrl
+		case op.settle == "btc":
+			op.url = gate.FuturesBtcUrl
+		default:
+			return nil, fmt.Errorf("unknown settle %q", op.settle)
+		}
+	}
+	return op, nil
+}
+
+func known(channel string) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// statusMetrics prints reconnects and counts messages of the service, failures are logged by the service
+type statusMetrics struct {
+	stderr     io.Writer
+	received   int64
+	reconnects int64
+	decodeErrs int64
+}
+
+func (m *statusMetrics) MessageReceived(string, int)             { atomic.AddInt64(&m.received, 1) }
+func (m *statusMetrics) DecodeError(string)                      { atomic.AddInt64(&m.decodeErrs, 1) }
+func (m *statusMetrics) CallbackDuration(string, time.Duration)  {}
+func (m *statusMetrics) ResubscribeFailure(string)               {}
+func (m *statusMetrics) APIRequestLatency(string, time.Duration) {}
+func (m *statusMetrics) OutstandingRequests(int)                 {}
+
+func (m *statusMetrics) Reconnect() {
+	n := atomic.AddInt64(&m.reconnects, 1)
+	fmt.Fprintf(m.stderr, "%s reconnected, %d reconnects\n", time.Now().Format(time.RFC3339), n)
+}
+
+func run(ctx context.Context, args []string, getenv func(string) string, stdout, stderr io.Writer) error {
+	op, err := parse(args, getenv, stderr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	metrics := &statusMetrics{stderr: stderr}
+	logger := log.New(stderr, "", log.LstdFlags)
+	app := strings.SplitN(op.channel, ".", 2)[0]
+	ws, err := gate.NewWsService(ctx, logger, gate.NewConnConfFromOption(&gate.ConfOptions{
+		App:              app,
+		URL:              op.url,
+		Key:              op.key,
+		Secret:           op.secret,
+		SkipTlsVerify:    op.insecure,
+		ShowReconnectMsg: true,
+		Metrics:          metrics,
+	}))
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	fmt.Fprintf(stderr, "%s %s %s\n", time.Now().Format(time.RFC3339), ws.Status(), op.url)
+
+	p := &printer{op: op, out: stdout, status: stderr, done: cancel}
+	ws.SetCallBack(op.channel, gate.NewCallBack(p.print))
+	if err := ws.Subscribe(op.channel, op.payload); err != nil {
+		if op.key == "" || op.secret == "" {
+			return fmt.Errorf("subscribe %s: %w, set GATE_API_KEY and GATE_API_SECRET for private channels", op.channel, err)
+		}
+		return fmt.Errorf("subscribe %s: %w", op.channel, err)
+	}
+
+	var ticks <-chan time.Time
+	if op.status > 0 {
+		ticker := time.NewTicker(op.status)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return p.err()
+		case <-ticks:
+			fmt.Fprintf(stderr, "%s %s, %d received, %d printed, %d reconnects, %d decode errors\n",
+				time.Now().Format(time.RFC3339), ws.Status(), atomic.LoadInt64(&metrics.received), p.count(),
+				atomic.LoadInt64(&metrics.reconnects), atomic.LoadInt64(&metrics.decodeErrs))
+		}
+	}
+}
+
+// printer writes updates of the channel to out
+type printer struct {
+	op     *options
+	out    io.Writer
+	status io.Writer
+	done   func()
+
+	mu      sync.Mutex
+	printed int
+	failed  error
+}
+
+// line JSON line of a message, Result is written as received
+type line struct {
+	Time    string             `json:"time"`
+	Channel string             `json:"channel"`
+	Event   string             `json:"event"`
+	Error   *gate.ServiceError `json:"error,omitempty"`
+	Result  json.RawMessage    `json:"result,omitempty"`
+}
+
+func (p *printer) print(msg *gate.UpdateMsg) {
+	if msg.Event == "subscribe" || msg.Event == "unsubscribe" {
+		if msg.Error != nil {
+			fmt.Fprintf(p.status, "%s %s %s failed: %s\n", time.Now().Format(time.RFC3339), msg.Event, msg.GetChannel(), msg.Error.Message)
+			if msg.Event == "subscribe" {
+				p.fail(msg.Error)
+			}
+			return
+		}
+		fmt.Fprintf(p.status, "%s %sd %s\n", time.Now().Format(time.RFC3339), msg.Event, msg.GetChannel())
+		return
+	}
+
+	result := msg.Result
+	if p.op.markets != nil && msg.Error == nil {
+		if result = filter(result, p.op.markets); result == nil {
+			return
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failed != nil || (p.op.count > 0 && p.printed >= p.op.count) {
+		return
+	}
+
+	at := msg.ExchangeTime().String()
+	if at == "" {
+		at = msg.ReceivedAt.Format(time.RFC3339Nano)
+	}
+	var err error
+	if p.op.jsonl {
+		var b []byte
+		if b, err = json.Marshal(line{Time: at, Channel: msg.GetChannel(), Event: string(msg.Event), Error: msg.Error, Result: result}); err == nil {
+			_, err = fmt.Fprintf(p.out, "%s\n", b)
+		}
+	} else {
+		var body bytes.Buffer
+		switch {
+		case msg.Error != nil:
+			fmt.Fprintf(&body, "error %d: %s", msg.Error.Code, msg.Error.Message)
+		case json.Indent(&body, result, "", "  ") != nil:
+			body.Reset()
+			body.Write(result)
+		}
+		_, err = fmt.Fprintf(p.out, "%s %s %s\n%s\n", at, msg.GetChannel(), msg.Event, body.Bytes())
+	}
+	if err != nil {
+		p.failed = err
+		p.done()
+		return
+	}
+	p.printed++
+	if p.op.count > 0 && p.printed >= p.op.count {
+		p.done()
+	}
+}
+
+func (p *printer) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failed == nil {
+		p.failed = err
+	}
+	p.done()
+}
+
+func (p *printer) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.printed
+}
+
+func (p *printer) err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failed
+}
+
+// filter returns result with only items of markets, nil if none. Items without a market are kept
+func filter(result json.RawMessage, markets map[string]bool) json.RawMessage {
+	var raws []json.RawMessage
+	if json.Unmarshal(result, &raws) != nil {
+		if match(result, markets) {
+			return result
+		}
+		return nil
+	}
+
+	kept := raws[:0]
+	for _, raw := range raws {
+		if match(raw, markets) {
+			kept = append(kept, raw)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(kept)
+	return b
+}
+
+func match(raw json.RawMessage, markets map[string]bool) bool {
+	var item map[string]json.RawMessage
+	if json.Unmarshal(raw, &item) != nil {
+		return true
+	}
+	market, ok := marketOf(item)
+	return !ok || markets[market]
+}
+
+// marketOf returns the market of an item by marketKeys, candlesticks are named like 1m_BTC_USDT
+func marketOf(item map[string]json.RawMessage) (string, bool) {
+	for _, key := range marketKeys {
+		var market string
+		if raw, ok := item[key]; ok && json.Unmarshal(raw, &market) == nil && market != "" {
+			if key == "n" {
+				if i := strings.IndexByte(market, '_'); i >= 0 {
+					market = market[i+1:]
+				}
+			}
+			return market, true
+		}
+	}
+	return "", false
+}
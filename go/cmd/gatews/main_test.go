@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gate "github.com/gateio/gatews/go"
+	"github.com/gateio/gatews/go/gatewstest"
+)
+
+// buffer is safe for the concurrent writes of callbacks and the test
+type buffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// tail runs the command against srv, pushing results to channel once subscribed
+func tail(t *testing.T, srv *gatewstest.Server, env map[string]string, args []string, channel string, results ...any) (string, string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		if srv.WaitSubscribed(channel, 5*time.Second) {
+			for _, result := range results {
+				srv.Push(channel, result)
+			}
+		}
+	}()
+
+	var stdout, stderr buffer
+	args = append([]string{"-url", srv.URL}, args...)
+	err := run(ctx, args, func(key string) string { return env[key] }, &stdout, &stderr)
+	if ctx.Err() != nil {
+		t.Fatalf("timeout, stdout:\n%s\nstderr:\n%s", stdout.String(), stderr.String())
+	}
+	return stdout.String(), stderr.String(), err
+}
+
+func TestTailJSONLines(t *testing.T) {
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+
+	out, status, err := tail(t, srv, nil, []string{"-json", "-n", "2", "-market", "BTC_USDT", "ChannelFutureTrade", "BTC_USDT", "ETH_USDT"},
+		gate.ChannelFutureTrade,
+		[]map[string]any{{"id": 1, "contract": "ETH_USDT"}},
+		[]map[string]any{{"id": 2, "contract": "ETH_USDT"}, {"id": 3, "contract": "BTC_USDT"}},
+		[]map[string]any{{"id": 4, "contract": "BTC_USDT", "create_time": 1700000000}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(status, "subscribed futures.trades") {
+		t.Fatalf("status:\n%s", status)
+	}
+
+	// results are written as received, times are not normalised to milliseconds
+	if !strings.Contains(out, `"create_time":1700000000`) || strings.Contains(out, "1700000000000") {
+		t.Fatalf("create_time re-encoded:\n%s", out)
+	}
+
+	var ids []int
+	for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+		var msg struct {
+			Channel string
+			Event   string
+			Result  []gate.FuturesTrade
+		}
+		if err := json.Unmarshal([]byte(l), &msg); err != nil || msg.Channel != gate.ChannelFutureTrade || msg.Event != "update" {
+			t.Fatalf("line %s, %v", l, err)
+		}
+		for _, trade := range msg.Result {
+			ids = append(ids, int(trade.Id))
+		}
+	}
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 4 {
+		t.Fatalf("printed trades %v\n%s", ids, out)
+	}
+}
+
+func TestTailPrivate(t *testing.T) {
+	srv := gatewstest.NewServer("KEY", "SECRET")
+	defer srv.Close()
+	balance := []map[string]any{{"currency": "USDT", "total": "100"}}
+
+	if _, _, err := tail(t, srv, nil, []string{"spot.balances"}, gate.ChannelSpotBalance); err == nil || !strings.Contains(err.Error(), "GATE_API_KEY") {
+		t.Fatalf("subscribed without key: %v", err)
+	}
+
+	env := map[string]string{"GATE_API_KEY": "KEY", "GATE_API_SECRET": "SECRET"}
+	out, _, err := tail(t, srv, env, []string{"-n", "1", "spot.balances"}, gate.ChannelSpotBalance, balance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "spot.balances update\n[\n  {\n    \"currency\": \"USDT\"") {
+		t.Fatalf("printed:\n%s", out)
+	}
+}
+
+func TestInterruptConnecting(t *testing.T) {
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	srv.RejectConnections(true)
+
+	// interrupted while retrying to connect
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := run(ctx, []string{"-url", srv.URL, "spot.tickers"}, func(string) string { return "" }, &buffer{}, &buffer{})
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Fatalf("err %v after %s", err, time.Since(start))
+	}
+}
+
+func TestParse(t *testing.T) {
+	getenv := func(string) string { return "" }
+	for _, c := range []struct {
+		args []string
+		url  string
+		err  string
+	}{
+		{args: []string{"spot.tickers", "BTC_USDT"}, url: gate.BaseUrl},
+		{args: []string{"ChannelFutureTicker", "BTC_USDT"}, url: gate.FuturesUsdtUrl},
+		{args: []string{"-settle", "btc", "futures.tickers", "BTC_USD"}, url: gate.FuturesBtcUrl},
+		{args: []string{"-settle", "eth", "futures.tickers"}, err: "unknown settle"},
+		{args: []string{"spot.order_place"}, err: "unknown channel"},
+		{args: nil, err: "channel required"},
+	} {
+		op, err := parse(c.args, getenv, &buffer{})
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%v: err %v, want %s", c.args, err, c.err)
+			}
+			continue
+		}
+		if err != nil || op.url != c.url {
+			t.Errorf("%v: url %v, %v", c.args, op, err)
+		}
+	}
+}
+
+func TestParseList(t *testing.T) {
+	var stderr buffer
+	if _, err := parse([]string{"-list"}, func(string) string { return "" }, &stderr); !errors.Is(err, errListed) {
+		t.Fatalf("err %v", err)
+	}
+	if !strings.Contains(stderr.String(), "ChannelSpotTicker") {
+		t.Fatalf("listed %q", stderr.String())
+	}
+}
+
+func TestFilter(t *testing.T) {
+	markets := map[string]bool{"BTC_USDT": true}
+	for _, c := range []struct{ in, want string }{
+		{`{"currency_pair":"BTC_USDT","last":"1"}`, `{"currency_pair":"BTC_USDT","last":"1"}`},
+		{`{"s":"ETH_USDT"}`, ``},
+		{`{"n":"1m_BTC_USDT"}`, `{"n":"1m_BTC_USDT"}`},
+		{`[{"contract":"ETH_USDT"},{"contract":"BTC_USDT"},{"currency":"USDT"}]`, `[{"contract":"BTC_USDT"},{"currency":"USDT"}]`},
+		{`[{"contract":"ETH_USDT"}]`, ``},
+		{`{"status":"success"}`, `{"status":"success"}`},
+	} {
+		if got := filter(json.RawMessage(c.in), markets); string(got) != c.want {
+			t.Errorf("%s: got %s, want %s", c.in, got, c.want)
+		}
+	}
+}
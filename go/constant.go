@@ -7,6 +7,9 @@ const (
 	FuturesBtcUrl  = "wss://fx-ws.gateio.ws/v4/ws/btc"
 	FuturesUsdtUrl = "wss://fx-ws.gateio.ws/v4/ws/usdt"
 
+	// RestBaseUrl base url of the REST API, used to fetch metadata
+	RestBaseUrl = "https://api.gateio.ws/api/v4"
+
 	AuthMethodApiKey = "api_key"
 	MaxRetryConn     = math.MaxInt64
 )
@@ -0,0 +1,465 @@
+// Package decimal provides an arbitrary precision fixed-point decimal used for prices and amounts,
+// avoiding the precision loss of float64 when dealing with money.
+package decimal
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DivisionPrecision number of decimal places kept by Div
+var DivisionPrecision int32 = 16
+
+// maxExponent bound of exponents in scientific notation, so the size of a parsed decimal is
+// proportional to its string and formatting or adding it can't exhaust memory
+const maxExponent = 1000
+
+var (
+	Zero = New(0, 0)
+	One  = New(1, 0)
+
+	ten = big.NewInt(10)
+)
+
+// Decimal represents value * 10^exp. The zero value is 0 and ready to use
+type Decimal struct {
+	value *big.Int
+	exp   int32
+}
+
+// New returns value * 10^exp
+func New(value int64, exp int32) Decimal {
+	return Decimal{value: big.NewInt(value), exp: exp}
+}
+
+func NewFromInt(value int64) Decimal {
+	return New(value, 0)
+}
+
+// NewFromFloat converts a float64 using its shortest decimal representation
+func NewFromFloat(value float64) Decimal {
+	d, err := NewFromString(strconv.FormatFloat(value, 'f', -1, 64))
+	if err != nil {
+		return Zero
+	}
+	return d
+}
+
+// NewFromString parses numbers like "1.23", "-0.001", "+5" or "1.5e-8"
+func NewFromString(s string) (Decimal, error) {
+	orig := s
+	if s == "" {
+		return Decimal{}, fmt.Errorf("decimal: can't parse empty string")
+	}
+
+	var exp int64
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("decimal: can't parse %q as decimal", orig)
+		}
+		if e < -maxExponent || e > maxExponent {
+			return Decimal{}, fmt.Errorf("decimal: exponent of %q out of range", orig)
+		}
+		exp = e
+		s = s[:i]
+	}
+
+	digits := s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		if strings.IndexByte(s[i+1:], '.') >= 0 {
+			return Decimal{}, fmt.Errorf("decimal: can't parse %q as decimal", orig)
+		}
+		digits = s[:i] + s[i+1:]
+		exp -= int64(len(s) - i - 1)
+	}
+
+	unsigned := strings.TrimLeft(digits, "+-")
+	if unsigned == "" || len(digits)-len(unsigned) > 1 || strings.ContainsAny(unsigned, "+-") {
+		return Decimal{}, fmt.Errorf("decimal: can't parse %q as decimal", orig)
+	}
+
+	value, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("decimal: can't parse %q as decimal", orig)
+	}
+	if exp < -1<<31 {
+		return Decimal{}, fmt.Errorf("decimal: exponent of %q out of range", orig)
+	}
+
+	return Decimal{value: value, exp: int32(exp)}, nil
+}
+
+// RequireFromString is like NewFromString but panics on error, for constants and tests
+func RequireFromString(s string) Decimal {
+	d, err := NewFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (d Decimal) val() *big.Int {
+	if d.value == nil {
+		return new(big.Int)
+	}
+	return d.value
+}
+
+// rescale returns the unscaled value of d with the exponent exp, exp must not be greater than d.exp
+func (d Decimal) rescale(exp int32) *big.Int {
+	v := new(big.Int).Set(d.val())
+	if exp < d.exp {
+		v.Mul(v, pow10(int64(d.exp)-int64(exp)))
+	}
+	return v
+}
+
+func align(d1, d2 Decimal) (*big.Int, *big.Int, int32) {
+	exp := d1.exp
+	if d2.exp < exp {
+		exp = d2.exp
+	}
+	return d1.rescale(exp), d2.rescale(exp), exp
+}
+
+func (d Decimal) Add(d2 Decimal) Decimal {
+	v1, v2, exp := align(d, d2)
+	return Decimal{value: v1.Add(v1, v2), exp: exp}
+}
+
+func (d Decimal) Sub(d2 Decimal) Decimal {
+	v1, v2, exp := align(d, d2)
+	return Decimal{value: v1.Sub(v1, v2), exp: exp}
+}
+
+func (d Decimal) Mul(d2 Decimal) Decimal {
+	return Decimal{value: new(big.Int).Mul(d.val(), d2.val()), exp: d.exp + d2.exp}
+}
+
+// Div divides with DivisionPrecision decimal places, panics on division by zero like integer division
+func (d Decimal) Div(d2 Decimal) Decimal {
+	return d.DivRound(d2, DivisionPrecision)
+}
+
+// DivRound divides and rounds the quotient half away from zero to places decimal places
+func (d Decimal) DivRound(d2 Decimal, places int32) Decimal {
+	if d2.IsZero() {
+		panic("decimal: division by zero")
+	}
+
+	// d / d2 * 10^places = v1 * 10^(e1-e2+places) / v2
+	num := new(big.Int).Set(d.val())
+	den := new(big.Int).Set(d2.val())
+	if shift := int64(d.exp) - int64(d2.exp) + int64(places); shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+
+	return Decimal{value: quoRound(num, den, roundHalfUp), exp: -places}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{value: new(big.Int).Neg(d.val()), exp: d.exp}
+}
+
+func (d Decimal) Abs() Decimal {
+	return Decimal{value: new(big.Int).Abs(d.val()), exp: d.exp}
+}
+
+// Cmp returns -1 if d < d2, 0 if d == d2 and +1 if d > d2
+func (d Decimal) Cmp(d2 Decimal) int {
+	v1, v2, _ := align(d, d2)
+	return v1.Cmp(v2)
+}
+
+func (d Decimal) Equal(d2 Decimal) bool {
+	return d.Cmp(d2) == 0
+}
+
+func (d Decimal) GreaterThan(d2 Decimal) bool {
+	return d.Cmp(d2) > 0
+}
+
+func (d Decimal) GreaterThanOrEqual(d2 Decimal) bool {
+	return d.Cmp(d2) >= 0
+}
+
+func (d Decimal) LessThan(d2 Decimal) bool {
+	return d.Cmp(d2) < 0
+}
+
+func (d Decimal) LessThanOrEqual(d2 Decimal) bool {
+	return d.Cmp(d2) <= 0
+}
+
+// Sign returns -1, 0 or +1
+func (d Decimal) Sign() int {
+	return d.val().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+func (d Decimal) IsPositive() bool {
+	return d.Sign() > 0
+}
+
+func (d Decimal) IsNegative() bool {
+	return d.Sign() < 0
+}
+
+type roundMode int
+
+const (
+	roundDown   roundMode = iota // toward zero
+	roundUp                      // away from zero
+	roundHalfUp                  // half away from zero
+	roundFloor                   // toward negative infinity
+	roundCeil                    // toward positive infinity
+)
+
+func (d Decimal) round(places int32, mode roundMode) Decimal {
+	if d.exp >= -places {
+		return d
+	}
+	den := pow10(int64(-places) - int64(d.exp))
+	return Decimal{value: quoRound(new(big.Int).Set(d.val()), den, mode), exp: -places}
+}
+
+// Round rounds half away from zero to places decimal places, places can be negative
+func (d Decimal) Round(places int32) Decimal {
+	return d.round(places, roundHalfUp)
+}
+
+// Truncate drops digits after places decimal places, i.e. rounds toward zero
+func (d Decimal) Truncate(places int32) Decimal {
+	return d.round(places, roundDown)
+}
+
+// RoundUp rounds away from zero to places decimal places
+func (d Decimal) RoundUp(places int32) Decimal {
+	return d.round(places, roundUp)
+}
+
+// RoundFloor rounds toward negative infinity to places decimal places
+func (d Decimal) RoundFloor(places int32) Decimal {
+	return d.round(places, roundFloor)
+}
+
+// RoundCeil rounds toward positive infinity to places decimal places
+func (d Decimal) RoundCeil(places int32) Decimal {
+	return d.round(places, roundCeil)
+}
+
+func (d Decimal) roundStep(step Decimal, mode roundMode) Decimal {
+	if step.Sign() <= 0 {
+		return d
+	}
+
+	num := new(big.Int).Set(d.val())
+	den := new(big.Int).Set(step.val())
+	if shift := int64(d.exp) - int64(step.exp); shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+
+	return Decimal{value: quoRound(num, den, mode)}.Mul(step)
+}
+
+// RoundStep rounds half away from zero to a multiple of step, e.g. a price tick. d is returned if step is not positive
+func (d Decimal) RoundStep(step Decimal) Decimal {
+	return d.roundStep(step, roundHalfUp)
+}
+
+// FloorStep rounds toward negative infinity to a multiple of step
+func (d Decimal) FloorStep(step Decimal) Decimal {
+	return d.roundStep(step, roundFloor)
+}
+
+// CeilStep rounds toward positive infinity to a multiple of step
+func (d Decimal) CeilStep(step Decimal) Decimal {
+	return d.roundStep(step, roundCeil)
+}
+
+// TruncateStep rounds toward zero to a multiple of step
+func (d Decimal) TruncateStep(step Decimal) Decimal {
+	return d.roundStep(step, roundDown)
+}
+
+// Exponent returns the exponent, i.e. -scale
+func (d Decimal) Exponent() int32 {
+	return d.exp
+}
+
+// IntPart returns the integer part, truncated toward zero
+func (d Decimal) IntPart() int64 {
+	return d.Truncate(0).rescale(0).Int64()
+}
+
+// Float64 returns the nearest float64, which may lose precision
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String returns the plain representation without trailing zeros, e.g. "0.0001" or "-12.5"
+func (d Decimal) String() string {
+	return d.string(true)
+}
+
+// StringFixed returns the representation rounded to exactly places decimal places
+func (d Decimal) StringFixed(places int32) string {
+	r := d.Round(places)
+	if places <= 0 {
+		return r.string(true)
+	}
+	if r.exp > -places {
+		r = Decimal{value: r.rescale(-places), exp: -places}
+	}
+	return r.string(false)
+}
+
+func (d Decimal) string(trim bool) string {
+	v := d.val()
+	if d.exp >= 0 {
+		return d.rescale(0).String()
+	}
+
+	digits := new(big.Int).Abs(v).String()
+	scale := int(-d.exp)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	intPart, fracPart := digits[:len(digits)-scale], digits[len(digits)-scale:]
+	if trim {
+		fracPart = strings.TrimRight(fracPart, "0")
+	}
+
+	s := intPart
+	if fracPart != "" {
+		s += "." + fracPart
+	}
+	if v.Sign() < 0 && strings.Trim(s, "0.") != "" {
+		s = "-" + s
+	}
+	return s
+}
+
+// MarshalJSON encodes as a JSON string, the same as the Gate APIs
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON decodes both JSON strings and numbers. null and "" are decoded as zero
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		*d = Decimal{}
+		return nil
+	}
+
+	s := string(data)
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return fmt.Errorf("decimal: can't unmarshal %s: %w", data, err)
+		}
+		s = strings.TrimSpace(unquoted)
+		if s == "" {
+			*d = Decimal{}
+			return nil
+		}
+	}
+
+	v, err := NewFromString(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = Decimal{}
+		return nil
+	}
+	v, err := NewFromString(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func Min(first Decimal, rest ...Decimal) Decimal {
+	m := first
+	for _, d := range rest {
+		if d.LessThan(m) {
+			m = d
+		}
+	}
+	return m
+}
+
+func Max(first Decimal, rest ...Decimal) Decimal {
+	m := first
+	for _, d := range rest {
+		if d.GreaterThan(m) {
+			m = d
+		}
+	}
+	return m
+}
+
+func Sum(values ...Decimal) Decimal {
+	s := Zero
+	for _, d := range values {
+		s = s.Add(d)
+	}
+	return s
+}
+
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(ten, big.NewInt(n), nil)
+}
+
+// quoRound returns num / den rounded with mode, num is modified
+func quoRound(num, den *big.Int, mode roundMode) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	// sign of the exact quotient
+	sign := num.Sign() * den.Sign()
+	away := false
+	switch mode {
+	case roundDown:
+	case roundUp:
+		away = true
+	case roundHalfUp:
+		r2 := new(big.Int).Abs(r)
+		away = r2.Lsh(r2, 1).Cmp(new(big.Int).Abs(den)) >= 0
+	case roundFloor:
+		away = sign < 0
+	case roundCeil:
+		away = sign > 0
+	}
+
+	if away {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return q
+}
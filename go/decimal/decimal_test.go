@@ -0,0 +1,135 @@
+package decimal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseAndString(t *testing.T) {
+	cases := map[string]string{
+		"0":           "0",
+		"1.50":        "1.5",
+		"-0.001":      "-0.001",
+		"+5":          "5",
+		"1.5e-8":      "0.000000015",
+		"12e3":        "12000",
+		"-0.0":        "0",
+		".5":          "0.5",
+		"26830.9":     "26830.9",
+		"0.000000001": "0.000000001",
+	}
+	for in, want := range cases {
+		d, err := NewFromString(in)
+		if err != nil {
+			t.Fatalf("parse %q: %v", in, err)
+		}
+		if got := d.String(); got != want {
+			t.Errorf("%q: got %s, want %s", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"", "-", "1.2.3", "abc", "1e", "--1", "1-", "1e1001", "1e-2000000000"} {
+		if _, err := NewFromString(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a := RequireFromString("0.1")
+	b := RequireFromString("0.2")
+	if got := a.Add(b); !got.Equal(RequireFromString("0.3")) {
+		t.Errorf("0.1+0.2 = %s", got)
+	}
+	if got := a.Sub(b); got.String() != "-0.1" {
+		t.Errorf("0.1-0.2 = %s", got)
+	}
+	if got := a.Mul(b); got.String() != "0.02" {
+		t.Errorf("0.1*0.2 = %s", got)
+	}
+	if got := NewFromInt(1).DivRound(NewFromInt(3), 4); got.String() != "0.3333" {
+		t.Errorf("1/3 = %s", got)
+	}
+	if got := NewFromInt(-2).DivRound(NewFromInt(3), 2); got.String() != "-0.67" {
+		t.Errorf("-2/3 = %s", got)
+	}
+	if got := RequireFromString("1e3").Div(RequireFromString("0.5")); got.String() != "2000" {
+		t.Errorf("1000/0.5 = %s", got)
+	}
+	if Sum(a, b, One).String() != "1.3" || !Max(a, b).Equal(b) || !Min(a, b).Equal(a) {
+		t.Error("unexpected sum/max/min")
+	}
+
+	var zero Decimal
+	if !zero.Add(a).Equal(a) || !zero.IsZero() || zero.String() != "0" {
+		t.Error("zero value is not usable")
+	}
+}
+
+func TestRound(t *testing.T) {
+	d := RequireFromString("-1.255")
+	cases := []struct {
+		got  Decimal
+		want string
+	}{
+		{d.Round(2), "-1.26"},
+		{d.Truncate(2), "-1.25"},
+		{d.RoundUp(1), "-1.3"},
+		{d.RoundFloor(1), "-1.3"},
+		{d.RoundCeil(1), "-1.2"},
+		{RequireFromString("1250").Round(-2), "1300"},
+		{RequireFromString("1.2").Round(4), "1.2"},
+	}
+	for i, c := range cases {
+		if c.got.String() != c.want {
+			t.Errorf("case %d: got %s, want %s", i, c.got, c.want)
+		}
+	}
+
+	step := RequireFromString("0.05")
+	for in, want := range map[string][4]string{
+		"1.23":  {"1.25", "1.2", "1.25", "1.2"},
+		"-1.23": {"-1.25", "-1.25", "-1.2", "-1.2"},
+		"1.25":  {"1.25", "1.25", "1.25", "1.25"},
+	} {
+		d := RequireFromString(in)
+		got := [4]string{d.RoundStep(step).String(), d.FloorStep(step).String(), d.CeilStep(step).String(), d.TruncateStep(step).String()}
+		if got != want {
+			t.Errorf("%s: got %v, want %v", in, got, want)
+		}
+	}
+
+	if got := RequireFromString("1.5").StringFixed(3); got != "1.500" {
+		t.Errorf("StringFixed = %s", got)
+	}
+	if got := RequireFromString("-12.75").IntPart(); got != -12 {
+		t.Errorf("IntPart = %d", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		A Decimal `json:"a"`
+		B Decimal `json:"b"`
+		C Decimal `json:"c"`
+		D Decimal `json:"d"`
+	}
+	if err := json.Unmarshal([]byte(`{"a":"1.10","b":0.3,"c":"","d":null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A.String() != "1.1" || v.B.String() != "0.3" || !v.C.IsZero() || !v.D.IsZero() {
+		t.Fatalf("unexpected %+v", v)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"a":"1.1","b":"0.3","c":"0","d":"0"}` {
+		t.Fatalf("unexpected %s", out)
+	}
+
+	if err := json.Unmarshal([]byte(`{"a":"x"}`), &v); err == nil {
+		t.Fatal("expected error")
+	}
+}
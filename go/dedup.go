@@ -0,0 +1,48 @@
+package gatews
+
+import "strconv"
+
+// maxSeenTrades number of trade ids kept per market by PositionTracker and Accounting to ignore trades added again
+const maxSeenTrades = DefaultTapeCapacity
+
+// recentIDs remembers the last ids added to a market to ignore ids added again, e.g. when trades are
+// pushed again after reconnecting. Trade ids increase per market, numeric ids not above the ones
+// forgotten are ignored too
+type recentIDs struct {
+	seen     map[string]struct{}
+	ids      []string // ring buffer
+	start    int
+	capacity int
+	// evicted the highest numeric id evicted from seen
+	evicted int64
+}
+
+func newRecentIDs(capacity int) *recentIDs {
+	return &recentIDs{seen: make(map[string]struct{}), capacity: capacity}
+}
+
+// add adds id, returns false if it has been added before or is older than the ids forgotten
+func (r *recentIDs) add(id string) bool {
+	if _, ok := r.seen[id]; ok {
+		return false
+	}
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil && r.evicted != 0 && n <= r.evicted {
+		return false
+	}
+	r.seen[id] = struct{}{}
+
+	if len(r.ids) < r.capacity {
+		r.ids = append(r.ids, id)
+		return true
+	}
+
+	// forget the oldest one
+	oldest := r.ids[r.start]
+	delete(r.seen, oldest)
+	if n, err := strconv.ParseInt(oldest, 10, 64); err == nil && n > r.evicted {
+		r.evicted = n
+	}
+	r.ids[r.start] = id
+	r.start = (r.start + 1) % len(r.ids)
+	return true
+}
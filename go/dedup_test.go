@@ -0,0 +1,27 @@
+package gatews
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRecentIDs(t *testing.T) {
+	ids := newRecentIDs(3)
+	for i := 1; i <= 5; i++ {
+		if !ids.add(strconv.Itoa(i)) {
+			t.Fatalf("%d rejected", i)
+		}
+	}
+	if len(ids.seen) != 3 || ids.evicted != 2 {
+		t.Fatalf("%d ids kept, %d evicted", len(ids.seen), ids.evicted)
+	}
+	// kept, evicted and older ids are rejected, non numeric ids are only deduplicated
+	for _, id := range []string{"5", "3", "2", "1"} {
+		if ids.add(id) {
+			t.Errorf("%s added again", id)
+		}
+	}
+	if !ids.add("6") || !ids.add("a") || ids.add("a") {
+		t.Fatal("unexpected dedup")
+	}
+}
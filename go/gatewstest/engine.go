@@ -0,0 +1,873 @@
+package gatewstest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+// amounts of spot market buys are converted to base amounts with this precision
+const amountPrecision = 8
+
+var (
+	DefaultMakerFeeRate = decimal.RequireFromString("0.002")
+	DefaultTakerFeeRate = decimal.RequireFromString("0.002")
+)
+
+// Engine simulated matching engine serving the order api channels of a Server.
+// User orders rest in per-market books with price-time priority and match each other,
+// the market quote of book tickers, and public trades fed by Feed. Fills generate
+// orders, usertrades and balances updates, and positions updates for futures
+type Engine struct {
+	srv *Server
+
+	mu sync.Mutex
+	// fee rates of makers and takers
+	MakerFeeRate decimal.Decimal
+	TakerFeeRate decimal.Decimal
+	// Settle currency of futures balances
+	Settle string
+	User   int64
+
+	books       map[string]*book
+	orders      map[int64]*simOrder
+	balances    map[string]*balance
+	futures     balance
+	positions   map[string]*position
+	multipliers map[string]decimal.Decimal
+	lastID      int64
+	lastTradeID int64
+	seq         int64
+}
+
+type balance struct {
+	available decimal.Decimal
+	freeze    decimal.Decimal
+}
+
+func (b *balance) total() decimal.Decimal {
+	return b.available.Add(b.freeze)
+}
+
+type position struct {
+	size       decimal.Decimal // contracts, negative for short
+	entry      decimal.Decimal
+	realised   decimal.Decimal
+	lastPnl    decimal.Decimal
+	historyPnl decimal.Decimal
+}
+
+// quoteLevel best price of the market and the size left to take at it
+type quoteLevel struct {
+	price decimal.Decimal
+	size  decimal.Decimal
+}
+
+type book struct {
+	bids []*simOrder // best price first, then oldest
+	asks []*simOrder
+	bid  quoteLevel
+	ask  quoteLevel
+}
+
+type simOrder struct {
+	id      int64
+	seq     int64
+	futures bool
+	market  string
+	side    model.Side
+	typ     model.OrderType
+	tif     model.TimeInForce
+	price   decimal.Decimal // zero for market orders
+	amount  decimal.Decimal // base amount, quote amount of spot market buys, or contracts
+	left    decimal.Decimal
+	// filled base amount or contracts, and quote total
+	filled      decimal.Decimal
+	filledTotal decimal.Decimal
+	fee         decimal.Decimal
+	feeCurrency string
+	text        string
+	amendText   string
+	reduceOnly  bool
+	isClose     bool
+	status      model.OrderStatus
+	finishAs    model.FinishAs
+	createTime  time.Time
+	updateTime  time.Time
+	finishTime  time.Time
+}
+
+func (o *simOrder) open() bool {
+	return o.status == model.OrderStatusOpen
+}
+
+// marketBuy spot market buys are in quote amounts
+func (o *simOrder) marketBuy() bool {
+	return !o.futures && o.typ == model.OrderTypeMarket && o.side == model.SideBuy
+}
+
+// crosses returns whether o takes liquidity at price
+func (o *simOrder) crosses(price decimal.Decimal) bool {
+	if o.price.IsZero() {
+		return true
+	}
+	if o.side == model.SideBuy {
+		return price.LessThanOrEqual(o.price)
+	}
+	return price.GreaterThanOrEqual(o.price)
+}
+
+// updates pending pushes, sent after the engine is unlocked
+type updates struct {
+	spotOrders     []any
+	spotTrades     []any
+	spotBalances   []any
+	futuresOrders  []any
+	futuresTrades  []any
+	futuresBalance []any
+	positions      []any
+}
+
+// NewEngine serves order api channels of srv by the engine
+func NewEngine(srv *Server) *Engine {
+	e := &Engine{
+		srv:          srv,
+		MakerFeeRate: DefaultMakerFeeRate,
+		TakerFeeRate: DefaultTakerFeeRate,
+		Settle:       "USDT",
+		User:         1,
+		books:        make(map[string]*book),
+		orders:       make(map[int64]*simOrder),
+		balances:     make(map[string]*balance),
+		positions:    make(map[string]*position),
+		multipliers:  make(map[string]decimal.Decimal),
+	}
+	e.register()
+	return e
+}
+
+// SetBalance sets the available spot balance of currency
+func (e *Engine) SetBalance(currency string, amount decimal.Decimal) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.balances[currency] = &balance{available: amount}
+}
+
+// Balance returns available and frozen spot balances of currency
+func (e *Engine) Balance(currency string) (available, freeze decimal.Decimal) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b := e.balance(currency)
+	return b.available, b.freeze
+}
+
+// SetFuturesBalance sets the futures balance in Settle currency
+func (e *Engine) SetFuturesBalance(amount decimal.Decimal) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.futures = balance{available: amount}
+}
+
+// FuturesBalance returns the futures balance, changed by fees and realised PnL
+func (e *Engine) FuturesBalance() decimal.Decimal {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.futures.available
+}
+
+// SetMultiplier sets the quanto multiplier of contract, 1 by default
+func (e *Engine) SetMultiplier(contract string, multiplier decimal.Decimal) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.multipliers[contract] = multiplier
+}
+
+// Position returns the size and entry price of the position in contract
+func (e *Engine) Position(contract string) (size int64, entry decimal.Decimal) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if p, ok := e.positions[contract]; ok {
+		return p.size.IntPart(), p.entry
+	}
+	return 0, decimal.Zero
+}
+
+// SetQuote sets the best bid and ask of market with sizes available to take,
+// resting orders crossing it are filled as makers. Zero sizes remove a side
+func (e *Engine) SetQuote(market string, bid, bidSize, ask, askSize decimal.Decimal) {
+	e.mu.Lock()
+	var u updates
+	b := e.book(market)
+	b.bid = quoteLevel{price: bid, size: bidSize}
+	b.ask = quoteLevel{price: ask, size: askSize}
+	e.matchQuote(market, b, &u)
+	e.mu.Unlock()
+
+	e.push(&u)
+}
+
+// Trade applies a public trade of market, taker side sells fill resting buys at or above price and
+// taker side buys fill resting sells at or below price, up to amount
+func (e *Engine) Trade(market string, side model.Side, price, amount decimal.Decimal) {
+	e.mu.Lock()
+	var u updates
+	e.matchTrade(market, side, price, amount, &u)
+	e.mu.Unlock()
+
+	e.push(&u)
+}
+
+// Feed pushes a market data update of channel to subscribers and applies it to the engine.
+// spot.trades, futures.trades, spot.book_ticker and futures.book_ticker updates drive fills
+func (e *Engine) Feed(channel string, result json.RawMessage) error {
+	e.srv.Push(channel, result)
+
+	switch channel {
+	case "spot.trades":
+		var t struct {
+			CurrencyPair string     `json:"currency_pair"`
+			Side         model.Side `json:"side"`
+			Amount       string     `json:"amount"`
+			Price        string     `json:"price"`
+		}
+		if err := json.Unmarshal(result, &t); err != nil {
+			return err
+		}
+		price, amount, err := parsePair(t.Price, t.Amount)
+		if err != nil {
+			return err
+		}
+		e.Trade(t.CurrencyPair, t.Side, price, amount)
+	case "futures.trades":
+		var trades []struct {
+			Contract string `json:"contract"`
+			Size     int64  `json:"size"`
+			Price    string `json:"price"`
+		}
+		if err := json.Unmarshal(result, &trades); err != nil {
+			return err
+		}
+		for _, t := range trades {
+			price, err := decimal.NewFromString(t.Price)
+			if err != nil {
+				return err
+			}
+			side := model.SideBuy
+			if t.Size < 0 {
+				side = model.SideSell
+			}
+			e.Trade(t.Contract, side, price, decimal.NewFromInt(t.Size).Abs())
+		}
+	case "spot.book_ticker", "futures.book_ticker":
+		var t struct {
+			Market  string          `json:"s"`
+			Bid     string          `json:"b"`
+			BidSize json.RawMessage `json:"B"`
+			Ask     string          `json:"a"`
+			AskSize json.RawMessage `json:"A"`
+		}
+		if err := json.Unmarshal(result, &t); err != nil {
+			return err
+		}
+		bid, bidSize, err := parsePair(t.Bid, unquote(t.BidSize))
+		if err != nil {
+			return err
+		}
+		ask, askSize, err := parsePair(t.Ask, unquote(t.AskSize))
+		if err != nil {
+			return err
+		}
+		e.SetQuote(t.Market, bid, bidSize, ask, askSize)
+	}
+	return nil
+}
+
+// FeedStream feeds updates of a recorded stream, one message as pushed by the server per line.
+// Lines without channel and result, e.g. subscription acks, are skipped
+func (e *Engine) FeedStream(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg struct {
+			Channel string          `json:"channel"`
+			Event   string          `json:"event"`
+			Result  json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return err
+		}
+		if msg.Channel == "" || msg.Event != "update" || len(msg.Result) == 0 {
+			continue
+		}
+		if err := e.Feed(msg.Channel, msg.Result); err != nil {
+			return fmt.Errorf("feed %s: %w", msg.Channel, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func parsePair(a, b string) (decimal.Decimal, decimal.Decimal, error) {
+	d1, err := decimal.NewFromString(a)
+	if err != nil {
+		return d1, d1, err
+	}
+	d2, err := decimal.NewFromString(b)
+	return d1, d2, err
+}
+
+// unquote returns a number which may be quoted
+func unquote(raw json.RawMessage) string {
+	return strings.Trim(string(raw), `"`)
+}
+
+// now orders and trades are stamped by the clock of the server
+func (e *Engine) now() time.Time {
+	return e.srv.now()
+}
+
+func (e *Engine) book(market string) *book {
+	b, ok := e.books[market]
+	if !ok {
+		b = &book{}
+		e.books[market] = b
+	}
+	return b
+}
+
+func (e *Engine) balance(currency string) *balance {
+	b, ok := e.balances[currency]
+	if !ok {
+		b = &balance{}
+		e.balances[currency] = b
+	}
+	return b
+}
+
+func (e *Engine) multiplier(contract string) decimal.Decimal {
+	if m, ok := e.multipliers[contract]; ok {
+		return m
+	}
+	return decimal.One
+}
+
+func splitPair(pair string) (base, quote string) {
+	base, quote, _ = strings.Cut(pair, "_")
+	return base, quote
+}
+
+// insert adds o to its book by price-time priority
+func (b *book) insert(o *simOrder) {
+	side := &b.bids
+	better := func(p decimal.Decimal) bool { return p.LessThan(o.price) }
+	if o.side == model.SideSell {
+		side = &b.asks
+		better = func(p decimal.Decimal) bool { return p.GreaterThan(o.price) }
+	}
+	orders := *side
+	i := sort.Search(len(orders), func(i int) bool { return better(orders[i].price) })
+	orders = append(orders, nil)
+	copy(orders[i+1:], orders[i:])
+	orders[i] = o
+	*side = orders
+}
+
+func (b *book) remove(o *simOrder) {
+	side := &b.bids
+	if o.side == model.SideSell {
+		side = &b.asks
+	}
+	for i, r := range *side {
+		if r == o {
+			*side = append((*side)[:i], (*side)[i+1:]...)
+			return
+		}
+	}
+}
+
+// opposite returns resting orders o can match, best first
+func (b *book) opposite(o *simOrder) []*simOrder {
+	if o.side == model.SideBuy {
+		return b.asks
+	}
+	return b.bids
+}
+
+func (b *book) oppositeQuote(o *simOrder) *quoteLevel {
+	if o.side == model.SideBuy {
+		return &b.ask
+	}
+	return &b.bid
+}
+
+// takeable returns the amount o would take from the book, in quote for spot market buys
+func (e *Engine) takeable(o *simOrder) decimal.Decimal {
+	b := e.book(o.market)
+	sum := decimal.Zero
+	add := func(price, size decimal.Decimal) {
+		if o.marketBuy() {
+			size = size.Mul(price)
+		}
+		sum = sum.Add(size)
+	}
+	for _, r := range b.opposite(o) {
+		if !o.crosses(r.price) {
+			break
+		}
+		add(r.price, r.left)
+	}
+	if q := b.oppositeQuote(o); q.size.IsPositive() && o.crosses(q.price) {
+		add(q.price, q.size)
+	}
+	return sum
+}
+
+// matchTaker matches a new order against resting orders and the quote
+func (e *Engine) matchTaker(o *simOrder, u *updates) {
+	b := e.book(o.market)
+	for o.left.IsPositive() {
+		var maker *simOrder
+		if rest := b.opposite(o); len(rest) > 0 && o.crosses(rest[0].price) {
+			maker = rest[0]
+		}
+		q := b.oppositeQuote(o)
+		useQuote := q.size.IsPositive() && o.crosses(q.price) &&
+			(maker == nil || (o.side == model.SideBuy && q.price.LessThan(maker.price)) ||
+				(o.side == model.SideSell && q.price.GreaterThan(maker.price)))
+		if maker == nil && !useQuote {
+			return
+		}
+
+		price, size := q.price, q.size
+		if !useQuote {
+			price, size = maker.price, maker.left
+		}
+		amount := o.left
+		if o.marketBuy() {
+			amount = o.left.DivRound(price, amountPrecision+4).Truncate(amountPrecision)
+		}
+		amount = decimal.Min(amount, size)
+		if !amount.IsPositive() {
+			return
+		}
+
+		if useQuote {
+			q.size = q.size.Sub(amount)
+		} else {
+			e.fill(maker, amount, price, model.RoleMaker, u)
+			if !maker.left.IsPositive() {
+				e.finish(maker, model.FinishAsFilled, u)
+			} else {
+				e.emitOrder(maker, model.OrderEventUpdate, u)
+			}
+		}
+		e.fill(o, amount, price, model.RoleTaker, u)
+	}
+}
+
+// matchQuote fills resting orders crossing the quote of the market
+func (e *Engine) matchQuote(market string, b *book, u *updates) {
+	for _, side := range []struct {
+		orders *[]*simOrder
+		level  *quoteLevel
+	}{{&b.bids, &b.ask}, {&b.asks, &b.bid}} {
+		for len(*side.orders) > 0 && side.level.size.IsPositive() {
+			o := (*side.orders)[0]
+			if !o.crosses(side.level.price) {
+				break
+			}
+			amount := decimal.Min(o.left, side.level.size)
+			side.level.size = side.level.size.Sub(amount)
+			e.fillResting(o, amount, u)
+		}
+	}
+}
+
+// matchTrade fills resting orders by a public trade
+func (e *Engine) matchTrade(market string, side model.Side, price, amount decimal.Decimal, u *updates) {
+	b := e.book(market)
+	resting := &b.bids
+	if side == model.SideBuy {
+		resting = &b.asks
+	}
+	for amount.IsPositive() && len(*resting) > 0 {
+		o := (*resting)[0]
+		if !o.crosses(price) {
+			return
+		}
+		fill := decimal.Min(o.left, amount)
+		amount = amount.Sub(fill)
+		e.fillResting(o, fill, u)
+	}
+}
+
+// fillResting fills a resting order as maker at its price
+func (e *Engine) fillResting(o *simOrder, amount decimal.Decimal, u *updates) {
+	e.fill(o, amount, o.price, model.RoleMaker, u)
+	if !o.left.IsPositive() {
+		e.finish(o, model.FinishAsFilled, u)
+	} else {
+		e.emitOrder(o, model.OrderEventUpdate, u)
+	}
+}
+
+// fill applies a fill of amount at price to o, its balances and position
+func (e *Engine) fill(o *simOrder, amount, price decimal.Decimal, role model.Role, u *updates) {
+	rate := e.TakerFeeRate
+	if role == model.RoleMaker {
+		rate = e.MakerFeeRate
+	}
+	now := e.now()
+	o.updateTime = now
+	o.filled = o.filled.Add(amount)
+	o.filledTotal = o.filledTotal.Add(amount.Mul(price))
+	e.lastTradeID++
+
+	if o.futures {
+		o.left = o.left.Sub(amount)
+		e.fillFutures(o, amount, price, rate, role, u)
+		return
+	}
+
+	base, quote := splitPair(o.market)
+	total := amount.Mul(price)
+	var fee decimal.Decimal
+	if o.side == model.SideBuy {
+		fee = amount.Mul(rate)
+		o.feeCurrency = base
+		qb := e.balance(quote)
+		if o.marketBuy() {
+			o.left = o.left.Sub(total)
+			qb.freeze = qb.freeze.Sub(total)
+		} else {
+			o.left = o.left.Sub(amount)
+			qb.freeze = qb.freeze.Sub(amount.Mul(o.price))
+			qb.available = qb.available.Add(amount.Mul(o.price.Sub(price)))
+		}
+		bb := e.balance(base)
+		bb.available = bb.available.Add(amount.Sub(fee))
+		e.emitBalance(quote, total.Neg(), u)
+		e.emitBalance(base, amount.Sub(fee), u)
+	} else {
+		fee = total.Mul(rate)
+		o.feeCurrency = quote
+		o.left = o.left.Sub(amount)
+		bb := e.balance(base)
+		bb.freeze = bb.freeze.Sub(amount)
+		qb := e.balance(quote)
+		qb.available = qb.available.Add(total.Sub(fee))
+		e.emitBalance(base, amount.Neg(), u)
+		e.emitBalance(quote, total.Sub(fee), u)
+	}
+	o.fee = o.fee.Add(fee)
+
+	u.spotTrades = append(u.spotTrades, map[string]any{
+		"id":             e.lastTradeID,
+		"user_id":        e.User,
+		"order_id":       strconv.FormatInt(o.id, 10),
+		"currency_pair":  o.market,
+		"create_time":    now.Unix(),
+		"create_time_ms": strconv.FormatInt(now.UnixMilli(), 10),
+		"side":           o.side,
+		"amount":         amount.String(),
+		"role":           role,
+		"price":          price.String(),
+		"fee":            fee.String(),
+		"fee_currency":   o.feeCurrency,
+		"point_fee":      "0",
+		"gt_fee":         "0",
+		"text":           o.text,
+	})
+}
+
+func (e *Engine) fillFutures(o *simOrder, amount, price, rate decimal.Decimal, role model.Role, u *updates) {
+	now := e.now()
+	mult := e.multiplier(o.market)
+	fee := amount.Mul(price).Mul(mult).Mul(rate)
+	o.fee = o.fee.Add(fee)
+	o.feeCurrency = e.Settle
+
+	signed := amount
+	if o.side == model.SideSell {
+		signed = amount.Neg()
+	}
+	p, ok := e.positions[o.market]
+	if !ok {
+		p = &position{}
+		e.positions[o.market] = p
+	}
+	pnl := decimal.Zero
+	if !p.size.IsZero() && p.size.Sign() != signed.Sign() {
+		closed := decimal.Min(p.size.Abs(), amount)
+		pnl = price.Sub(p.entry).Mul(closed).Mul(mult)
+		if p.size.IsNegative() {
+			pnl = pnl.Neg()
+		}
+		p.lastPnl = pnl
+		p.realised = p.realised.Add(pnl)
+		p.historyPnl = p.historyPnl.Add(pnl)
+	}
+	size := p.size.Add(signed)
+	switch {
+	case size.IsZero():
+		p.entry = decimal.Zero
+	case p.size.IsZero() || p.size.Sign() != size.Sign():
+		p.entry = price
+	case size.Abs().GreaterThan(p.size.Abs()):
+		p.entry = p.entry.Mul(p.size).Add(price.Mul(signed)).DivRound(size, 12)
+	}
+	p.size = size
+
+	e.futures.available = e.futures.available.Sub(fee)
+	e.emitFuturesBalance(fee.Neg(), "fee", u)
+	if !pnl.IsZero() {
+		e.futures.available = e.futures.available.Add(pnl)
+		e.emitFuturesBalance(pnl, "pnl", u)
+	}
+
+	tradeSize := amount.IntPart()
+	if o.side == model.SideSell {
+		tradeSize = -tradeSize
+	}
+	u.futuresTrades = append(u.futuresTrades, map[string]any{
+		"id":             strconv.FormatInt(e.lastTradeID, 10),
+		"create_time":    now.Unix(),
+		"create_time_ms": now.UnixMilli(),
+		"contract":       o.market,
+		"order_id":       strconv.FormatInt(o.id, 10),
+		"size":           tradeSize,
+		"price":          price.String(),
+		"role":           role,
+		"text":           o.text,
+		"fee":            fee.Float64(),
+		"point_fee":      0,
+	})
+	u.positions = append(u.positions, map[string]any{
+		"contract":       o.market,
+		"entry_price":    p.entry.Float64(),
+		"history_pnl":    p.historyPnl.Float64(),
+		"last_close_pnl": p.lastPnl.Float64(),
+		"mode":           "single",
+		"realised_pnl":   p.realised.Float64(),
+		"size":           p.size.IntPart(),
+		"time":           now.Unix(),
+		"time_ms":        now.UnixMilli(),
+		"user":           strconv.FormatInt(e.User, 10),
+	})
+}
+
+// finish ends o, releasing frozen balances of what is left
+func (e *Engine) finish(o *simOrder, finishAs model.FinishAs, u *updates) {
+	if !o.open() {
+		return
+	}
+	e.book(o.market).remove(o)
+
+	now := e.now()
+	o.updateTime, o.finishTime = now, now
+	o.finishAs = finishAs
+	switch {
+	case o.futures:
+		o.status = model.OrderStatusFinished
+	case finishAs == model.FinishAsFilled:
+		o.status = model.OrderStatusClosed
+	default:
+		o.status = model.OrderStatusCancelled
+	}
+
+	if !o.futures && o.left.IsPositive() {
+		base, quote := splitPair(o.market)
+		currency, frozen := base, o.left
+		if o.side == model.SideBuy {
+			currency = quote
+			if !o.marketBuy() {
+				frozen = o.left.Mul(o.price)
+			}
+		}
+		b := e.balance(currency)
+		b.freeze = b.freeze.Sub(frozen)
+		b.available = b.available.Add(frozen)
+		e.emitBalance(currency, decimal.Zero, u)
+	}
+	e.emitOrder(o, model.OrderEventFinish, u)
+}
+
+func (e *Engine) emitBalance(currency string, change decimal.Decimal, u *updates) {
+	now := e.now()
+	b := e.balance(currency)
+	u.spotBalances = append(u.spotBalances, map[string]any{
+		"timestamp":    strconv.FormatInt(now.Unix(), 10),
+		"timestamp_ms": strconv.FormatInt(now.UnixMilli(), 10),
+		"user":         strconv.FormatInt(e.User, 10),
+		"currency":     currency,
+		"change":       change.String(),
+		"total":        b.total().String(),
+		"available":    b.available.String(),
+		"freeze":       b.freeze.String(),
+	})
+}
+
+func (e *Engine) emitFuturesBalance(change decimal.Decimal, typ string, u *updates) {
+	now := e.now()
+	u.futuresBalance = append(u.futuresBalance, map[string]any{
+		"balance":  e.futures.available.Float64(),
+		"change":   change.Float64(),
+		"text":     typ,
+		"time":     now.Unix(),
+		"time_ms":  now.UnixMilli(),
+		"user":     strconv.FormatInt(e.User, 10),
+		"type":     typ,
+		"currency": e.Settle,
+	})
+}
+
+func (e *Engine) emitOrder(o *simOrder, event model.OrderEvent, u *updates) {
+	if !o.futures {
+		msg := e.spotOrder(o)
+		msg["event"] = event
+		msg["user"] = e.User
+		u.spotOrders = append(u.spotOrders, msg)
+		return
+	}
+
+	finishAs := o.finishAs
+	if o.open() {
+		finishAs = model.FinishAsNew
+		if o.filled.IsPositive() {
+			finishAs = model.FinishAsUpdate
+		}
+	}
+	msg := map[string]any{
+		"id":             o.id,
+		"user":           strconv.FormatInt(e.User, 10),
+		"create_time":    o.createTime.Unix(),
+		"create_time_ms": o.createTime.UnixMilli(),
+		"finish_as":      finishAs,
+		"status":         o.status,
+		"contract":       o.market,
+		"size":           e.signed(o, o.amount),
+		"price":          o.price.Float64(),
+		"tif":            o.tif,
+		"left":           e.signed(o, o.left),
+		"fill_price":     e.avgPrice(o).Float64(),
+		"text":           o.text,
+		"tkfr":           e.TakerFeeRate.Float64(),
+		"mkfr":           e.MakerFeeRate.Float64(),
+		"is_reduce_only": o.reduceOnly,
+		"is_close":       o.isClose,
+		"amend_text":     o.amendText,
+	}
+	if !o.finishTime.IsZero() {
+		msg["finish_time"] = o.finishTime.Unix()
+		msg["finish_time_ms"] = o.finishTime.UnixMilli()
+	}
+	u.futuresOrders = append(u.futuresOrders, msg)
+}
+
+func (e *Engine) signed(o *simOrder, size decimal.Decimal) int64 {
+	if o.side == model.SideSell {
+		return -size.IntPart()
+	}
+	return size.IntPart()
+}
+
+func (e *Engine) avgPrice(o *simOrder) decimal.Decimal {
+	if o.filled.IsZero() {
+		return decimal.Zero
+	}
+	return o.filledTotal.DivRound(o.filled, 12)
+}
+
+// spotOrder returns o in the shape of spot order api results and spot.orders updates
+func (e *Engine) spotOrder(o *simOrder) map[string]any {
+	msg := map[string]any{
+		"id":             strconv.FormatInt(o.id, 10),
+		"text":           o.text,
+		"amend_text":     o.amendText,
+		"create_time":    strconv.FormatInt(o.createTime.Unix(), 10),
+		"update_time":    strconv.FormatInt(o.updateTime.Unix(), 10),
+		"create_time_ms": strconv.FormatInt(o.createTime.UnixMilli(), 10),
+		"update_time_ms": strconv.FormatInt(o.updateTime.UnixMilli(), 10),
+		"status":         o.status,
+		"currency_pair":  o.market,
+		"type":           o.typ,
+		"account":        "spot",
+		"side":           o.side,
+		"amount":         o.amount.String(),
+		"price":          o.price.String(),
+		"time_in_force":  o.tif,
+		"left":           o.left.String(),
+		"fill_price":     o.filledTotal.String(),
+		"filled_total":   o.filledTotal.String(),
+		"avg_deal_price": e.avgPrice(o).String(),
+		"fee":            o.fee.String(),
+		"fee_currency":   o.feeCurrency,
+		"point_fee":      "0",
+		"gt_fee":         "0",
+		"finish_as":      model.FinishAsOpen,
+	}
+	if !o.open() {
+		msg["finish_as"] = o.finishAs
+	}
+	return msg
+}
+
+// futuresOrder returns o in the shape of futures order api results
+func (e *Engine) futuresOrder(o *simOrder) map[string]any {
+	msg := map[string]any{
+		"id":             o.id,
+		"user":           e.User,
+		"create_time":    o.createTime.Unix(),
+		"status":         o.status,
+		"contract":       o.market,
+		"size":           e.signed(o, o.amount),
+		"price":          o.price.String(),
+		"tif":            o.tif,
+		"left":           e.signed(o, o.left),
+		"fill_price":     e.avgPrice(o).String(),
+		"text":           o.text,
+		"tkfr":           e.TakerFeeRate.String(),
+		"mkfr":           e.MakerFeeRate.String(),
+		"is_reduce_only": o.reduceOnly,
+		"is_close":       o.isClose,
+		"amend_text":     o.amendText,
+	}
+	if !o.open() {
+		msg["finish_as"] = o.finishAs
+		msg["finish_time"] = o.finishTime.Unix()
+	}
+	return msg
+}
+
+func (e *Engine) push(u *updates) {
+	for _, p := range []struct {
+		channel string
+		result  []any
+	}{
+		{"spot.orders", u.spotOrders},
+		{"spot.usertrades", u.spotTrades},
+		{"spot.balances", u.spotBalances},
+		{"futures.orders", u.futuresOrders},
+		{"futures.usertrades", u.futuresTrades},
+		{"futures.balances", u.futuresBalance},
+		{"futures.positions", u.positions},
+	} {
+		if len(p.result) > 0 {
+			e.srv.Push(p.channel, p.result)
+		}
+	}
+}
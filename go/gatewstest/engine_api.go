@@ -0,0 +1,570 @@
+package gatewstest
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+// labels of api errors
+const (
+	LabelInvalidParam     = "INVALID_PARAM_VALUE"
+	LabelBalanceNotEnough = "BALANCE_NOT_ENOUGH"
+	LabelOrderNotFound    = "ORDER_NOT_FOUND"
+	LabelReduceOnly       = "REDUCE_ONLY_FAIL"
+)
+
+func invalidParam(message string) *APIError {
+	return &APIError{Label: LabelInvalidParam, Message: message}
+}
+
+// decodeParam decodes and validates the param of req
+func decodeParam(req APIRequest, p interface{ Validate() error }) *APIError {
+	if err := json.Unmarshal(req.Param, p); err != nil {
+		return invalidParam(err.Error())
+	}
+	if err := p.Validate(); err != nil {
+		return invalidParam(err.Error())
+	}
+	return nil
+}
+
+func (e *Engine) register() {
+	handlers := map[string]APIHandler{
+		"spot.order_place":          e.spotPlace,
+		"spot.order_cancel":         e.spotCancel,
+		"spot.order_cancel_ids":     e.spotCancelIds,
+		"spot.order_cancel_cp":      e.spotCancelCp,
+		"spot.order_amend":          e.spotAmend,
+		"spot.order_status":         e.spotStatus,
+		"futures.order_place":       e.futuresPlace,
+		"futures.order_batch_place": e.futuresBatchPlace,
+		"futures.order_cancel":      e.futuresCancel,
+		"futures.order_cancel_cp":   e.futuresCancelCp,
+		"futures.order_amend":       e.futuresAmend,
+		"futures.order_status":      e.futuresStatus,
+		"futures.order_list":        e.futuresList,
+	}
+	for channel, h := range handlers {
+		e.srv.Handle(channel, h)
+	}
+}
+
+// locked runs f with the engine locked, then pushes its updates
+func (e *Engine) locked(f func(u *updates) APIResult) APIResult {
+	var u updates
+	e.mu.Lock()
+	res := f(&u)
+	e.mu.Unlock()
+
+	e.push(&u)
+	return res
+}
+
+// lookup finds an order by id or text
+func (e *Engine) lookup(ref string, futures bool) *simOrder {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		if o, ok := e.orders[id]; ok && o.futures == futures {
+			return o
+		}
+		return nil
+	}
+	var found *simOrder
+	for _, o := range e.orders {
+		if o.futures == futures && o.text == ref && ref != "" && (found == nil || o.id > found.id) {
+			found = o
+		}
+	}
+	return found
+}
+
+func (e *Engine) newOrder(futures bool, market string, side model.Side) *simOrder {
+	e.lastID++
+	e.seq++
+	now := e.now()
+	o := &simOrder{
+		id:         e.lastID,
+		seq:        e.seq,
+		futures:    futures,
+		market:     market,
+		side:       side,
+		status:     model.OrderStatusOpen,
+		createTime: now,
+		updateTime: now,
+	}
+	return o
+}
+
+// execute matches a new order by its time in force, and rests what is left
+func (e *Engine) execute(o *simOrder, u *updates) {
+	e.orders[o.id] = o
+	e.emitOrder(o, model.OrderEventPut, u)
+
+	takeable := e.takeable(o)
+	switch {
+	case o.tif == model.TimeInForcePOC && takeable.IsPositive():
+		e.finish(o, model.FinishAsPOC, u)
+		return
+	case o.tif == model.TimeInForceFOK && takeable.LessThan(o.left):
+		e.finish(o, model.FinishAsFOK, u)
+		return
+	}
+
+	e.matchTaker(o, u)
+	switch {
+	case !o.left.IsPositive():
+		e.finish(o, model.FinishAsFilled, u)
+	case o.tif == model.TimeInForceIOC || o.tif == model.TimeInForceFOK || o.price.IsZero():
+		e.finish(o, model.FinishAsIOC, u)
+	default:
+		e.book(o.market).insert(o)
+		if o.filled.IsPositive() {
+			e.emitOrder(o, model.OrderEventUpdate, u)
+		}
+	}
+}
+
+func (e *Engine) spotPlace(req APIRequest) APIResult {
+	var orders []model.Order
+	batch := json.Unmarshal(req.Param, &orders) == nil
+	if !batch {
+		var order model.Order
+		if err := json.Unmarshal(req.Param, &order); err != nil {
+			return APIResult{Err: invalidParam(err.Error())}
+		}
+		orders = []model.Order{order}
+	}
+
+	return e.locked(func(u *updates) APIResult {
+		results := make([]map[string]any, 0, len(orders))
+		for i := range orders {
+			o, apiErr := e.placeSpot(&orders[i], u)
+			if !batch {
+				if apiErr != nil {
+					return APIResult{Err: apiErr}
+				}
+				return APIResult{Ack: true, Result: e.spotOrder(o)}
+			}
+			if apiErr != nil {
+				results = append(results, map[string]any{
+					"text": orders[i].Text, "succeeded": false, "label": apiErr.Label, "message": apiErr.Message,
+				})
+				continue
+			}
+			result := e.spotOrder(o)
+			result["succeeded"] = true
+			results = append(results, result)
+		}
+		return APIResult{Ack: true, Result: results}
+	})
+}
+
+func (e *Engine) placeSpot(p *model.Order, u *updates) (*simOrder, *APIError) {
+	if err := p.Validate(); err != nil {
+		return nil, invalidParam(err.Error())
+	}
+	base, quote := splitPair(p.CurrencyPair)
+	if base == "" || quote == "" {
+		return nil, &APIError{Label: "INVALID_CURRENCY_PAIR", Message: "invalid currency pair " + p.CurrencyPair}
+	}
+	// validated above
+	amount := decimal.RequireFromString(p.Amount)
+	typ, tif, price := p.Type, p.TimeInForce, decimal.Zero
+	if typ == "" {
+		typ = model.OrderTypeLimit
+	}
+	if typ == model.OrderTypeLimit {
+		price = decimal.RequireFromString(p.Price)
+		if tif == "" {
+			tif = model.TimeInForceGTC
+		}
+	}
+
+	// buys freeze quote, market buys are in quote amounts
+	currency, frozen := base, amount
+	if p.Side == model.SideBuy {
+		currency = quote
+		if typ == model.OrderTypeLimit {
+			frozen = amount.Mul(price)
+		}
+	}
+	b := e.balance(currency)
+	if b.available.LessThan(frozen) {
+		return nil, &APIError{Label: LabelBalanceNotEnough, Message: "not enough " + currency}
+	}
+	b.available = b.available.Sub(frozen)
+	b.freeze = b.freeze.Add(frozen)
+	e.emitBalance(currency, decimal.Zero, u)
+
+	o := e.newOrder(false, p.CurrencyPair, p.Side)
+	o.typ, o.tif, o.text, o.price = typ, tif, p.Text, price
+	o.amount, o.left = amount, amount
+
+	e.execute(o, u)
+	return o, nil
+}
+
+func (e *Engine) spotCancel(req APIRequest) APIResult {
+	var p model.CancelOrderParam
+	if apiErr := decodeParam(req, &p); apiErr != nil {
+		return APIResult{Err: apiErr}
+	}
+	return e.locked(func(u *updates) APIResult {
+		o := e.lookup(p.OrderId, false)
+		if o == nil || !o.open() || (p.CurrencyPair != "" && o.market != p.CurrencyPair) {
+			return APIResult{Err: &APIError{Label: LabelOrderNotFound, Message: "order not found"}}
+		}
+		e.finish(o, model.FinishAsCancelled, u)
+		return APIResult{Result: e.spotOrder(o)}
+	})
+}
+
+func (e *Engine) spotCancelIds(req APIRequest) APIResult {
+	var params []struct {
+		Id           string `json:"id"`
+		OrderId      string `json:"order_id"`
+		CurrencyPair string `json:"currency_pair"`
+	}
+	if err := json.Unmarshal(req.Param, &params); err != nil {
+		return APIResult{Err: invalidParam(err.Error())}
+	}
+	return e.locked(func(u *updates) APIResult {
+		results := make([]map[string]any, 0, len(params))
+		for _, p := range params {
+			ref := p.Id
+			if ref == "" {
+				ref = p.OrderId
+			}
+			result := map[string]any{"currency_pair": p.CurrencyPair, "id": ref, "succeeded": true}
+			if o := e.lookup(ref, false); o != nil && o.open() && o.market == p.CurrencyPair {
+				e.finish(o, model.FinishAsCancelled, u)
+			} else {
+				result["succeeded"], result["label"], result["message"] = false, LabelOrderNotFound, "order not found"
+			}
+			results = append(results, result)
+		}
+		return APIResult{Result: results}
+	})
+}
+
+func (e *Engine) spotCancelCp(req APIRequest) APIResult {
+	var p model.CancelOrderWithCpParam
+	if apiErr := decodeParam(req, &p); apiErr != nil {
+		return APIResult{Err: apiErr}
+	}
+	return e.locked(func(u *updates) APIResult {
+		results := make([]map[string]any, 0)
+		for _, o := range e.openOrders(false, p.CurrencyPair) {
+			if p.Side == "" || o.side == p.Side {
+				e.finish(o, model.FinishAsCancelled, u)
+				results = append(results, e.spotOrder(o))
+			}
+		}
+		return APIResult{Result: results}
+	})
+}
+
+// openOrders returns open orders of market by priority, bids first
+func (e *Engine) openOrders(futures bool, market string) []*simOrder {
+	var orders []*simOrder
+	for name, b := range e.books {
+		if market != "" && name != market {
+			continue
+		}
+		for _, side := range [][]*simOrder{b.bids, b.asks} {
+			for _, o := range side {
+				if o.futures == futures {
+					orders = append(orders, o)
+				}
+			}
+		}
+	}
+	return orders
+}
+
+func (e *Engine) spotAmend(req APIRequest) APIResult {
+	var p model.AmendOrderParam
+	if apiErr := decodeParam(req, &p); apiErr != nil {
+		return APIResult{Err: apiErr}
+	}
+	return e.locked(func(u *updates) APIResult {
+		o := e.lookup(p.OrderId, false)
+		if o == nil || !o.open() || (p.CurrencyPair != "" && o.market != p.CurrencyPair) {
+			return APIResult{Err: &APIError{Label: LabelOrderNotFound, Message: "order not found"}}
+		}
+		price, amount := o.price, o.amount
+		var err error
+		if p.Price != "" {
+			if price, err = decimal.NewFromString(p.Price); err != nil || !price.IsPositive() {
+				return APIResult{Err: invalidParam("invalid price")}
+			}
+		}
+		if p.Amount != "" {
+			if amount, err = decimal.NewFromString(p.Amount); err != nil || !amount.GreaterThan(o.filled) {
+				return APIResult{Err: invalidParam("invalid amount")}
+			}
+		}
+		left := amount.Sub(o.filled)
+
+		// frozen balance follows what is left
+		base, quote := splitPair(o.market)
+		currency, before, after := base, o.left, left
+		if o.side == model.SideBuy {
+			currency, before, after = quote, o.left.Mul(o.price), left.Mul(price)
+		}
+		b := e.balance(currency)
+		if diff := after.Sub(before); b.available.LessThan(diff) {
+			return APIResult{Err: &APIError{Label: LabelBalanceNotEnough, Message: "not enough " + currency}}
+		} else {
+			b.available = b.available.Sub(diff)
+			b.freeze = b.freeze.Add(diff)
+		}
+
+		e.amend(o, price, amount, left, p.AmendText, u)
+		return APIResult{Result: e.spotOrder(o)}
+	})
+}
+
+// amend changes price and amount of o, losing priority unless only decreasing the amount
+func (e *Engine) amend(o *simOrder, price, amount, left decimal.Decimal, amendText string, u *updates) {
+	b := e.book(o.market)
+	keep := price.Equal(o.price) && !left.GreaterThan(o.left)
+	o.price, o.amount, o.left, o.amendText = price, amount, left, amendText
+	o.updateTime = e.now()
+	if keep {
+		e.emitOrder(o, model.OrderEventUpdate, u)
+		return
+	}
+
+	b.remove(o)
+	e.seq++
+	o.seq = e.seq
+	e.matchTaker(o, u)
+	if !o.left.IsPositive() {
+		e.finish(o, model.FinishAsFilled, u)
+		return
+	}
+	b.insert(o)
+	e.emitOrder(o, model.OrderEventUpdate, u)
+}
+
+func (e *Engine) spotStatus(req APIRequest) APIResult {
+	var p model.StatusOrderParam
+	if apiErr := decodeParam(req, &p); apiErr != nil {
+		return APIResult{Err: apiErr}
+	}
+	return e.locked(func(u *updates) APIResult {
+		o := e.lookup(p.OrderId, false)
+		if o == nil || (p.CurrencyPair != "" && o.market != p.CurrencyPair) {
+			return APIResult{Err: &APIError{Label: LabelOrderNotFound, Message: "order not found"}}
+		}
+		return APIResult{Result: e.spotOrder(o)}
+	})
+}
+
+func (e *Engine) futuresPlace(req APIRequest) APIResult {
+	var p model.FuturesOrder
+	if apiErr := decodeParam(req, &p); apiErr != nil {
+		return APIResult{Err: apiErr}
+	}
+	return e.locked(func(u *updates) APIResult {
+		o, apiErr := e.placeFutures(&p, u)
+		if apiErr != nil {
+			return APIResult{Err: apiErr}
+		}
+		return APIResult{Ack: true, Result: e.futuresOrder(o)}
+	})
+}
+
+func (e *Engine) futuresBatchPlace(req APIRequest) APIResult {
+	var orders []model.FuturesOrder
+	if err := json.Unmarshal(req.Param, &orders); err != nil {
+		return APIResult{Err: invalidParam(err.Error())}
+	}
+	return e.locked(func(u *updates) APIResult {
+		results := make([]map[string]any, 0, len(orders))
+		for i := range orders {
+			o, apiErr := e.placeFutures(&orders[i], u)
+			if apiErr != nil {
+				results = append(results, map[string]any{
+					"text": orders[i].Text, "succeeded": false, "label": apiErr.Label, "message": apiErr.Message,
+				})
+				continue
+			}
+			result := e.futuresOrder(o)
+			result["succeeded"] = true
+			results = append(results, result)
+		}
+		return APIResult{Ack: true, Result: results}
+	})
+}
+
+func (e *Engine) placeFutures(p *model.FuturesOrder, u *updates) (*simOrder, *APIError) {
+	if err := p.Validate(); err != nil {
+		return nil, invalidParam(err.Error())
+	}
+	size := p.Size
+	var held int64
+	if pos, ok := e.positions[p.Contract]; ok {
+		held = pos.size.IntPart()
+	}
+	if p.Close || p.AutoSize != "" {
+		if held == 0 {
+			return nil, &APIError{Label: LabelReduceOnly, Message: "no position to close"}
+		}
+		size = -held
+	}
+	if size == 0 {
+		return nil, invalidParam("size is required")
+	}
+	if (p.ReduceOnly || p.Close) && (held == 0 || (held > 0) == (size > 0) || abs(size) > abs(held)) {
+		return nil, &APIError{Label: LabelReduceOnly, Message: "order would increase position"}
+	}
+
+	price := decimal.Zero
+	if p.Price != "" {
+		var err error
+		if price, err = decimal.NewFromString(p.Price); err != nil || price.IsNegative() {
+			return nil, invalidParam("invalid price")
+		}
+	}
+	side := model.SideBuy
+	if size < 0 {
+		side = model.SideSell
+	}
+
+	o := e.newOrder(true, p.Contract, side)
+	o.typ = model.OrderTypeLimit
+	o.tif, o.text, o.price = p.Tif, p.Text, price
+	o.amount = decimal.NewFromInt(abs(size))
+	o.left = o.amount
+	o.reduceOnly, o.isClose = p.ReduceOnly || p.Close, p.Close
+	if o.tif == "" {
+		o.tif = model.TimeInForceGTC
+	}
+	if price.IsZero() {
+		o.typ = model.OrderTypeMarket
+	}
+
+	e.execute(o, u)
+	return o, nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (e *Engine) futuresCancel(req APIRequest) APIResult {
+	var p model.CancelFuturesOrder
+	if apiErr := decodeParam(req, &p); apiErr != nil {
+		return APIResult{Err: apiErr}
+	}
+	return e.locked(func(u *updates) APIResult {
+		o := e.lookup(p.OrderId, true)
+		if o == nil || !o.open() {
+			return APIResult{Err: &APIError{Label: LabelOrderNotFound, Message: "order not found"}}
+		}
+		e.finish(o, model.FinishAsCancelled, u)
+		return APIResult{Result: e.futuresOrder(o)}
+	})
+}
+
+func (e *Engine) futuresCancelCp(req APIRequest) APIResult {
+	var p model.CancelFuturesCpOrder
+	if apiErr := decodeParam(req, &p); apiErr != nil {
+		return APIResult{Err: apiErr}
+	}
+	return e.locked(func(u *updates) APIResult {
+		results := make([]map[string]any, 0)
+		for _, o := range e.openOrders(true, p.Contract) {
+			if p.Side == "" || (p.Side == model.CancelSideBid) == (o.side == model.SideBuy) {
+				e.finish(o, model.FinishAsCancelled, u)
+				results = append(results, e.futuresOrder(o))
+			}
+		}
+		return APIResult{Result: results}
+	})
+}
+
+func (e *Engine) futuresAmend(req APIRequest) APIResult {
+	var p model.AmendFuturesOrder
+	if apiErr := decodeParam(req, &p); apiErr != nil {
+		return APIResult{Err: apiErr}
+	}
+	return e.locked(func(u *updates) APIResult {
+		o := e.lookup(p.OrderId, true)
+		if o == nil || !o.open() {
+			return APIResult{Err: &APIError{Label: LabelOrderNotFound, Message: "order not found"}}
+		}
+		price, amount := o.price, o.amount
+		if p.Price != "" {
+			var err error
+			if price, err = decimal.NewFromString(p.Price); err != nil || !price.IsPositive() {
+				return APIResult{Err: invalidParam("invalid price")}
+			}
+		}
+		// size is the new total size with the same sign
+		if p.Size != 0 {
+			if amount = decimal.NewFromInt(abs(p.Size)); !amount.GreaterThan(o.filled) {
+				return APIResult{Err: invalidParam("invalid size")}
+			}
+		}
+		e.amend(o, price, amount, amount.Sub(o.filled), p.AmendText, u)
+		return APIResult{Result: e.futuresOrder(o)}
+	})
+}
+
+func (e *Engine) futuresStatus(req APIRequest) APIResult {
+	var p model.StatusFuturesOrder
+	if apiErr := decodeParam(req, &p); apiErr != nil {
+		return APIResult{Err: apiErr}
+	}
+	return e.locked(func(u *updates) APIResult {
+		o := e.lookup(p.OrderId, true)
+		if o == nil {
+			return APIResult{Err: &APIError{Label: LabelOrderNotFound, Message: "order not found"}}
+		}
+		return APIResult{Result: e.futuresOrder(o)}
+	})
+}
+
+func (e *Engine) futuresList(req APIRequest) APIResult {
+	var p model.ListFuturesOrders
+	if apiErr := decodeParam(req, &p); apiErr != nil {
+		return APIResult{Err: apiErr}
+	}
+	return e.locked(func(u *updates) APIResult {
+		var orders []*simOrder
+		for _, o := range e.orders {
+			if !o.futures || (p.Contract != "" && o.market != p.Contract) {
+				continue
+			}
+			if p.Status == model.OrderStatusOpen && !o.open() || p.Status == model.OrderStatusFinished && o.open() {
+				continue
+			}
+			orders = append(orders, o)
+		}
+		// newest first as the exchange does
+		sort.Slice(orders, func(i, j int) bool { return orders[i].id > orders[j].id })
+
+		if int(p.Offset) < len(orders) {
+			orders = orders[p.Offset:]
+		} else {
+			orders = nil
+		}
+		if p.Limit > 0 && int(p.Limit) < len(orders) {
+			orders = orders[:p.Limit]
+		}
+		results := make([]map[string]any, 0, len(orders))
+		for _, o := range orders {
+			results = append(results, e.futuresOrder(o))
+		}
+		return APIResult{Result: results}
+	})
+}
@@ -0,0 +1,175 @@
+package gatewstest
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+func call(t *testing.T, h APIHandler, param any) APIResult {
+	t.Helper()
+	raw, err := json.Marshal(param)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h(APIRequest{Param: raw})
+}
+
+func order(t *testing.T, res APIResult) map[string]any {
+	t.Helper()
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	return res.Result.(map[string]any)
+}
+
+func assertDecimal(t *testing.T, name string, got decimal.Decimal, want string) {
+	t.Helper()
+	if !got.Equal(decimal.RequireFromString(want)) {
+		t.Fatalf("%s %s, want %s", name, got, want)
+	}
+}
+
+func TestEngineSpot(t *testing.T) {
+	s := NewServer("KEY", "SECRET")
+	defer s.Close()
+	e := NewEngine(s)
+	e.SetBalance("USDT", decimal.NewFromInt(1000))
+
+	res := call(t, e.spotPlace, model.Order{CurrencyPair: "BTC_USDT", Side: model.SideBuy, Amount: "2", Price: "1000"})
+	if res.Err == nil || res.Err.Label != LabelBalanceNotEnough {
+		t.Fatalf("place without balance %v", res.Err)
+	}
+
+	buy := order(t, call(t, e.spotPlace, model.Order{CurrencyPair: "BTC_USDT", Side: model.SideBuy, Amount: "1", Price: "100", Text: "t-buy"}))
+	available, freeze := e.Balance("USDT")
+	assertDecimal(t, "available", available, "900")
+	assertDecimal(t, "freeze", freeze, "100")
+
+	// a public sell below the price fills the resting buy at its price
+	if err := e.Feed("spot.trades", json.RawMessage(`{"currency_pair":"BTC_USDT","side":"sell","amount":"0.4","price":"99"}`)); err != nil {
+		t.Fatal(err)
+	}
+	available, _ = e.Balance("BTC")
+	assertDecimal(t, "BTC available", available, "0.3992")
+
+	res = call(t, e.spotPlace, model.Order{CurrencyPair: "BTC_USDT", Side: model.SideSell, Amount: "0.1", Price: "100", TimeInForce: model.TimeInForcePOC})
+	if o := order(t, res); o["finish_as"] != model.FinishAsPOC {
+		t.Fatalf("crossing poc %v", o)
+	}
+	res = call(t, e.spotPlace, model.Order{CurrencyPair: "BTC_USDT", Side: model.SideSell, Amount: "0.3", Price: "100", TimeInForce: model.TimeInForceIOC})
+	if o := order(t, res); o["finish_as"] != model.FinishAsFilled || o["left"] != "0" {
+		t.Fatalf("self matched ioc %v", o)
+	}
+
+	o := order(t, call(t, e.spotAmend, model.AmendOrderParam{OrderId: "t-buy", CurrencyPair: "BTC_USDT", Price: "90"}))
+	if o["price"] != "90" || o["left"] != "0.3" {
+		t.Fatalf("amended %v", o)
+	}
+	o = order(t, call(t, e.spotCancel, model.CancelOrderParam{OrderId: buy["id"].(string), CurrencyPair: "BTC_USDT"}))
+	if o["status"] != model.OrderStatusCancelled {
+		t.Fatalf("cancelled %v", o)
+	}
+	available, freeze = e.Balance("USDT")
+	// paid 70 for 0.7 BTC, received 30 less fee for 0.3 BTC
+	assertDecimal(t, "available", available, "959.94")
+	assertDecimal(t, "freeze", freeze, "0")
+
+	res = call(t, e.spotStatus, model.StatusOrderParam{OrderId: "404", CurrencyPair: "BTC_USDT"})
+	if res.Err == nil || res.Err.Label != LabelOrderNotFound {
+		t.Fatalf("status of unknown order %v", res.Err)
+	}
+}
+
+func TestEngineSpotBatch(t *testing.T) {
+	s := NewServer("KEY", "SECRET")
+	defer s.Close()
+	e := NewEngine(s)
+	e.SetBalance("ETH", decimal.NewFromInt(1))
+	e.SetQuote("ETH_USDT", decimal.NewFromInt(10), decimal.NewFromInt(5), decimal.NewFromInt(11), decimal.NewFromInt(5))
+
+	res := call(t, e.spotPlace, []model.Order{
+		{CurrencyPair: "ETH_USDT", Side: model.SideSell, Amount: "0.5", Price: "12"},
+		{CurrencyPair: "ETH_USDT", Side: model.SideSell, Amount: "2", Price: "10", TimeInForce: model.TimeInForceFOK},
+		{CurrencyPair: "ETH_USDT", Side: model.SideSell, Amount: "0.5", Type: model.OrderTypeMarket, TimeInForce: model.TimeInForceIOC},
+	})
+	results := res.Result.([]map[string]any)
+	if !res.Ack || len(results) != 3 {
+		t.Fatalf("batch %v", res)
+	}
+	if results[0]["status"] != model.OrderStatusOpen || results[1]["succeeded"] != false ||
+		results[2]["finish_as"] != model.FinishAsFilled || results[2]["avg_deal_price"] != "10" {
+		t.Fatalf("batch %v", results)
+	}
+
+	res = call(t, e.spotCancelCp, model.CancelOrderWithCpParam{CurrencyPair: "ETH_USDT"})
+	if cancelled := res.Result.([]map[string]any); len(cancelled) != 1 {
+		t.Fatalf("cancel all %v", cancelled)
+	}
+	available, _ := e.Balance("ETH")
+	assertDecimal(t, "available", available, "0.5")
+}
+
+func TestEngineFutures(t *testing.T) {
+	s := NewServer("KEY", "SECRET")
+	defer s.Close()
+	e := NewEngine(s)
+	e.SetFuturesBalance(decimal.NewFromInt(1000))
+	e.SetQuote("BTC_USDT", decimal.NewFromInt(99), decimal.NewFromInt(10), decimal.NewFromInt(100), decimal.NewFromInt(10))
+
+	o := order(t, call(t, e.futuresPlace, model.FuturesOrder{Contract: "BTC_USDT", Size: 2, Price: "0", Tif: model.TimeInForceIOC}))
+	if o["finish_as"] != model.FinishAsFilled || o["fill_price"] != "100" {
+		t.Fatalf("market order %v", o)
+	}
+	if size, entry := e.Position("BTC_USDT"); size != 2 || !entry.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("position %d %s", size, entry)
+	}
+
+	res := call(t, e.futuresPlace, model.FuturesOrder{Contract: "BTC_USDT", Size: 1, Price: "100", ReduceOnly: true})
+	if res.Err == nil || res.Err.Label != LabelReduceOnly {
+		t.Fatalf("reduce only increasing position %v", res.Err)
+	}
+	o = order(t, call(t, e.futuresPlace, model.FuturesOrder{Contract: "BTC_USDT", Close: true, Price: "120"}))
+	if o["size"] != int64(-2) || o["status"] != model.OrderStatusOpen {
+		t.Fatalf("close order %v", o)
+	}
+
+	if err := e.Feed("futures.trades", json.RawMessage(`[{"contract":"BTC_USDT","size":5,"price":"121"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if size, _ := e.Position("BTC_USDT"); size != 0 {
+		t.Fatalf("position %d", size)
+	}
+	// fees 200*0.002 and 240*0.002, pnl 2*20
+	assertDecimal(t, "balance", e.FuturesBalance(), "1039.12")
+
+	res = call(t, e.futuresList, model.ListFuturesOrders{Contract: "BTC_USDT", Status: model.OrderStatusFinished, Limit: 1})
+	if list := res.Result.([]map[string]any); len(list) != 1 || list[0]["id"] != o["id"] {
+		t.Fatalf("finished orders %v", list)
+	}
+}
+
+func TestEngineFeedStream(t *testing.T) {
+	s := NewServer("KEY", "SECRET")
+	defer s.Close()
+	e := NewEngine(s)
+	e.SetBalance("USDT", decimal.NewFromInt(100))
+	o := order(t, call(t, e.spotPlace, model.Order{CurrencyPair: "BTC_USDT", Side: model.SideBuy, Amount: "1", Price: "50"}))
+
+	stream := []string{
+		`{"time":1,"channel":"spot.book_ticker","event":"subscribe","result":{"status":"success"}}`,
+		`{"time":2,"channel":"spot.book_ticker","event":"update","result":{"t":1,"u":1,"s":"BTC_USDT","b":"49","B":"1","a":"51","A":"1"}}`,
+		`{"time":3,"channel":"spot.book_ticker","event":"update","result":{"t":2,"u":2,"s":"BTC_USDT","b":"48","B":"1","a":"50","A":"3"}}`,
+	}
+	if err := e.FeedStream(strings.NewReader(strings.Join(stream, "\n"))); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := strconv.ParseInt(o["id"].(string), 10, 64)
+	if status := e.orders[id].status; status != model.OrderStatusClosed {
+		t.Fatalf("order %s", status)
+	}
+}
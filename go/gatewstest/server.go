@@ -0,0 +1,584 @@
+// Package gatewstest provides an in-process fake Gate v4 websocket server for tests
+package gatewstest
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// error codes of subscribe responses
+const (
+	ErrCodeInvalidRequest  = 1
+	ErrCodeInvalidArgument = 2
+	ErrCodeServerError     = 3
+	ErrCodeAuthFailed      = 4
+)
+
+// MaxClockSkew requests signed with timestamps further from the server time are rejected
+const MaxClockSkew = 60 * time.Second
+
+// PrivateChannels channels which require a valid signature to subscribe
+var PrivateChannels = map[string]bool{
+	"spot.balances":              true,
+	"spot.funding_balances":      true,
+	"spot.margin_balances":       true,
+	"spot.cross_balances":        true,
+	"spot.orders":                true,
+	"spot.usertrades":            true,
+	"futures.orders":             true,
+	"futures.usertrades":         true,
+	"futures.liquidates":         true,
+	"futures.auto_deleverages":   true,
+	"futures.position_closes":    true,
+	"futures.reduce_risk_limits": true,
+	"futures.positions":          true,
+	"futures.autoorders":         true,
+	"futures.balances":           true,
+}
+
+// Frame a request received from a client
+type Frame struct {
+	Time    int64           `json:"time"`
+	Id      *int64          `json:"id,omitempty"`
+	Channel string          `json:"channel"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+	Auth    *struct {
+		Method string `json:"method"`
+		Key    string `json:"KEY"`
+		Sign   string `json:"SIGN"`
+	} `json:"auth,omitempty"`
+}
+
+// Strings decodes the payload of subscribe and unsubscribe frames, nil if it isn't a list of strings
+func (f Frame) Strings() []string {
+	var s []string
+	if err := json.Unmarshal(f.Payload, &s); err != nil {
+		return nil
+	}
+	return s
+}
+
+// APIRequest an api request received by the server
+type APIRequest struct {
+	Channel string
+	ReqID   string
+	Header  json.RawMessage
+	Param   json.RawMessage
+}
+
+// APIError error of an api response
+type APIError struct {
+	Label   string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return e.Label + ": " + e.Message
+}
+
+// APIResult response of an api request, an error response if Err is set
+type APIResult struct {
+	Result any
+	Err    *APIError
+	// Ack sends an acknowledgement before the result, as order placing channels do
+	Ack bool
+}
+
+// APIHandler serves api requests of a channel
+type APIHandler func(req APIRequest) APIResult
+
+// Server fake Gate websocket server. Subscriptions are acknowledged and recorded,
+// pushed updates are sent to subscribed connections, api requests are served by handlers.
+// Channels ending with .login authenticate the connection
+type Server struct {
+	// URL websocket url of the server, e.g. ConfOptions.URL
+	URL    string
+	Key    string
+	Secret string
+
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu            sync.Mutex
+	clock         func() time.Time
+	conns         map[*conn]struct{}
+	handlers      map[string]APIHandler
+	subscribeErrs map[string]string
+	delay         time.Duration
+	reject        bool
+	frames        []Frame
+	// closed and replaced when a frame is received
+	changed chan struct{}
+}
+
+// NewServer starts a server accepting credentials key and secret, closed by Close
+func NewServer(key, secret string) *Server {
+	s := &Server{
+		Key:           key,
+		Secret:        secret,
+		clock:         time.Now,
+		conns:         make(map[*conn]struct{}),
+		handlers:      make(map[string]APIHandler),
+		subscribeErrs: make(map[string]string),
+		changed:       make(chan struct{}),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = "ws" + strings.TrimPrefix(s.srv.URL, "http")
+	return s
+}
+
+// Close disconnects all clients and stops the server
+func (s *Server) Close() {
+	s.Disconnect()
+	s.srv.Close()
+}
+
+// Handle serves api requests of channel by h, replacing the previous handler
+func (s *Server) Handle(channel string, h APIHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[channel] = h
+}
+
+// FailSubscribe makes subscribing channel fail with message, an empty message clears it
+func (s *Server) FailSubscribe(channel, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if message == "" {
+		delete(s.subscribeErrs, channel)
+		return
+	}
+	s.subscribeErrs[channel] = message
+}
+
+// SetDelay delays every message sent by the server by d
+func (s *Server) SetDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delay = d
+}
+
+// SetClock makes the server read the time from now, e.g. the Now of a fake clock the client signs with.
+// Timestamps of signatures are checked and messages are stamped by it, time.Now by default
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock = now
+}
+
+// RejectConnections makes new connections fail with 503 while reject is true
+func (s *Server) RejectConnections(reject bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reject = reject
+}
+
+// Disconnect closes all connections, clients see a read error
+func (s *Server) Disconnect() {
+	for _, c := range s.connections() {
+		c.ws.Close()
+	}
+}
+
+// Connections returns the number of connected clients
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// Frames returns requests handled so far in order
+func (s *Server) Frames() []Frame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Frame(nil), s.frames...)
+}
+
+// WaitFrame waits until a frame matching match is handled, including ones handled before
+func (s *Server) WaitFrame(timeout time.Duration, match func(Frame) bool) (Frame, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	seen := 0
+	for {
+		s.mu.Lock()
+		frames, changed := s.frames[seen:], s.changed
+		seen = len(s.frames)
+		s.mu.Unlock()
+
+		for _, f := range frames {
+			if match(f) {
+				return f, true
+			}
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return Frame{}, false
+		}
+	}
+}
+
+// WaitSubscribed waits until channel is subscribed by a client
+func (s *Server) WaitSubscribed(channel string, timeout time.Duration) bool {
+	_, ok := s.WaitFrame(timeout, func(f Frame) bool {
+		return f.Channel == channel && f.Event == "subscribe"
+	})
+	return ok
+}
+
+// Push sends an update of channel to connections subscribed to it, returns the number of connections sent to
+func (s *Server) Push(channel string, result any) int {
+	return s.PushEvent(channel, "update", result)
+}
+
+// PushEvent sends a message of channel with event to connections subscribed to it
+func (s *Server) PushEvent(channel, event string, result any) int {
+	now := s.now()
+	msg := map[string]any{
+		"time":    now.Unix(),
+		"time_ms": now.UnixMilli(),
+		"channel": channel,
+		"event":   event,
+		"result":  result,
+	}
+
+	n := 0
+	for _, c := range s.connections() {
+		if c.subscribed(channel) {
+			if c.send(msg) == nil {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Broadcast sends raw to all connections
+func (s *Server) Broadcast(raw []byte) {
+	for _, c := range s.connections() {
+		_ = c.sendRaw(raw)
+	}
+}
+
+func (s *Server) connections() []*conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	reject := s.reject
+	s.mu.Unlock()
+	if reject {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &conn{server: s, ws: ws, subscriptions: make(map[string]bool)}
+
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		ws.Close()
+	}()
+
+	for {
+		_, raw, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.handle(raw)
+	}
+}
+
+func (s *Server) now() time.Time {
+	s.mu.Lock()
+	now := s.clock
+	s.mu.Unlock()
+	return now()
+}
+
+func (s *Server) record(f Frame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frames = append(s.frames, f)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) sign(message string) string {
+	h := hmac.New(sha512.New, []byte(s.Secret))
+	h.Write([]byte(message))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *Server) checkTime(ts int64) error {
+	if d := s.now().Sub(time.Unix(ts, 0)); d > MaxClockSkew || d < -MaxClockSkew {
+		return fmt.Errorf("timestamp %d out of range", ts)
+	}
+	return nil
+}
+
+// checkSubscribeAuth verifies the signature of a private channel subscription
+func (s *Server) checkSubscribeAuth(f Frame) error {
+	if f.Auth == nil || f.Auth.Key == "" {
+		return fmt.Errorf("auth required for %s", f.Channel)
+	}
+	if f.Auth.Key != s.Key {
+		return fmt.Errorf("invalid key")
+	}
+	if err := s.checkTime(f.Time); err != nil {
+		return err
+	}
+	// the event signed is subscribe for unsubscribing too
+	for _, event := range []string{f.Event, "subscribe"} {
+		if hmac.Equal([]byte(f.Auth.Sign), []byte(s.sign(fmt.Sprintf("channel=%s&event=%s&time=%d", f.Channel, event, f.Time)))) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid signature")
+}
+
+type apiPayload struct {
+	ApiKey    string          `json:"api_key"`
+	Signature string          `json:"signature"`
+	Timestamp string          `json:"timestamp"`
+	ReqId     string          `json:"req_id"`
+	ReqHeader json.RawMessage `json:"req_header"`
+	ReqParam  json.RawMessage `json:"req_param"`
+}
+
+// checkAPIAuth verifies the signature of an api request
+func (s *Server) checkAPIAuth(channel string, p *apiPayload) *APIError {
+	if p.ApiKey != s.Key {
+		return &APIError{Label: "INVALID_KEY", Message: "invalid key"}
+	}
+	var ts int64
+	if _, err := fmt.Sscan(p.Timestamp, &ts); err != nil {
+		return &APIError{Label: "INVALID_PARAM_VALUE", Message: "invalid timestamp"}
+	}
+	if err := s.checkTime(ts); err != nil {
+		return &APIError{Label: "REQUEST_EXPIRED", Message: err.Error()}
+	}
+	expected := s.sign(fmt.Sprintf("api\n%s\n%s\n%s", channel, p.ReqParam, p.Timestamp))
+	if !hmac.Equal([]byte(p.Signature), []byte(expected)) {
+		return &APIError{Label: "INVALID_SIGNATURE", Message: "signature mismatch"}
+	}
+	return nil
+}
+
+// conn a client connection
+type conn struct {
+	server *Server
+	ws     *websocket.Conn
+	wmu    sync.Mutex
+
+	mu            sync.Mutex
+	subscriptions map[string]bool
+	loggedIn      bool
+}
+
+func (c *conn) subscribed(channel string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.subscriptions[channel]
+}
+
+func (c *conn) send(msg any) error {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.sendRaw(raw)
+}
+
+func (c *conn) sendRaw(raw []byte) error {
+	c.server.mu.Lock()
+	delay := c.server.delay
+	c.server.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	return c.ws.WriteMessage(websocket.TextMessage, raw)
+}
+
+func (c *conn) handle(raw []byte) {
+	var f Frame
+	if err := json.Unmarshal(raw, &f); err != nil || f.Channel == "" {
+		_ = c.send(map[string]any{
+			"time":   c.server.now().Unix(),
+			"error":  map[string]any{"code": ErrCodeInvalidRequest, "message": "invalid request body"},
+			"result": nil,
+		})
+		return
+	}
+	// recorded after handled, so a subscription is active once its frame is seen
+	defer c.server.record(f)
+
+	switch {
+	case strings.HasSuffix(f.Channel, ".ping"):
+		now := c.server.now()
+		_ = c.send(map[string]any{
+			"time":    now.Unix(),
+			"time_ms": now.UnixMilli(),
+			"channel": strings.TrimSuffix(f.Channel, ".ping") + ".pong",
+			"event":   "",
+			"error":   nil,
+			"result":  nil,
+		})
+	case f.Event == "subscribe" || f.Event == "unsubscribe":
+		c.subscribe(f)
+	case f.Event == "api":
+		c.api(f)
+	default:
+		c.replyError(f, ErrCodeInvalidRequest, "unknown event "+f.Event)
+	}
+}
+
+func (c *conn) replyError(f Frame, code int, message string) {
+	now := c.server.now()
+	_ = c.send(map[string]any{
+		"time":    now.Unix(),
+		"time_ms": now.UnixMilli(),
+		"id":      f.Id,
+		"channel": f.Channel,
+		"event":   f.Event,
+		"error":   map[string]any{"code": code, "message": message},
+		"result":  map[string]any{"status": "failed"},
+	})
+}
+
+func (c *conn) subscribe(f Frame) {
+	if PrivateChannels[f.Channel] {
+		if err := c.server.checkSubscribeAuth(f); err != nil {
+			c.replyError(f, ErrCodeAuthFailed, err.Error())
+			return
+		}
+	}
+	if f.Event == "subscribe" {
+		c.server.mu.Lock()
+		message, fail := c.server.subscribeErrs[f.Channel]
+		c.server.mu.Unlock()
+		if fail {
+			c.replyError(f, ErrCodeServerError, message)
+			return
+		}
+	}
+
+	c.mu.Lock()
+	c.subscriptions[f.Channel] = f.Event == "subscribe"
+	c.mu.Unlock()
+
+	now := c.server.now()
+	_ = c.send(map[string]any{
+		"time":    now.Unix(),
+		"time_ms": now.UnixMilli(),
+		"id":      f.Id,
+		"channel": f.Channel,
+		"event":   f.Event,
+		"error":   nil,
+		"result":  map[string]any{"status": "success"},
+	})
+}
+
+func (c *conn) api(f Frame) {
+	var p apiPayload
+	if err := json.Unmarshal(f.Payload, &p); err != nil {
+		c.replyError(f, ErrCodeInvalidRequest, "invalid api payload")
+		return
+	}
+
+	c.mu.Lock()
+	loggedIn := c.loggedIn
+	c.mu.Unlock()
+
+	login := strings.HasSuffix(f.Channel, ".login")
+	// requests of a logged in connection don't need signatures
+	if login || !loggedIn || p.Signature != "" {
+		if apiErr := c.server.checkAPIAuth(f.Channel, &p); apiErr != nil {
+			c.replyAPI(f.Channel, p.ReqId, false, nil, apiErr)
+			return
+		}
+	}
+	if login {
+		c.mu.Lock()
+		c.loggedIn = true
+		c.mu.Unlock()
+		c.replyAPI(f.Channel, p.ReqId, false, map[string]any{"api_key": p.ApiKey, "uid": "1"}, nil)
+		return
+	}
+
+	c.server.mu.Lock()
+	h, ok := c.server.handlers[f.Channel]
+	c.server.mu.Unlock()
+	if !ok {
+		c.replyAPI(f.Channel, p.ReqId, false, nil, &APIError{Label: "INVALID_CHANNEL", Message: "channel not supported: " + f.Channel})
+		return
+	}
+
+	res := h(APIRequest{Channel: f.Channel, ReqID: p.ReqId, Header: p.ReqHeader, Param: p.ReqParam})
+	if res.Ack && res.Err == nil {
+		c.replyAPI(f.Channel, p.ReqId, true, map[string]any{"req_id": p.ReqId}, nil)
+	}
+	c.replyAPI(f.Channel, p.ReqId, false, res.Result, res.Err)
+}
+
+func (c *conn) replyAPI(channel, reqID string, ack bool, result any, apiErr *APIError) {
+	status := "200"
+	data := map[string]any{"result": result}
+	if apiErr != nil {
+		status = "400"
+		data = map[string]any{"errs": map[string]any{"label": apiErr.Label, "message": apiErr.Message}}
+	}
+	_ = c.send(map[string]any{
+		"request_id": reqID,
+		"ack":        ack,
+		"header": map[string]any{
+			"response_time": fmt.Sprint(c.server.now().UnixMilli()),
+			"status":        status,
+			"channel":       channel,
+			"event":         "api",
+		},
+		"data": data,
+	})
+}
@@ -0,0 +1,82 @@
+package gatewstest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dial(t *testing.T, s *Server) *websocket.Conn {
+	t.Helper()
+	c, _, err := websocket.DefaultDialer.Dial(s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func roundTrip(t *testing.T, c *websocket.Conn, req string) map[string]any {
+	t.Helper()
+	if err := c.WriteMessage(websocket.TextMessage, []byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, raw, err := c.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer("KEY", "SECRET")
+	defer s.Close()
+	c := dial(t, s)
+
+	resp := roundTrip(t, c, `{"time":1,"channel":"spot.trades","event":"subscribe","payload":["BTC_USDT"]}`)
+	if resp["error"] != nil || resp["result"].(map[string]any)["status"] != "success" {
+		t.Fatalf("subscribe %v", resp)
+	}
+	resp = roundTrip(t, c, `{"time":1,"channel":"spot.orders","event":"subscribe","payload":["BTC_USDT"]}`)
+	if code := resp["error"].(map[string]any)["code"]; code != float64(ErrCodeAuthFailed) {
+		t.Fatalf("private subscribe without auth %v", resp)
+	}
+	resp = roundTrip(t, c, `{"time":1,"channel":"spot.order_place","event":"api","payload":{"api_key":"KEY","signature":"x","timestamp":"1","req_id":"1","req_param":{}}}`)
+	if status := resp["header"].(map[string]any)["status"]; status != "400" {
+		t.Fatalf("api with invalid signature %v", resp)
+	}
+
+	s.FailSubscribe("spot.tickers", "maintenance")
+	resp = roundTrip(t, c, `{"time":1,"channel":"spot.tickers","event":"subscribe","payload":["BTC_USDT"]}`)
+	if msg := resp["error"].(map[string]any)["message"]; msg != "maintenance" {
+		t.Fatalf("failed subscribe %v", resp)
+	}
+
+	if n := s.Push("spot.trades", map[string]any{"id": 1}); n != 1 {
+		t.Fatalf("pushed to %d", n)
+	}
+	if len(s.Frames()) != 4 {
+		t.Fatalf("frames %v", s.Frames())
+	}
+
+	s.RejectConnections(true)
+	if _, _, err := websocket.DefaultDialer.Dial(s.URL, nil); err == nil {
+		t.Fatal("connection not rejected")
+	}
+	s.RejectConnections(false)
+
+	s.Disconnect()
+	if _, _, err := c.ReadMessage(); err != nil {
+		t.Fatal("pushed update not received before disconnect")
+	}
+	if _, _, err := c.ReadMessage(); err == nil {
+		t.Fatal("not disconnected")
+	}
+}
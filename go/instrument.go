@@ -0,0 +1,415 @@
+package gatews
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+// CurrencyPairInfo spot currency pair metadata, the same as GET /spot/currency_pairs
+type CurrencyPairInfo struct {
+	Id    string `json:"id"`
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+	// Fee trading fee rate
+	Fee            decimal.Decimal `json:"fee"`
+	MinBaseAmount  decimal.Decimal `json:"min_base_amount"`
+	MinQuoteAmount decimal.Decimal `json:"min_quote_amount"`
+	MaxBaseAmount  decimal.Decimal `json:"max_base_amount"`
+	MaxQuoteAmount decimal.Decimal `json:"max_quote_amount"`
+	// AmountPrecision decimal places of amount
+	AmountPrecision int32 `json:"amount_precision"`
+	// Precision decimal places of price
+	Precision int32 `json:"precision"`
+	// TradeStatus untradable, buyable, sellable or tradable
+	TradeStatus string `json:"trade_status"`
+}
+
+// RoundPrice rounds price half away from zero to valid decimal places
+func (p *CurrencyPairInfo) RoundPrice(price decimal.Decimal) decimal.Decimal {
+	return price.Round(p.Precision)
+}
+
+// FloorPrice rounds price down to valid decimal places, e.g. for a passive bid
+func (p *CurrencyPairInfo) FloorPrice(price decimal.Decimal) decimal.Decimal {
+	return price.RoundFloor(p.Precision)
+}
+
+// CeilPrice rounds price up to valid decimal places, e.g. for a passive ask
+func (p *CurrencyPairInfo) CeilPrice(price decimal.Decimal) decimal.Decimal {
+	return price.RoundCeil(p.Precision)
+}
+
+// TruncateAmount rounds amount toward zero to valid decimal places so it never exceeds the given amount
+func (p *CurrencyPairInfo) TruncateAmount(amount decimal.Decimal) decimal.Decimal {
+	return amount.Truncate(p.AmountPrecision)
+}
+
+// ContractInfo futures contract metadata, the same as GET /futures/{settle}/contracts
+type ContractInfo struct {
+	Name string `json:"name"`
+	// Type inverse or direct
+	Type string `json:"type"`
+	// QuantoMultiplier base currency of one contract
+	QuantoMultiplier decimal.Decimal `json:"quanto_multiplier"`
+	OrderPriceRound  decimal.Decimal `json:"order_price_round"`
+	MarkPriceRound   decimal.Decimal `json:"mark_price_round"`
+	OrderSizeMin     int64           `json:"order_size_min"`
+	OrderSizeMax     int64           `json:"order_size_max"`
+	MakerFeeRate     decimal.Decimal `json:"maker_fee_rate"`
+	TakerFeeRate     decimal.Decimal `json:"taker_fee_rate"`
+	LeverageMin      decimal.Decimal `json:"leverage_min"`
+	LeverageMax      decimal.Decimal `json:"leverage_max"`
+	MaintenanceRate  decimal.Decimal `json:"maintenance_rate"`
+	InDelisting      bool            `json:"in_delisting"`
+}
+
+// RoundPrice rounds price half away from zero to a multiple of order_price_round
+func (c *ContractInfo) RoundPrice(price decimal.Decimal) decimal.Decimal {
+	return price.RoundStep(c.OrderPriceRound)
+}
+
+// FloorPrice rounds price down to a multiple of order_price_round
+func (c *ContractInfo) FloorPrice(price decimal.Decimal) decimal.Decimal {
+	return price.FloorStep(c.OrderPriceRound)
+}
+
+// CeilPrice rounds price up to a multiple of order_price_round
+func (c *ContractInfo) CeilPrice(price decimal.Decimal) decimal.Decimal {
+	return price.CeilStep(c.OrderPriceRound)
+}
+
+func (c *ContractInfo) inverse() bool {
+	return c.Type == "inverse"
+}
+
+// multiplier value of one contract, in base currency for direct contracts and in quote currency for inverse ones
+func (c *ContractInfo) multiplier() decimal.Decimal {
+	if c.QuantoMultiplier.IsZero() {
+		return decimal.One
+	}
+	return c.QuantoMultiplier
+}
+
+// BaseAmount converts size of contracts to base currency at price, price is only used by inverse contracts
+func (c *ContractInfo) BaseAmount(size int64, price decimal.Decimal) decimal.Decimal {
+	value := decimal.NewFromInt(size).Mul(c.multiplier())
+	if !c.inverse() {
+		return value
+	}
+	if price.IsZero() {
+		return decimal.Zero
+	}
+	return value.Div(price)
+}
+
+// QuoteAmount converts size of contracts to notional in quote currency at price
+func (c *ContractInfo) QuoteAmount(size int64, price decimal.Decimal) decimal.Decimal {
+	value := decimal.NewFromInt(size).Mul(c.multiplier())
+	if c.inverse() {
+		return value
+	}
+	return value.Mul(price)
+}
+
+// SizeForBase converts amount in base currency to size of contracts, rounded toward zero
+func (c *ContractInfo) SizeForBase(amount, price decimal.Decimal) int64 {
+	if c.inverse() {
+		return amount.Mul(price).Div(c.multiplier()).IntPart()
+	}
+	return amount.Div(c.multiplier()).IntPart()
+}
+
+// InstrumentFetcher fetches instruments metadata, e.g. from the REST API
+type InstrumentFetcher interface {
+	Fetch(ctx context.Context) ([]CurrencyPairInfo, []ContractInfo, error)
+}
+
+// InstrumentFetcherFunc adapts a function to InstrumentFetcher
+type InstrumentFetcherFunc func(ctx context.Context) ([]CurrencyPairInfo, []ContractInfo, error)
+
+func (f InstrumentFetcherFunc) Fetch(ctx context.Context) ([]CurrencyPairInfo, []ContractInfo, error) {
+	return f(ctx)
+}
+
+// HTTPInstrumentFetcher fetches currency pairs and contracts of settle currencies from the Gate REST API
+type HTTPInstrumentFetcher struct {
+	// URL default RestBaseUrl
+	URL string
+	// Settles settle currencies of futures contracts, e.g. usdt and btc. Contracts are not fetched if empty
+	Settles []string
+	// SkipSpot skip fetching spot currency pairs
+	SkipSpot bool
+	Client   *http.Client
+}
+
+func (f *HTTPInstrumentFetcher) Fetch(ctx context.Context) ([]CurrencyPairInfo, []ContractInfo, error) {
+	var pairs []CurrencyPairInfo
+	if !f.SkipSpot {
+		if err := f.get(ctx, "/spot/currency_pairs", &pairs); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var contracts []ContractInfo
+	for _, settle := range f.Settles {
+		var cs []ContractInfo
+		if err := f.get(ctx, "/futures/"+strings.ToLower(settle)+"/contracts", &cs); err != nil {
+			return nil, nil, err
+		}
+		contracts = append(contracts, cs...)
+	}
+
+	return pairs, contracts, nil
+}
+
+func (f *HTTPInstrumentFetcher) get(ctx context.Context, path string, v any) error {
+	url := f.URL
+	if url == "" {
+		url = RestBaseUrl
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(url, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("GET %s: %s %s", path, res.Status, body)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// Instruments registry of currency pairs and futures contracts metadata, safe for concurrent use
+type Instruments struct {
+	mu        sync.RWMutex
+	pairs     map[string]CurrencyPairInfo
+	contracts map[string]ContractInfo
+}
+
+// instrumentsFile format of instruments JSON files
+type instrumentsFile struct {
+	CurrencyPairs []CurrencyPairInfo `json:"currency_pairs"`
+	Contracts     []ContractInfo     `json:"contracts"`
+}
+
+func NewInstruments() *Instruments {
+	return &Instruments{
+		pairs:     make(map[string]CurrencyPairInfo),
+		contracts: make(map[string]ContractInfo),
+	}
+}
+
+// LoadInstrumentsFile loads instruments from a JSON file like {"currency_pairs": [...], "contracts": [...]},
+// items are in the same format as the REST API responses
+func LoadInstrumentsFile(path string) (*Instruments, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ins := NewInstruments()
+	if err := ins.Load(f); err != nil {
+		return nil, fmt.Errorf("load instruments from %s: %w", path, err)
+	}
+	return ins, nil
+}
+
+// Load adds instruments in JSON read from r, existing ones with the same name are replaced
+func (i *Instruments) Load(r io.Reader) error {
+	var file instrumentsFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return err
+	}
+	i.Set(file.CurrencyPairs, file.Contracts)
+	return nil
+}
+
+// Refresh fetches instruments by fetcher and adds them to the registry
+func (i *Instruments) Refresh(ctx context.Context, fetcher InstrumentFetcher) error {
+	pairs, contracts, err := fetcher.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	i.Set(pairs, contracts)
+	return nil
+}
+
+// Set adds or replaces currency pairs and contracts
+func (i *Instruments) Set(pairs []CurrencyPairInfo, contracts []ContractInfo) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, p := range pairs {
+		i.pairs[p.Id] = p
+	}
+	for _, c := range contracts {
+		i.contracts[c.Name] = c
+	}
+}
+
+func (i *Instruments) CurrencyPair(id string) (CurrencyPairInfo, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	p, ok := i.pairs[id]
+	return p, ok
+}
+
+func (i *Instruments) Contract(name string) (ContractInfo, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	c, ok := i.contracts[name]
+	return c, ok
+}
+
+// ValidateOrder checks price and amount of a spot order against precision and limits of its currency pair.
+// Currency pairs not in the registry are not checked
+func (i *Instruments) ValidateOrder(o *model.Order) error {
+	pair, ok := i.CurrencyPair(o.CurrencyPair)
+	if !ok {
+		return nil
+	}
+
+	var errs model.ValidationErrors
+	add := func(field, format string, args ...any) {
+		errs = append(errs, model.FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	switch pair.TradeStatus {
+	case "untradable":
+		add("currency_pair", "%s is not tradable", pair.Id)
+	case "buyable":
+		if o.Side == model.SideSell {
+			add("side", "%s is buyable only", pair.Id)
+		}
+	case "sellable":
+		if o.Side == model.SideBuy {
+			add("side", "%s is sellable only", pair.Id)
+		}
+	}
+
+	price, priceErr := decimal.NewFromString(o.Price)
+	if priceErr == nil && !price.Round(pair.Precision).Equal(price) {
+		add("price", "must have at most %d decimal places", pair.Precision)
+	}
+
+	amount, err := decimal.NewFromString(o.Amount)
+	if err != nil {
+		return validationErr(errs)
+	}
+
+	if o.Type == model.OrderTypeMarket {
+		// amount of market buy orders is in quote currency
+		if o.Side == model.SideBuy {
+			if !pair.MinQuoteAmount.IsZero() && amount.LessThan(pair.MinQuoteAmount) {
+				add("amount", "must be at least %s %s", pair.MinQuoteAmount, pair.Quote)
+			}
+			return validationErr(errs)
+		}
+	} else if priceErr == nil {
+		quote := amount.Mul(price)
+		if !pair.MinQuoteAmount.IsZero() && quote.LessThan(pair.MinQuoteAmount) {
+			add("amount", "total must be at least %s %s", pair.MinQuoteAmount, pair.Quote)
+		}
+		if !pair.MaxQuoteAmount.IsZero() && quote.GreaterThan(pair.MaxQuoteAmount) {
+			add("amount", "total must be at most %s %s", pair.MaxQuoteAmount, pair.Quote)
+		}
+	}
+
+	if !amount.Truncate(pair.AmountPrecision).Equal(amount) {
+		add("amount", "must have at most %d decimal places", pair.AmountPrecision)
+	}
+	if !pair.MinBaseAmount.IsZero() && amount.LessThan(pair.MinBaseAmount) {
+		add("amount", "must be at least %s %s", pair.MinBaseAmount, pair.Base)
+	}
+	if !pair.MaxBaseAmount.IsZero() && amount.GreaterThan(pair.MaxBaseAmount) {
+		add("amount", "must be at most %s %s", pair.MaxBaseAmount, pair.Base)
+	}
+
+	return validationErr(errs)
+}
+
+// ValidateFuturesOrder checks price and size of a futures order against its contract.
+// Contracts not in the registry are not checked
+func (i *Instruments) ValidateFuturesOrder(o *model.FuturesOrder) error {
+	c, ok := i.Contract(o.Contract)
+	if !ok {
+		return nil
+	}
+
+	var errs model.ValidationErrors
+	add := func(field, format string, args ...any) {
+		errs = append(errs, model.FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if price, err := decimal.NewFromString(o.Price); err == nil && !price.IsZero() && !c.OrderPriceRound.IsZero() {
+		if !price.RoundStep(c.OrderPriceRound).Equal(price) {
+			add("price", "must be a multiple of %s", c.OrderPriceRound)
+		}
+	}
+
+	size := o.Size
+	if size < 0 {
+		size = -size
+	}
+	if size != 0 {
+		if c.OrderSizeMin > 0 && size < c.OrderSizeMin {
+			add("size", "must be at least %d", c.OrderSizeMin)
+		}
+		if c.OrderSizeMax > 0 && size > c.OrderSizeMax {
+			add("size", "must be at most %d", c.OrderSizeMax)
+		}
+		if c.InDelisting && !o.ReduceOnly && !o.Close {
+			add("contract", "%s is in delisting, only reduce-only orders are allowed", c.Name)
+		}
+	}
+
+	return validationErr(errs)
+}
+
+// validatePayload validates orders in payload of order placing channels
+func (i *Instruments) validatePayload(channel string, payload any) error {
+	if channel != ChannelSpotOrderPlace && channel != ChannelFutureOrderPlace && channel != ChannelFutureOrderBatchPlace {
+		return nil
+	}
+
+	check := func(v any) error {
+		switch o := v.(type) {
+		case *model.Order:
+			return i.ValidateOrder(o)
+		case *model.FuturesOrder:
+			return i.ValidateFuturesOrder(o)
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(payload)
+	if rv.Kind() == reflect.Slice {
+		return validateElems(rv, check)
+	}
+	return check(pointerTo(rv))
+}
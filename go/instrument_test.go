@@ -0,0 +1,88 @@
+package gatews
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+const testInstruments = `{
+	"currency_pairs": [
+		{"id":"BTC_USDT","base":"BTC","quote":"USDT","min_base_amount":"0.0001","min_quote_amount":"3","amount_precision":4,"precision":1,"trade_status":"tradable"}
+	],
+	"contracts": [
+		{"name":"BTC_USDT","type":"direct","quanto_multiplier":"0.0001","order_price_round":"0.1","order_size_min":1,"order_size_max":1000000},
+		{"name":"BTC_USD","type":"inverse","quanto_multiplier":"0","order_price_round":"0.5","order_size_min":1,"order_size_max":1000000}
+	]
+}`
+
+func TestInstrumentsValidate(t *testing.T) {
+	ins := NewInstruments()
+	if err := ins.Load(strings.NewReader(testInstruments)); err != nil {
+		t.Fatal(err)
+	}
+
+	order := &model.Order{CurrencyPair: "BTC_USDT", Side: model.SideBuy, Amount: "0.001", Price: "30000.1"}
+	if err := ins.ValidateOrder(order); err != nil {
+		t.Fatal(err)
+	}
+
+	var errs model.ValidationErrors
+	order = &model.Order{CurrencyPair: "BTC_USDT", Side: model.SideBuy, Amount: "0.00005", Price: "30000.15"}
+	if err := ins.ValidateOrder(order); !errors.As(err, &errs) || !errs.Has("price") || !errs.Has("amount") {
+		t.Fatalf("expected errors of price and amount, got %v", err)
+	}
+
+	futures := &model.FuturesOrder{Contract: "BTC_USD", Size: 10, Price: "30000.3"}
+	if err := ins.ValidateFuturesOrder(futures); !errors.As(err, &errs) || !errs.Has("price") {
+		t.Fatalf("expected error of price, got %v", err)
+	}
+
+	batch := []*model.FuturesOrder{{Contract: "BTC_USDT", Size: 1, Price: "1.1"}, {Contract: "UNKNOWN", Size: 1, Price: "1.123"}}
+	if err := ins.validatePayload(ChannelFutureOrderBatchPlace, batch); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContractInfoConversion(t *testing.T) {
+	ins := NewInstruments()
+	err := ins.Refresh(context.Background(), InstrumentFetcherFunc(func(ctx context.Context) ([]CurrencyPairInfo, []ContractInfo, error) {
+		return nil, []ContractInfo{
+			{Name: "BTC_USDT", Type: "direct", QuantoMultiplier: decimal.RequireFromString("0.0001"), OrderPriceRound: decimal.RequireFromString("0.1")},
+			{Name: "BTC_USD", Type: "inverse", OrderPriceRound: decimal.RequireFromString("0.5")},
+		}, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	price := decimal.RequireFromString("25000")
+	direct, _ := ins.Contract("BTC_USDT")
+	if got := direct.BaseAmount(-30, price); got.String() != "-0.003" {
+		t.Errorf("base amount = %s", got)
+	}
+	if got := direct.QuoteAmount(30, price); got.String() != "75" {
+		t.Errorf("quote amount = %s", got)
+	}
+	if got := direct.SizeForBase(decimal.RequireFromString("0.00355"), price); got != 35 {
+		t.Errorf("size = %d", got)
+	}
+	if got := direct.FloorPrice(decimal.RequireFromString("25000.17")); got.String() != "25000.1" {
+		t.Errorf("floor price = %s", got)
+	}
+
+	inverse, _ := ins.Contract("BTC_USD")
+	if got := inverse.BaseAmount(100, price); got.String() != "0.004" {
+		t.Errorf("base amount = %s", got)
+	}
+	if got := inverse.QuoteAmount(100, price); got.String() != "100" {
+		t.Errorf("quote amount = %s", got)
+	}
+	if got := inverse.RoundPrice(decimal.RequireFromString("25000.3")); got.String() != "25000.5" {
+		t.Errorf("round price = %s", got)
+	}
+}
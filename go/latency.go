@@ -0,0 +1,219 @@
+package gatews
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gateio/gatews/go/model"
+)
+
+const (
+	DefaultLatencySamples = 1000
+	// clock offset is estimated from the ping with the lowest round trip among the recent ones
+	clockOffsetSamples = 16
+)
+
+// LatencyStats percentiles of latency samples
+type LatencyStats struct {
+	Count int
+	Min   time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// LatencySummary latency of messages of a channel.
+// Network is from the exchange event time to received, corrected by the clock offset if known.
+// Dispatch is from received to the start of the callback
+type LatencySummary struct {
+	Channel  string
+	Network  LatencyStats
+	Dispatch LatencyStats
+}
+
+// ClockOffset estimated offset of the exchange clock, exchange time is local time + Offset
+type ClockOffset struct {
+	Offset time.Duration
+	// RTT round trip of the ping the offset is estimated from, the error is at most RTT/2
+	RTT time.Duration
+	At  time.Time
+}
+
+type latencySamples struct {
+	samples []time.Duration // ring buffer
+	next    int
+}
+
+func (s *latencySamples) add(d time.Duration, capacity int) {
+	if len(s.samples) < capacity {
+		s.samples = append(s.samples, d)
+		return
+	}
+	s.samples[s.next] = d
+	s.next = (s.next + 1) % capacity
+}
+
+func (s *latencySamples) stats() LatencyStats {
+	if len(s.samples) == 0 {
+		return LatencyStats{}
+	}
+	sorted := make([]time.Duration, len(s.samples))
+	copy(sorted, s.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1)+0.5)]
+	}
+	return LatencyStats{
+		Count: len(sorted),
+		Min:   sorted[0],
+		P50:   percentile(0.5),
+		P90:   percentile(0.9),
+		P99:   percentile(0.99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+type channelLatency struct {
+	network  latencySamples
+	dispatch latencySamples
+}
+
+// LatencyTracker keeps recent latency samples per channel and estimates the clock offset
+// to the exchange from ping replies
+type LatencyTracker struct {
+	mu       sync.Mutex
+	samples  int
+	channels map[string]*channelLatency
+
+	pings   map[string]time.Time // ping send time by app
+	offsets []ClockOffset
+}
+
+// NewLatencyTracker samples is the max samples kept per channel, DefaultLatencySamples is used if samples <= 0
+func NewLatencyTracker(samples int) *LatencyTracker {
+	if samples <= 0 {
+		samples = DefaultLatencySamples
+	}
+	return &LatencyTracker{
+		samples:  samples,
+		channels: make(map[string]*channelLatency),
+		pings:    make(map[string]time.Time),
+	}
+}
+
+// ObserveMessage records the latency of msg whose callback starts at callbackStart
+func (l *LatencyTracker) ObserveMessage(msg *UpdateMsg, callbackStart time.Time) {
+	if msg.ReceivedAt.IsZero() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	channel := msg.GetChannel()
+	c, ok := l.channels[channel]
+	if !ok {
+		c = &channelLatency{}
+		l.channels[channel] = c
+	}
+	if exchange := msg.ExchangeTime(); !exchange.IsZero() {
+		network := exchange.Latency(msg.ReceivedAt)
+		if offset, ok := l.clockOffset(); ok {
+			network += offset.Offset
+		}
+		c.network.add(network, l.samples)
+	}
+	c.dispatch.add(callbackStart.Sub(msg.ReceivedAt), l.samples)
+}
+
+// ObservePing estimates the clock offset from a ping sent at sent and replied with server time at received
+func (l *LatencyTracker) ObservePing(sent time.Time, server model.Timestamp, received time.Time) {
+	if server.IsZero() || received.Before(sent) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rtt := received.Sub(sent)
+	mid := sent.Add(rtt / 2)
+	l.offsets = append(l.offsets, ClockOffset{Offset: server.Time().Sub(mid), RTT: rtt, At: received})
+	if len(l.offsets) > clockOffsetSamples {
+		l.offsets = l.offsets[len(l.offsets)-clockOffsetSamples:]
+	}
+}
+
+func (l *LatencyTracker) clockOffset() (ClockOffset, bool) {
+	if len(l.offsets) == 0 {
+		return ClockOffset{}, false
+	}
+	best := l.offsets[0]
+	for _, o := range l.offsets[1:] {
+		if o.RTT < best.RTT {
+			best = o
+		}
+	}
+	return best, true
+}
+
+// ClockOffset returns the estimated clock offset, false if no ping is replied yet
+func (l *LatencyTracker) ClockOffset() (ClockOffset, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.clockOffset()
+}
+
+// Summary returns latency of channel, false if no message of it is observed
+func (l *LatencyTracker) Summary(channel string) (LatencySummary, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.channels[channel]
+	if !ok {
+		return LatencySummary{}, false
+	}
+	return LatencySummary{Channel: channel, Network: c.network.stats(), Dispatch: c.dispatch.stats()}, true
+}
+
+// Summaries returns latency of all observed channels, sorted by channel
+func (l *LatencyTracker) Summaries() []LatencySummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	summaries := make([]LatencySummary, 0, len(l.channels))
+	for channel, c := range l.channels {
+		summaries = append(summaries, LatencySummary{Channel: channel, Network: c.network.stats(), Dispatch: c.dispatch.stats()})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Channel < summaries[j].Channel })
+	return summaries
+}
+
+// pingSent records the send time of the ping of app, e.g. spot
+func (l *LatencyTracker) pingSent(app string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pings[app] = at
+}
+
+// observePong matches a spot.pong or futures.pong reply to the last ping of its app
+func (l *LatencyTracker) observePong(msg *UpdateMsg) {
+	app := strings.TrimSuffix(msg.Channel, ".pong")
+	if app == msg.Channel {
+		return
+	}
+
+	l.mu.Lock()
+	sent, ok := l.pings[app]
+	delete(l.pings, app)
+	l.mu.Unlock()
+
+	if ok {
+		l.ObservePing(sent, msg.ExchangeTime(), msg.ReceivedAt)
+	}
+}
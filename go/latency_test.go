@@ -0,0 +1,65 @@
+package gatews
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/model"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	tracker := NewLatencyTracker(100)
+	local := time.Unix(1700000000, 0)
+
+	// the exchange clock is 200ms ahead, the ping with the lowest rtt wins
+	tracker.pingSent("spot", local)
+	tracker.observePong(&UpdateMsg{
+		Channel:    "spot.pong",
+		TimeMs:     model.NewTimestamp(local.Add(250 * time.Millisecond)),
+		ReceivedAt: local.Add(100 * time.Millisecond),
+	})
+	tracker.ObservePing(local, model.NewTimestamp(local.Add(230*time.Millisecond)), local.Add(60*time.Millisecond))
+	offset, ok := tracker.ClockOffset()
+	if !ok || offset.Offset != 200*time.Millisecond || offset.RTT != 60*time.Millisecond {
+		t.Fatalf("offset %+v", offset)
+	}
+
+	for i := 1; i <= 100; i++ {
+		received := local.Add(time.Second)
+		tracker.ObserveMessage(&UpdateMsg{
+			Channel: ChannelSpotPublicTrade,
+			// the exchange event happens i ms before received in local time
+			TimeMs:     model.NewTimestamp(received.Add(200*time.Millisecond - time.Duration(i)*time.Millisecond)),
+			ReceivedAt: received,
+		}, received.Add(time.Duration(i)*time.Microsecond))
+	}
+	tracker.ObserveMessage(&UpdateMsg{Channel: ChannelSpotPublicTrade}, local)
+
+	s, ok := tracker.Summary(ChannelSpotPublicTrade)
+	if !ok {
+		t.Fatal("no summary")
+	}
+	if s.Network.Count != 100 || s.Network.Min != time.Millisecond || s.Network.P50 != 51*time.Millisecond ||
+		s.Network.P99 != 99*time.Millisecond || s.Network.Max != 100*time.Millisecond {
+		t.Fatalf("network %+v", s.Network)
+	}
+	if s.Dispatch.P90 != 90*time.Microsecond {
+		t.Fatalf("dispatch %+v", s.Dispatch)
+	}
+	if _, ok := tracker.Summary(ChannelSpotOrderBook); ok {
+		t.Fatal("summary of unobserved channel")
+	}
+	if len(tracker.Summaries()) != 1 {
+		t.Fatal("unexpected summaries")
+	}
+}
+
+func TestLatencySamplesBounded(t *testing.T) {
+	var s latencySamples
+	for i := 0; i < 10; i++ {
+		s.add(time.Duration(i), 4)
+	}
+	if stats := s.stats(); stats.Count != 4 || stats.Min != 6 || stats.Max != 9 {
+		t.Fatalf("stats %+v", stats)
+	}
+}
@@ -0,0 +1,298 @@
+package gatews
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+const (
+	AccountSpot    = "spot"
+	AccountMargin  = "margin"
+	AccountFunding = "funding"
+	AccountCross   = "cross_margin"
+
+	DefaultLedgerHistory = 1000
+)
+
+// BalanceKey identifies a balance, Market is only set for margin accounts
+type BalanceKey struct {
+	Account  string
+	Market   string
+	Currency string
+}
+
+// Balance of a currency in an account
+type Balance struct {
+	BalanceKey
+	Available decimal.Decimal
+	Frozen    decimal.Decimal
+	Total     decimal.Decimal
+	// Borrowed and Interest of margin accounts
+	Borrowed decimal.Decimal
+	Interest decimal.Decimal
+	// Lent of funding accounts
+	Lent       decimal.Decimal
+	UpdateTime time.Time
+}
+
+// BalanceChange a balance update applied to the ledger
+type BalanceChange struct {
+	BalanceKey
+	Change       decimal.Decimal
+	FreezeChange decimal.Decimal
+	ChangeType   string
+	Time         time.Time
+	// Expected total by the previous total and change, only if Checked as the previous total is known
+	Expected decimal.Decimal
+	Checked  bool
+	// Balance after the change
+	Balance Balance
+}
+
+// Mismatched reports whether the reported total differs from the previous total plus change
+func (c *BalanceChange) Mismatched() bool {
+	return c.Checked && !c.Expected.Equal(c.Balance.Total)
+}
+
+// BalanceMismatch a total in the ledger differs from the reported one
+type BalanceMismatch struct {
+	BalanceKey
+	Ledger   decimal.Decimal
+	Reported decimal.Decimal
+}
+
+type ledgerEntry struct {
+	Balance
+	// known once seeded or updated with a total
+	known bool
+	// changes applied at UpdateTime, to drop duplicates with the same time
+	applied map[balanceUpdate]bool
+}
+
+type balanceUpdate struct {
+	change string
+	total  string
+	freeze string
+}
+
+// Ledger maintains balances of spot, margin, funding and cross margin accounts from balance channels.
+// Updates are applied in time order, updates older than the balance or already applied are ignored
+type Ledger struct {
+	mu       sync.RWMutex
+	entries  map[BalanceKey]*ledgerEntry
+	history  []BalanceChange
+	capacity int
+}
+
+// NewLedger keeps at most history recent changes, DefaultLedgerHistory if history <= 0
+func NewLedger(history int) *Ledger {
+	if history <= 0 {
+		history = DefaultLedgerHistory
+	}
+	return &Ledger{
+		entries:  make(map[BalanceKey]*ledgerEntry),
+		capacity: history,
+	}
+}
+
+// Seed sets balances from a snapshot, e.g. of the REST API. Updates older than UpdateTime of a balance are ignored
+func (l *Ledger) Seed(balances []Balance) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, b := range balances {
+		l.entries[b.BalanceKey] = &ledgerEntry{Balance: b, known: true}
+	}
+}
+
+// apply applies an update at ts by set, reports false if the update is stale or duplicated.
+// withTotal reports whether the update has the total, otherwise the balance is known only once seeded
+func (l *Ledger) apply(key BalanceKey, ts time.Time, u balanceUpdate, change BalanceChange, withTotal bool, set func(b *Balance)) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		e = &ledgerEntry{Balance: Balance{BalanceKey: key}}
+		l.entries[key] = e
+	}
+	switch {
+	case ts.Before(e.UpdateTime):
+		return false
+	case ts.Equal(e.UpdateTime) && e.applied[u]:
+		return false
+	case ts.After(e.UpdateTime):
+		e.applied = nil
+	}
+	if e.applied == nil {
+		e.applied = make(map[balanceUpdate]bool)
+	}
+	e.applied[u] = true
+
+	if e.known {
+		change.Expected, change.Checked = e.Total.Add(change.Change), true
+	}
+	set(&e.Balance)
+	e.known = e.known || withTotal
+	e.UpdateTime = ts
+
+	change.BalanceKey = key
+	change.Time = ts
+	change.Balance = e.Balance
+	l.history = append(l.history, change)
+	if len(l.history) > l.capacity {
+		l.history = append(l.history[:0:0], l.history[len(l.history)-l.capacity:]...)
+	}
+	return true
+}
+
+// ApplySpotBalance applies an update of spot.balances
+func (l *Ledger) ApplySpotBalance(msg *SpotBalancesMsg) bool {
+	return l.applyBalance(AccountSpot, msg)
+}
+
+// ApplyCrossBalance applies an update of spot.cross_balances, which has the same fields as spot.balances
+func (l *Ledger) ApplyCrossBalance(msg *SpotBalancesMsg) bool {
+	return l.applyBalance(AccountCross, msg)
+}
+
+func (l *Ledger) applyBalance(account string, msg *SpotBalancesMsg) bool {
+	key := BalanceKey{Account: account, Currency: msg.Asset}
+	ts := tradeTime(msg.TimestampInMilli, msg.Timestamp)
+	total, available, freeze := parseDecimal(msg.Total), parseDecimal(msg.Available), parseDecimal(msg.Freeze)
+	return l.apply(key, ts, balanceUpdate{msg.Change, msg.Total, msg.Freeze}, BalanceChange{
+		Change:       parseDecimal(msg.Change),
+		FreezeChange: parseDecimal(msg.FreezeChange),
+		ChangeType:   msg.ChangeType,
+	}, true, func(b *Balance) {
+		b.Total, b.Available, b.Frozen = total, available, freeze
+	})
+}
+
+// ApplyMarginBalance applies an update of spot.margin_balances
+func (l *Ledger) ApplyMarginBalance(msg *SpotMarginBalancesMsg) bool {
+	key := BalanceKey{Account: AccountMargin, Market: msg.Market, Currency: msg.Asset}
+	ts := tradeTime(msg.TimestampInMilli, msg.Timestamp)
+	available, freeze := parseDecimal(msg.Available), parseDecimal(msg.Freeze)
+	borrowed, interest := parseDecimal(msg.Borrowed), parseDecimal(msg.Interest)
+	return l.apply(key, ts, balanceUpdate{msg.Change, msg.Available, msg.Freeze}, BalanceChange{
+		Change: parseDecimal(msg.Change),
+	}, true, func(b *Balance) {
+		b.Available, b.Frozen, b.Total = available, freeze, available.Add(freeze)
+		b.Borrowed, b.Interest = borrowed, interest
+	})
+}
+
+// ApplyFundingBalance applies an update of spot.funding_balances, which has no total,
+// so the total is accumulated from changes and the balance is unknown until seeded
+func (l *Ledger) ApplyFundingBalance(msg *SpotFundingBalancesMsg) bool {
+	key := BalanceKey{Account: AccountFunding, Currency: msg.Asset}
+	ts := tradeTime(msg.TimestampInMilli, msg.Timestamp)
+	change, freeze, lent := parseDecimal(msg.Change), parseDecimal(msg.Freeze), parseDecimal(msg.Lent)
+	return l.apply(key, ts, balanceUpdate{msg.Change, "", msg.Freeze}, BalanceChange{
+		Change: change,
+	}, false, func(b *Balance) {
+		b.Total = b.Total.Add(change)
+		b.Frozen, b.Lent = freeze, lent
+		b.Available = b.Total.Sub(freeze).Sub(lent)
+	})
+}
+
+// Balance returns the balance of key
+func (l *Ledger) Balance(key BalanceKey) (Balance, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	e, ok := l.entries[key]
+	if !ok || !e.known {
+		return Balance{}, false
+	}
+	return e.Balance, true
+}
+
+// Balances returns balances of account, all accounts if account is empty
+func (l *Ledger) Balances(account string) []Balance {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var balances []Balance
+	for key, e := range l.entries {
+		if e.known && (account == "" || key.Account == account) {
+			balances = append(balances, e.Balance)
+		}
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		a, b := balances[i].BalanceKey, balances[j].BalanceKey
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		if a.Market != b.Market {
+			return a.Market < b.Market
+		}
+		return a.Currency < b.Currency
+	})
+	return balances
+}
+
+// History returns at most n recent changes of key, oldest first. Zero fields of key match any, n <= 0 returns all
+func (l *Ledger) History(key BalanceKey, n int) []BalanceChange {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var changes []BalanceChange
+	for i := len(l.history) - 1; i >= 0 && (n <= 0 || len(changes) < n); i-- {
+		c := l.history[i]
+		if (key.Account == "" || key.Account == c.Account) &&
+			(key.Market == "" || key.Market == c.Market) &&
+			(key.Currency == "" || key.Currency == c.Currency) {
+			changes = append(changes, c)
+		}
+	}
+	for i, j := 0, len(changes)-1; i < j; i, j = i+1, j-1 {
+		changes[i], changes[j] = changes[j], changes[i]
+	}
+	return changes
+}
+
+// Reconcile compares totals in the ledger with reported balances, e.g. of the REST API,
+// balances missing or unknown in the ledger are compared as zero
+func (l *Ledger) Reconcile(reported []Balance) []BalanceMismatch {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var mismatches []BalanceMismatch
+	for _, r := range reported {
+		var total decimal.Decimal
+		if e, ok := l.entries[r.BalanceKey]; ok && e.known {
+			total = e.Total
+		}
+		if !total.Equal(r.Total) {
+			mismatches = append(mismatches, BalanceMismatch{BalanceKey: r.BalanceKey, Ledger: total, Reported: r.Total})
+		}
+	}
+	return mismatches
+}
+
+// CallBack returns callback to set on spot.balances, spot.margin_balances, spot.funding_balances and spot.cross_balances
+func (l *Ledger) CallBack() CallBack {
+	return NewCallBack(func(msg *UpdateMsg) {
+		if msg.Event != model.EventUpdate {
+			return
+		}
+
+		switch msg.GetChannel() {
+		case ChannelSpotBalance:
+			decodeEach(msg.Result, func(v *SpotBalancesMsg) { l.ApplySpotBalance(v) })
+		case ChannelSpotCrossBalance:
+			decodeEach(msg.Result, func(v *SpotBalancesMsg) { l.ApplyCrossBalance(v) })
+		case ChannelSpotMarginBalance:
+			decodeEach(msg.Result, func(v *SpotMarginBalancesMsg) { l.ApplyMarginBalance(v) })
+		case ChannelSpotFundingBalance:
+			decodeEach(msg.Result, func(v *SpotFundingBalancesMsg) { l.ApplyFundingBalance(v) })
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package gatews
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+func TestLedger(t *testing.T) {
+	ledger := NewLedger(0)
+	usdt := BalanceKey{Account: AccountSpot, Currency: "USDT"}
+	ledger.Seed([]Balance{{BalanceKey: usdt, Total: decimal.NewFromInt(100), Available: decimal.NewFromInt(100)}})
+
+	call := ledger.CallBack()
+	update := &UpdateMsg{
+		Channel: ChannelSpotBalance,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"timestamp_ms":"1700000000000","currency":"USDT","change":"-10","total":"90","available":"80","freeze":"10","change_type":"order-create"}]`),
+	}
+	call(update)
+	call(update)
+	// older update is ignored
+	ledger.ApplySpotBalance(&SpotBalancesMsg{TimestampInMilli: model.TimestampFromMilli(1699999999000), Asset: "USDT", Change: "5", Total: "105"})
+	// reported total doesn't follow the change
+	ledger.ApplySpotBalance(&SpotBalancesMsg{TimestampInMilli: model.TimestampFromMilli(1700000001000), Asset: "USDT", Change: "1", Total: "95", Available: "95"})
+
+	b, _ := ledger.Balance(usdt)
+	if b.Total.String() != "95" || b.Frozen.String() != "0" {
+		t.Fatalf("unexpected balance %+v", b)
+	}
+	history := ledger.History(BalanceKey{Currency: "USDT"}, 0)
+	if len(history) != 2 || history[0].Mismatched() || !history[1].Mismatched() {
+		t.Fatalf("unexpected history %+v", history)
+	}
+
+	// funding balances have no total, unknown until seeded
+	btc := BalanceKey{Account: AccountFunding, Currency: "BTC"}
+	ledger.ApplyFundingBalance(&SpotFundingBalancesMsg{TimestampInMilli: model.TimestampFromMilli(1700000000000), Asset: "BTC", Change: "2"})
+	if b, ok := ledger.Balance(btc); ok {
+		t.Fatalf("unseeded balance %+v", b)
+	}
+	ledger.Seed([]Balance{{BalanceKey: btc, Total: decimal.NewFromInt(3), UpdateTime: time.UnixMilli(1700000000000)}})
+	ledger.ApplyFundingBalance(&SpotFundingBalancesMsg{TimestampInMilli: model.TimestampFromMilli(1700000001000), Asset: "BTC", Change: "2", Freeze: "0.5", Lent: "1"})
+	if b, _ := ledger.Balance(btc); b.Total.String() != "5" || b.Available.String() != "3.5" {
+		t.Fatalf("unexpected balance %+v", b)
+	}
+
+	mismatches := ledger.Reconcile([]Balance{
+		{BalanceKey: usdt, Total: decimal.NewFromInt(95)},
+		{BalanceKey: BalanceKey{Account: AccountSpot, Currency: "ETH"}, Total: decimal.One},
+	})
+	if len(mismatches) != 1 || mismatches[0].Currency != "ETH" {
+		t.Fatalf("unexpected mismatches %+v", mismatches)
+	}
+}
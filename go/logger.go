@@ -0,0 +1,67 @@
+package gatews
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger structured leveled logger, args are alternating keys and values.
+// *slog.Logger implements it, e.g. slog.New(handler) routes logs to any slog.Handler.
+// Keys used are channel, url, attempt, req_id, latency and err. Secrets and signatures are never logged
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// stdLogger adapts *log.Logger to Logger, printing key=value pairs after the message. Debug logs are dropped
+type stdLogger struct {
+	l *log.Logger
+}
+
+// NewStdLogger returns a Logger printing to l, the default logger if l is nil
+func NewStdLogger(l *log.Logger) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+	return stdLogger{l: l}
+}
+
+func (s stdLogger) Debug(string, ...any) {}
+
+func (s stdLogger) Info(msg string, args ...any) {
+	s.print("INFO", msg, args)
+}
+
+func (s stdLogger) Warn(msg string, args ...any) {
+	s.print("WARN", msg, args)
+}
+
+func (s stdLogger) Error(msg string, args ...any) {
+	s.print("ERROR", msg, args)
+}
+
+func (s stdLogger) print(level, msg string, args []any) {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " !BADKEY=%v", args[i])
+		}
+	}
+	_ = s.l.Output(3, b.String())
+}
+
+// log returns the logger of conf, or Logger of ws adapted
+func (ws *WsService) log() Logger {
+	if ws.conf != nil && ws.conf.Logger != nil {
+		return ws.conf.Logger
+	}
+	return NewStdLogger(ws.Logger)
+}
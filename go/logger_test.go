@@ -0,0 +1,46 @@
+package gatews
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	lg := NewStdLogger(log.New(&buf, "", 0))
+
+	lg.Debug("dropped")
+	lg.Warn("failed to connect", "url", BaseUrl, "attempt", 2, "odd")
+
+	want := "WARN failed to connect url=" + BaseUrl + " attempt=2 !BADKEY=odd\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+type recordLogger struct {
+	lines []string
+}
+
+func (r *recordLogger) record(level, msg string, args []any) {
+	r.lines = append(r.lines, level+" "+msg)
+}
+
+func (r *recordLogger) Debug(msg string, args ...any) { r.record("DEBUG", msg, args) }
+func (r *recordLogger) Info(msg string, args ...any)  { r.record("INFO", msg, args) }
+func (r *recordLogger) Warn(msg string, args ...any)  { r.record("WARN", msg, args) }
+func (r *recordLogger) Error(msg string, args ...any) { r.record("ERROR", msg, args) }
+
+func TestConfLogger(t *testing.T) {
+	rec := &recordLogger{}
+	conf := NewConnConfFromOption(&ConfOptions{Logger: rec})
+	ws := &WsService{conf: conf, safety: newSafetyState()}
+
+	// no tracked markets, nothing to cancel
+	ws.CancelAll("test")
+	if len(rec.lines) != 1 || !strings.HasPrefix(rec.lines[0], "INFO cancelled all orders") {
+		t.Fatalf("unexpected logs %v", rec.lines)
+	}
+}
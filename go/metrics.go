@@ -0,0 +1,133 @@
+package gatews
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of the client, implementations must be safe for concurrent use.
+// The prommetrics subpackage implements it with Prometheus collectors
+type Metrics interface {
+	// MessageReceived a message of channel with size in bytes is read
+	MessageReceived(channel string, bytes int)
+	// DecodeError a message can't be decoded, channel is empty if unknown
+	DecodeError(channel string)
+	// CallbackDuration the callback of channel returned after d
+	CallbackDuration(channel string, d time.Duration)
+	// Reconnect the connection is restored
+	Reconnect()
+	// ResubscribeFailure resubscribing channel after reconnect failed
+	ResubscribeFailure(channel string)
+	// APIRequestLatency the response of an api request to channel arrived after d
+	APIRequestLatency(channel string, d time.Duration)
+	// OutstandingRequests n api requests are waiting for responses
+	OutstandingRequests(n int)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) MessageReceived(string, int)             {}
+func (nopMetrics) DecodeError(string)                      {}
+func (nopMetrics) CallbackDuration(string, time.Duration)  {}
+func (nopMetrics) Reconnect()                              {}
+func (nopMetrics) ResubscribeFailure(string)               {}
+func (nopMetrics) APIRequestLatency(string, time.Duration) {}
+func (nopMetrics) OutstandingRequests(int)                 {}
+
+func (ws *WsService) metrics() Metrics {
+	if ws.conf != nil && ws.conf.Metrics != nil {
+		return ws.conf.Metrics
+	}
+	return nopMetrics{}
+}
+
+// apiRequests api requests waiting for responses by channel and req_id
+type apiRequests struct {
+	mu      sync.Mutex
+	pending map[apiRequestKey][]pendingRequest
+	count   int
+}
+
+type apiRequestKey struct {
+	channel string
+	reqID   string
+}
+
+type pendingRequest struct {
+	sentAt time.Time
+	span   RequestSpan
+}
+
+func newAPIRequests() *apiRequests {
+	return &apiRequests{pending: make(map[apiRequestKey][]pendingRequest)}
+}
+
+// sent records a request, returns the number of outstanding requests
+func (r *apiRequests) sent(channel, reqID string, req pendingRequest) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := apiRequestKey{channel, reqID}
+	r.pending[key] = append(r.pending[key], req)
+	r.count++
+	return r.count
+}
+
+// received matches a response to the oldest request with the same channel and req_id
+func (r *apiRequests) received(channel, reqID string) (req pendingRequest, outstanding int, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := apiRequestKey{channel, reqID}
+	reqs := r.pending[key]
+	if len(reqs) == 0 {
+		return pendingRequest{}, r.count, false
+	}
+	req = reqs[0]
+	if len(reqs) == 1 {
+		delete(r.pending, key)
+	} else {
+		r.pending[key] = reqs[1:]
+	}
+	r.count--
+	return req, r.count, true
+}
+
+// drain removes all outstanding requests
+func (r *apiRequests) drain() []pendingRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var reqs []pendingRequest
+	for _, pending := range r.pending {
+		reqs = append(reqs, pending...)
+	}
+	r.pending = make(map[apiRequestKey][]pendingRequest)
+	r.count = 0
+	return reqs
+}
+
+// observeAPIRequest records an api request sent to channel
+func (ws *WsService) observeAPIRequest(channel, reqID string, span RequestSpan) {
+	if ws.apiRequests == nil {
+		span.End(nil, nil)
+		return
+	}
+	req := pendingRequest{sentAt: time.Now(), span: span}
+	ws.metrics().OutstandingRequests(ws.apiRequests.sent(channel, reqID, req))
+}
+
+// observeAPIResponse records the latency of an api response and ends its span, acks don't complete requests
+func (ws *WsService) observeAPIResponse(msg *UpdateMsg) {
+	if ws.apiRequests == nil || msg.Ack || msg.Header.Channel == "" || msg.Channel != "" {
+		return
+	}
+	req, outstanding, ok := ws.apiRequests.received(msg.Header.Channel, msg.RequestId)
+	if !ok {
+		return
+	}
+	m := ws.metrics()
+	m.APIRequestLatency(msg.Header.Channel, time.Since(req.sentAt))
+	m.OutstandingRequests(outstanding)
+	req.span.End(msg, nil)
+}
@@ -0,0 +1,76 @@
+package gatews
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordMetrics struct {
+	nopMetrics
+	mu          sync.Mutex
+	latencies   map[string]int
+	outstanding []int
+}
+
+func (m *recordMetrics) APIRequestLatency(channel string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latencies[channel]++
+}
+
+func (m *recordMetrics) OutstandingRequests(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outstanding = append(m.outstanding, n)
+}
+
+func TestAPIRequests(t *testing.T) {
+	r := newAPIRequests()
+	start := time.Unix(1700000000, 0)
+	r.sent(ChannelSpotOrderPlace, "1", pendingRequest{sentAt: start})
+	r.sent(ChannelSpotOrderPlace, "1", pendingRequest{sentAt: start.Add(time.Second)})
+	if n := r.sent(ChannelSpotOrderCancel, "1", pendingRequest{sentAt: start}); n != 3 {
+		t.Fatalf("outstanding = %d", n)
+	}
+
+	req, n, ok := r.received(ChannelSpotOrderPlace, "1")
+	if !ok || !req.sentAt.Equal(start) || n != 2 {
+		t.Fatalf("received %s %d %v", req.sentAt, n, ok)
+	}
+	if req, _, _ = r.received(ChannelSpotOrderPlace, "1"); !req.sentAt.Equal(start.Add(time.Second)) {
+		t.Fatalf("received %s", req.sentAt)
+	}
+	if _, n, ok = r.received(ChannelSpotOrderPlace, "1"); ok || n != 1 {
+		t.Fatalf("unexpected match, outstanding %d", n)
+	}
+	if reqs := r.drain(); len(reqs) != 1 {
+		t.Fatalf("drained %d", len(reqs))
+	}
+}
+
+func TestObserveAPIResponse(t *testing.T) {
+	m := &recordMetrics{latencies: make(map[string]int)}
+	ws := &WsService{conf: &ConnConf{Metrics: m}, apiRequests: newAPIRequests()}
+	ws.observeAPIRequest(ChannelSpotOrderPlace, "42", nopSpan{})
+
+	for _, raw := range []string{
+		`{"request_id":"42","ack":true,"header":{"channel":"spot.order_place"}}`,
+		`{"channel":"spot.orders","event":"update","result":[]}`,
+		`{"request_id":"42","header":{"channel":"spot.order_place","status":"200"}}`,
+	} {
+		var msg UpdateMsg
+		if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+			t.Fatal(err)
+		}
+		ws.observeAPIResponse(&msg)
+	}
+
+	if m.latencies[ChannelSpotOrderPlace] != 1 {
+		t.Fatalf("latencies %v", m.latencies)
+	}
+	if len(m.outstanding) != 2 || m.outstanding[0] != 1 || m.outstanding[1] != 0 {
+		t.Fatalf("outstanding %v", m.outstanding)
+	}
+}
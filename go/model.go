@@ -3,15 +3,22 @@ package gatews
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+
+	"github.com/gateio/gatews/go/model"
 )
 
 type UpdateMsg struct {
+	// RequestId req_id of the API request, only in API responses
+	RequestId string `json:"request_id,omitempty"`
+	// Ack true for the acknowledgement before the result of an API request
+	Ack     bool            `json:"ack,omitempty"`
 	Header  ResponseHeader  `json:"header"`
-	Time    int64           `json:"time"`
-	TimeMs  int64           `json:"time_ms"`
+	Time    model.Timestamp `json:"time"`
+	TimeMs  model.Timestamp `json:"time_ms"`
 	Id      *int64          `json:"id,omitempty"`
 	Channel string          `json:"channel"`
-	Event   string          `json:"event"`
+	Event   model.Event     `json:"event"`
 	Error   *ServiceError   `json:"error,omitempty"`
 	Result  json.RawMessage `json:"result"`
 	Data    struct {
@@ -21,14 +28,20 @@ type UpdateMsg struct {
 			Message string `json:"message"`
 		} `json:"errs"`
 	} `json:"data"`
+	// ReceivedAt local time the message is read from the connection
+	ReceivedAt time.Time `json:"-"`
 }
 
 type ResponseHeader struct {
-	ResponseTime string `json:"response_time"`
-	Status       string `json:"status"`
-	Channel      string `json:"channel"`
-	Event        string `json:"event"`
-	ClientID     string `json:"client_id"`
+	ResponseTime model.Timestamp `json:"response_time"`
+	Status       string          `json:"status"`
+	Channel      string          `json:"channel"`
+	Event        model.Event     `json:"event"`
+	ClientID     string          `json:"client_id"`
+	// rate limit of api channels
+	RateLimitRemain int             `json:"x_gate_ratelimit_requests_remain,omitempty"`
+	RateLimitLimit  int             `json:"x_gate_ratelimit_limit,omitempty"`
+	RateLimitReset  model.Timestamp `json:"x_gat_ratelimit_reset_timestamp,omitempty"`
 }
 
 func (u *UpdateMsg) GetChannel() string {
@@ -39,6 +52,24 @@ func (u *UpdateMsg) GetChannel() string {
 	return u.Header.Channel
 }
 
+// ExchangeTime returns the time the message was created by the server,
+// the most precise one among time_ms, time and header.response_time
+func (u *UpdateMsg) ExchangeTime() model.Timestamp {
+	switch {
+	case !u.TimeMs.IsZero():
+		return u.TimeMs
+	case !u.Time.IsZero():
+		return u.Time
+	default:
+		return u.Header.ResponseTime
+	}
+}
+
+// Latency returns the duration from the server creating the message to received, 0 if the message has no time
+func (u *UpdateMsg) Latency(received time.Time) time.Duration {
+	return u.ExchangeTime().Latency(received)
+}
+
 type ServiceError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -98,10 +129,10 @@ type APIReq struct {
 }
 
 type APIResp struct {
-	ClientID   string `json:"client_id"`
-	ReqID      string `json:"req_id"`
-	RespTimeMs int64  `json:"resp_time_ms"`
-	Status     int    `json:"status"`
+	ClientID   string          `json:"client_id"`
+	ReqID      string          `json:"req_id"`
+	RespTimeMs model.Timestamp `json:"resp_time_ms"`
+	Status     int             `json:"status"`
 	ReqHeader  struct {
 		XGateChannelID string `json:"x-gate-channel-id"`
 	} `json:"req_header"`
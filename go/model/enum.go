@@ -0,0 +1,364 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// UnknownEnumError returned by UnmarshalStrict and CheckEnums for values of enum fields not defined below
+type UnknownEnumError struct {
+	Type  string
+	Value string
+}
+
+func (e *UnknownEnumError) Error() string {
+	return fmt.Sprintf("unknown %s value %q", e.Type, e.Value)
+}
+
+// enumNames enum types checked by CheckEnums. Event is left out as it's a field of message envelopes,
+// which must decode whatever the server sends
+var enumNames = map[reflect.Type]string{
+	reflect.TypeOf(Side("")):        "side",
+	reflect.TypeOf(CancelSide("")):  "cancel side",
+	reflect.TypeOf(OrderType("")):   "order type",
+	reflect.TypeOf(OrderStatus("")): "order status",
+	reflect.TypeOf(TimeInForce("")): "time in force",
+	reflect.TypeOf(FinishAs("")):    "finish_as",
+	reflect.TypeOf(StpAct("")):      "stp_act",
+	reflect.TypeOf(Role("")):        "role",
+	reflect.TypeOf(OrderEvent("")):  "order event",
+}
+
+// UnmarshalStrict is json.Unmarshal failing on unknown values of enum fields in v, see CheckEnums.
+// json.Unmarshal accepts any value so that new values introduced by the server don't break decoding
+func UnmarshalStrict(data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	return CheckEnums(v)
+}
+
+// CheckEnums returns an UnknownEnumError for the first non-empty enum value of v, its fields, elements
+// and map values not defined below
+func CheckEnums(v any) error {
+	return checkEnums(reflect.ValueOf(v))
+}
+
+func checkEnums(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return checkEnums(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				if err := checkEnums(v.Field(i)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := checkEnums(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := checkEnums(iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		name, ok := enumNames[v.Type()]
+		if ok && v.Len() > 0 && !v.Interface().(interface{ Valid() bool }).Valid() {
+			return &UnknownEnumError{Type: name, Value: v.String()}
+		}
+	}
+	return nil
+}
+
+func unmarshalEnum[T ~string](data []byte, v *T) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*v = T(s)
+	return nil
+}
+
+// Side order or trade side
+type Side string
+
+const (
+	SideBuy  Side = "buy"
+	SideSell Side = "sell"
+)
+
+func (s Side) String() string { return string(s) }
+
+func (s Side) Valid() bool {
+	switch s {
+	case SideBuy, SideSell:
+		return true
+	}
+	return false
+}
+
+func (s *Side) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, s)
+}
+
+// CancelSide side of futures.order_cancel_cp
+type CancelSide string
+
+const (
+	CancelSideAsk CancelSide = "ask"
+	CancelSideBid CancelSide = "bid"
+)
+
+func (s CancelSide) String() string { return string(s) }
+
+func (s CancelSide) Valid() bool {
+	switch s {
+	case CancelSideAsk, CancelSideBid:
+		return true
+	}
+	return false
+}
+
+func (s *CancelSide) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, s)
+}
+
+// OrderType type of spot order
+type OrderType string
+
+const (
+	OrderTypeLimit  OrderType = "limit"
+	OrderTypeMarket OrderType = "market"
+)
+
+func (t OrderType) String() string { return string(t) }
+
+func (t OrderType) Valid() bool {
+	switch t {
+	case OrderTypeLimit, OrderTypeMarket:
+		return true
+	}
+	return false
+}
+
+func (t *OrderType) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, t)
+}
+
+// OrderStatus status of spot and futures orders
+type OrderStatus string
+
+const (
+	// spot and futures
+	OrderStatusOpen OrderStatus = "open"
+	// spot
+	OrderStatusClosed    OrderStatus = "closed"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	// futures
+	OrderStatusFinished OrderStatus = "finished"
+)
+
+func (s OrderStatus) String() string { return string(s) }
+
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case OrderStatusOpen, OrderStatusClosed, OrderStatusCancelled, OrderStatusFinished:
+		return true
+	}
+	return false
+}
+
+func (s *OrderStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, s)
+}
+
+// TimeInForce time_in_force of spot orders and tif of futures orders
+type TimeInForce string
+
+const (
+	// GoodTillCancelled
+	TimeInForceGTC TimeInForce = "gtc"
+	// ImmediateOrCancelled, taker only
+	TimeInForceIOC TimeInForce = "ioc"
+	// PendingOrCancelled, post-only order that always enjoys a maker fee
+	TimeInForcePOC TimeInForce = "poc"
+	// FillOrKill, fill either completely or none
+	TimeInForceFOK TimeInForce = "fok"
+)
+
+func (t TimeInForce) String() string { return string(t) }
+
+func (t TimeInForce) Valid() bool {
+	switch t {
+	case TimeInForceGTC, TimeInForceIOC, TimeInForcePOC, TimeInForceFOK:
+		return true
+	}
+	return false
+}
+
+func (t *TimeInForce) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, t)
+}
+
+// FinishAs how an order is finished
+type FinishAs string
+
+const (
+	FinishAsOpen               FinishAs = "open"
+	FinishAsFilled             FinishAs = "filled"
+	FinishAsCancelled          FinishAs = "cancelled"
+	FinishAsIOC                FinishAs = "ioc"
+	FinishAsPOC                FinishAs = "poc"
+	FinishAsFOK                FinishAs = "fok"
+	FinishAsSTP                FinishAs = "stp"
+	FinishAsLiquidateCancelled FinishAs = "liquidate_cancelled"
+	FinishAsDepthNotEnough     FinishAs = "depth_not_enough"
+	FinishAsTraderNotEnough    FinishAs = "trader_not_enough"
+	FinishAsSmall              FinishAs = "small"
+	FinishAsUnknown            FinishAs = "unknown"
+	// futures
+	FinishAsLiquidated      FinishAs = "liquidated"
+	FinishAsAutoDeleveraged FinishAs = "auto_deleveraged"
+	FinishAsReduceOnly      FinishAs = "reduce_only"
+	FinishAsPositionClosed  FinishAs = "position_closed"
+	FinishAsReduceOut       FinishAs = "reduce_out"
+	// futures.orders pushes, the order is not finished yet
+	FinishAsNew    FinishAs = "_new"
+	FinishAsUpdate FinishAs = "_update"
+)
+
+func (f FinishAs) String() string { return string(f) }
+
+func (f FinishAs) Valid() bool {
+	switch f {
+	case FinishAsOpen, FinishAsFilled, FinishAsCancelled, FinishAsIOC, FinishAsPOC, FinishAsFOK, FinishAsSTP,
+		FinishAsLiquidateCancelled, FinishAsDepthNotEnough, FinishAsTraderNotEnough, FinishAsSmall, FinishAsUnknown,
+		FinishAsLiquidated, FinishAsAutoDeleveraged, FinishAsReduceOnly, FinishAsPositionClosed, FinishAsReduceOut,
+		FinishAsNew, FinishAsUpdate:
+		return true
+	}
+	return false
+}
+
+// Finished reports whether the order is finished, i.e. not open, _new or _update
+func (f FinishAs) Finished() bool {
+	switch f {
+	case "", FinishAsOpen, FinishAsNew, FinishAsUpdate:
+		return false
+	}
+	return true
+}
+
+func (f *FinishAs) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, f)
+}
+
+// StpAct self-trade prevention action
+type StpAct string
+
+const (
+	// StpActCancelNewest cancel new orders and keep old ones
+	StpActCancelNewest StpAct = "cn"
+	// StpActCancelOldest cancel old orders and keep new ones
+	StpActCancelOldest StpAct = "co"
+	// StpActCancelBoth cancel both old and new orders
+	StpActCancelBoth StpAct = "cb"
+	// StpActNone returned for orders without stp_act
+	StpActNone StpAct = "-"
+)
+
+func (a StpAct) String() string { return string(a) }
+
+func (a StpAct) Valid() bool {
+	switch a {
+	case StpActCancelNewest, StpActCancelOldest, StpActCancelBoth, StpActNone:
+		return true
+	}
+	return false
+}
+
+func (a *StpAct) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, a)
+}
+
+// Role of user trades
+type Role string
+
+const (
+	RoleMaker Role = "maker"
+	RoleTaker Role = "taker"
+)
+
+func (r Role) String() string { return string(r) }
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleMaker, RoleTaker:
+		return true
+	}
+	return false
+}
+
+func (r *Role) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, r)
+}
+
+// Event of websocket messages
+type Event string
+
+const (
+	EventSubscribe   Event = "subscribe"
+	EventUnsubscribe Event = "unsubscribe"
+	EventUpdate      Event = "update"
+	// EventAll full order book snapshot of order_book channels
+	EventAll Event = "all"
+	EventAPI Event = "api"
+)
+
+func (e Event) String() string { return string(e) }
+
+func (e Event) Valid() bool {
+	switch e {
+	case EventSubscribe, EventUnsubscribe, EventUpdate, EventAll, EventAPI:
+		return true
+	}
+	return false
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, e)
+}
+
+// OrderEvent event of spot.orders pushes
+type OrderEvent string
+
+const (
+	OrderEventPut    OrderEvent = "put"
+	OrderEventUpdate OrderEvent = "update"
+	OrderEventFinish OrderEvent = "finish"
+)
+
+func (e OrderEvent) String() string { return string(e) }
+
+func (e OrderEvent) Valid() bool {
+	switch e {
+	case OrderEventPut, OrderEventUpdate, OrderEventFinish:
+		return true
+	}
+	return false
+}
+
+func (e *OrderEvent) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, e)
+}
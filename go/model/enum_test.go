@@ -0,0 +1,38 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestEnumUnmarshal(t *testing.T) {
+	var order FuturesOrder
+	payload := `{"contract":"BTC_USDT","size":1,"tif":"ioc","finish_as":"_new","status":"open","stp_act":"-"}`
+	if err := json.Unmarshal([]byte(payload), &order); err != nil {
+		t.Fatal(err)
+	}
+	if order.Tif != TimeInForceIOC || order.FinishAs != FinishAsNew || order.Status != OrderStatusOpen || order.StpAct != StpActNone {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+	if order.FinishAs.Finished() {
+		t.Fatal("_new should not be finished")
+	}
+
+	unknown := `{"contract":"BTC_USDT","size":1,"finish_as":"new_reason"}`
+	if err := json.Unmarshal([]byte(unknown), &order); err != nil || order.FinishAs.Valid() {
+		t.Fatalf("unknown value should be decoded in non-strict mode: %v", err)
+	}
+
+	var enumErr *UnknownEnumError
+	var orders []*FuturesOrder
+	if err := UnmarshalStrict([]byte("["+unknown+"]"), &orders); !errors.As(err, &enumErr) || enumErr.Value != "new_reason" || enumErr.Type != "finish_as" {
+		t.Fatalf("expected UnknownEnumError, got %v", err)
+	}
+	if err := UnmarshalStrict([]byte(`{"side":"buy","time_in_force":""}`), &Order{}); err != nil {
+		t.Fatalf("known or empty values should be accepted: %v", err)
+	}
+	if err := CheckEnums(map[string]any{"event": Event("new_event"), "side": Side("buy")}); err != nil {
+		t.Fatalf("events should be accepted: %v", err)
+	}
+}
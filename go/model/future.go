@@ -1,12 +1,12 @@
 package model
 
 type ListFuturesOrders struct {
-	Contract string `json:"contract,omitempty"`
-	Status   string `json:"status,omitempty"`
-	LastId   string `json:"last_id,omitempty"`
-	Settle   string `json:"settle,omitempty"`
-	Limit    int32  `json:"limit,omitempty"`
-	Offset   int32  `json:"offset,omitempty"`
+	Contract string      `json:"contract,omitempty"`
+	Status   OrderStatus `json:"status,omitempty"`
+	LastId   string      `json:"last_id,omitempty"`
+	Settle   string      `json:"settle,omitempty"`
+	Limit    int32       `json:"limit,omitempty"`
+	Offset   int32       `json:"offset,omitempty"`
 }
 
 type CancelFuturesOrder struct {
@@ -15,9 +15,9 @@ type CancelFuturesOrder struct {
 }
 
 type CancelFuturesCpOrder struct {
-	Contract string `json:"contract,omitempty"`
-	Side     string `json:"side,omitempty"`
-	Settle   string `json:"settle,omitempty"`
+	Contract string     `json:"contract,omitempty"`
+	Side     CancelSide `json:"side,omitempty"`
+	Settle   string     `json:"settle,omitempty"`
 }
 
 type StatusFuturesOrder struct {
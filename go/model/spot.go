@@ -17,7 +17,7 @@ type CancelOrderParam struct {
 
 type CancelOrderWithCpParam struct {
 	CurrencyPair string `json:"currency_pair,omitempty"`
-	Side         string `json:"side,omitempty"`
+	Side         Side   `json:"side,omitempty"`
 	Account      string `json:"account,omitempty"`
 }
 
@@ -0,0 +1,163 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp is a point in time in unix microseconds.
+//
+// Gate sends times in seconds, milliseconds or microseconds, as JSON numbers or strings, with or without decimals,
+// e.g. 1606292218, 1606292218213, "1606292218213.4578" or 1546569968.184.
+// Timestamp decodes all of them by the magnitude of the value, and encodes to milliseconds whatever the unit
+// it's decoded from, e.g. create_time 1606292218 in seconds is encoded as 1606292218000. Encoded values decode
+// to the same Timestamp, but consumers of the JSON must expect milliseconds
+type Timestamp int64
+
+// NewTimestamp returns t truncated to microseconds
+func NewTimestamp(t time.Time) Timestamp {
+	if t.IsZero() {
+		return 0
+	}
+	return Timestamp(t.UnixMicro())
+}
+
+func TimestampFromMilli(ms int64) Timestamp {
+	return Timestamp(ms * 1000)
+}
+
+func (t Timestamp) Time() time.Time {
+	return time.UnixMicro(int64(t))
+}
+
+func (t Timestamp) IsZero() bool {
+	return t == 0
+}
+
+func (t Timestamp) Unix() int64 {
+	return int64(t) / 1e6
+}
+
+func (t Timestamp) UnixMilli() int64 {
+	return int64(t) / 1e3
+}
+
+func (t Timestamp) UnixMicro() int64 {
+	return int64(t)
+}
+
+// Latency returns the duration from t to local, e.g. from the exchange event time to the local receive time.
+// It's 0 if t is zero
+func (t Timestamp) Latency(local time.Time) time.Duration {
+	if t.IsZero() {
+		return 0
+	}
+	return local.Sub(t.Time())
+}
+
+func (t Timestamp) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Time().Format(time.RFC3339Nano)
+}
+
+// MarshalJSON encodes t as milliseconds, with microseconds as decimals if any, the unit it's decoded from is not kept
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t%1000 == 0 {
+		return []byte(strconv.FormatInt(int64(t)/1000, 10)), nil
+	}
+
+	sign, v := "", int64(t)
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return []byte(strings.TrimRight(fmt.Sprintf("%s%d.%03d", sign, v/1000, v%1000), "0")), nil
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+
+	s := string(data)
+	if len(data) >= 2 && data[0] == '"' {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return fmt.Errorf("can't unmarshal %s as timestamp: %w", data, err)
+		}
+		s = strings.TrimSpace(unquoted)
+	}
+
+	v, err := ParseTimestamp(s)
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// ParseTimestamp parses s as seconds, milliseconds, microseconds or nanoseconds by its magnitude, or as RFC 3339.
+// Empty string and "0" are parsed as zero
+func ParseTimestamp(s string) (Timestamp, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	n, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || strings.Trim(fracPart, "0123456789") != "" {
+		if tm, e := time.Parse(time.RFC3339Nano, s); e == nil {
+			return NewTimestamp(tm), nil
+		}
+		return 0, fmt.Errorf("can't parse %q as timestamp", s)
+	}
+
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+
+	// number of digits after the microsecond
+	var shift int
+	switch {
+	case abs < 1e11:
+		shift = 6 // seconds
+	case abs < 1e14:
+		shift = 3 // milliseconds
+	case abs < 1e17:
+		shift = 0 // microseconds
+	default:
+		shift = -3 // nanoseconds
+	}
+
+	if shift < 0 {
+		return Timestamp(n / 1000), nil
+	}
+
+	if len(fracPart) > shift {
+		fracPart = fracPart[:shift]
+	}
+	fracPart += strings.Repeat("0", shift-len(fracPart))
+
+	us := n
+	for i := 0; i < shift; i++ {
+		us *= 10
+	}
+	if fracPart != "" {
+		frac, _ := strconv.ParseInt(fracPart, 10, 64)
+		if n < 0 || strings.HasPrefix(intPart, "-") {
+			frac = -frac
+		}
+		us += frac
+	}
+	return Timestamp(us), nil
+}
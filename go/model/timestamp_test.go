@@ -0,0 +1,74 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampUnmarshal(t *testing.T) {
+	want := time.Date(2020, 11, 25, 8, 16, 58, 213457000, time.UTC)
+	cases := map[string]int64{
+		`1606292218`:                    want.Unix() * 1e6,
+		`"1606292218"`:                  want.Unix() * 1e6,
+		`1606292218.213457`:             want.UnixMicro(),
+		`1606292218213`:                 want.UnixMilli() * 1e3,
+		`"1606292218213.4578"`:          want.UnixMicro(),
+		`1606292218213457`:              want.UnixMicro(),
+		`1606292218213457000`:           want.UnixMicro(),
+		`"2020-11-25T08:16:58.213457Z"`: want.UnixMicro(),
+		`""`:                            0,
+		`0`:                             0,
+		`null`:                          0,
+	}
+	for in, us := range cases {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(in), &ts); err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if ts.UnixMicro() != us {
+			t.Errorf("%s: got %d, want %d", in, ts.UnixMicro(), us)
+		}
+	}
+
+	var ts Timestamp
+	if err := json.Unmarshal([]byte(`"yesterday"`), &ts); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestTimestampMarshal(t *testing.T) {
+	for ts, want := range map[Timestamp]string{
+		1606292218213000: "1606292218213",
+		1606292218213450: "1606292218213.45",
+		0:                "0",
+	} {
+		out, err := json.Marshal(ts)
+		if err != nil || string(out) != want {
+			t.Errorf("%d: got %s, want %s (%v)", ts, out, want, err)
+		}
+	}
+
+	// normalised to milliseconds, decoded back to the same time
+	for _, in := range []string{`1700000000`, `"1546569968.184"`, `1606292218213`, `"1606292218213.4578"`} {
+		var ts, decoded Timestamp
+		if err := json.Unmarshal([]byte(in), &ts); err != nil {
+			t.Fatal(err)
+		}
+		out, _ := json.Marshal(ts)
+		if err := json.Unmarshal(out, &decoded); err != nil || decoded != ts {
+			t.Errorf("%s: encoded %s, decoded %d, want %d (%v)", in, out, decoded, ts, err)
+		}
+	}
+	if out, _ := json.Marshal(Timestamp(1700000000 * 1e6)); string(out) != "1700000000000" {
+		t.Errorf("seconds encoded as %s", out)
+	}
+
+	ts := TimestampFromMilli(1000)
+	if got := ts.Latency(time.UnixMilli(1250)); got != 250*time.Millisecond {
+		t.Errorf("latency = %v", got)
+	}
+	if got := Timestamp(0).Latency(time.Now()); got != 0 {
+		t.Errorf("latency of zero timestamp = %v", got)
+	}
+}
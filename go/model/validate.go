@@ -0,0 +1,327 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gateio/gatews/go/decimal"
+)
+
+// MaxTextLength max length of user defined text without the `t-` prefix
+const MaxTextLength = 28
+
+// FieldError describes why a field of a request is invalid
+type FieldError struct {
+	// Field json name of the field
+	Field  string
+	Reason string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// ValidationErrors is returned by Validate with all invalid fields of a request
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	reasons := make([]string, 0, len(e))
+	for _, fe := range e {
+		reasons = append(reasons, fe.Error())
+	}
+	return "invalid request: " + strings.Join(reasons, "; ")
+}
+
+// Has reports whether field is invalid
+func (e ValidationErrors) Has(field string) bool {
+	for _, fe := range e {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *ValidationErrors) add(field, format string, args ...any) {
+	*e = append(*e, FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ValidateText checks user defined order text: prefixed with `t-`, no longer than 28 bytes without the prefix,
+// and only includes 0-9, A-Z, a-z, underscore(_), hyphen(-) or dot(.)
+func ValidateText(text string) error {
+	if !strings.HasPrefix(text, "t-") {
+		return fmt.Errorf("must be prefixed with t-")
+	}
+	body := text[2:]
+	if body == "" {
+		return fmt.Errorf("must not be empty after t-")
+	}
+	if len(body) > MaxTextLength {
+		return fmt.Errorf("must be no longer than %d bytes without t-", MaxTextLength)
+	}
+	for _, c := range body {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '-' || c == '.') {
+			return fmt.Errorf("invalid character %q", c)
+		}
+	}
+	return nil
+}
+
+func (e *ValidationErrors) checkText(field, text string) {
+	if text == "" {
+		return
+	}
+	if err := ValidateText(text); err != nil {
+		e.add(field, err.Error())
+	}
+}
+
+func (e *ValidationErrors) checkRequired(field, value string) bool {
+	if value == "" {
+		e.add(field, "is required")
+		return false
+	}
+	return true
+}
+
+// checkDecimal checks value is a decimal and its sign, returns the parsed value
+func (e *ValidationErrors) checkDecimal(field, value string, allowZero bool) (decimal.Decimal, bool) {
+	d, err := decimal.NewFromString(value)
+	switch {
+	case err != nil:
+		e.add(field, "%q is not a number", value)
+		return d, false
+	case d.IsNegative():
+		e.add(field, "must not be negative")
+		return d, false
+	case d.IsZero() && !allowZero:
+		e.add(field, "must be positive")
+		return d, false
+	}
+	return d, true
+}
+
+func (e *ValidationErrors) checkStpAct(act StpAct) {
+	switch act {
+	case "", StpActCancelNewest, StpActCancelOldest, StpActCancelBoth:
+	default:
+		e.add("stp_act", "must be one of cn, co and cb")
+	}
+}
+
+// Validate checks order before placing it
+func (o *Order) Validate() error {
+	var errs ValidationErrors
+
+	errs.checkRequired("currency_pair", o.CurrencyPair)
+	if !o.Side.Valid() {
+		errs.add("side", "must be buy or sell")
+	}
+	if errs.checkRequired("amount", o.Amount) {
+		errs.checkDecimal("amount", o.Amount, false)
+	}
+
+	tif := o.TimeInForce
+	if tif != "" && !tif.Valid() {
+		errs.add("time_in_force", "must be one of gtc, ioc, poc and fok")
+	}
+
+	switch o.Type {
+	case "", OrderTypeLimit:
+		if errs.checkRequired("price", o.Price) {
+			errs.checkDecimal("price", o.Price, false)
+		}
+	case OrderTypeMarket:
+		if tif != TimeInForceIOC && tif != TimeInForceFOK {
+			errs.add("time_in_force", "only ioc and fok are supported for market orders")
+		}
+	default:
+		errs.add("type", "must be limit or market")
+	}
+
+	switch o.Account {
+	case "", "spot", "margin", "cross_margin", "unified":
+	default:
+		errs.add("account", "must be one of spot, margin, cross_margin and unified")
+	}
+
+	if o.Iceberg != "" {
+		errs.checkDecimal("iceberg", o.Iceberg, true)
+	}
+	if o.AutoBorrow && o.AutoRepay {
+		errs.add("auto_repay", "auto_borrow and auto_repay can't be both true")
+	}
+	errs.checkText("text", o.Text)
+	errs.checkStpAct(o.StpAct)
+
+	return errs.err()
+}
+
+// Validate checks futures order before placing it
+func (o *FuturesOrder) Validate() error {
+	var errs ValidationErrors
+
+	errs.checkRequired("contract", o.Contract)
+
+	closing := o.Close || o.AutoSize != ""
+	switch {
+	case closing && o.Size != 0:
+		errs.add("size", "must be 0 when closing position")
+	case !closing && o.Size == 0:
+		errs.add("size", "must not be 0, positive to bid and negative to ask")
+	}
+	switch o.AutoSize {
+	case "", "close_long", "close_short":
+	default:
+		errs.add("auto_size", "must be close_long or close_short")
+	}
+
+	tif := o.Tif
+	if tif != "" && !tif.Valid() {
+		errs.add("tif", "must be one of gtc, ioc, poc and fok")
+	}
+
+	if errs.checkRequired("price", o.Price) {
+		// price 0 means market order
+		if price, ok := errs.checkDecimal("price", o.Price, true); ok && price.IsZero() {
+			if tif != TimeInForceIOC && tif != TimeInForceFOK {
+				errs.add("tif", "only ioc and fok are supported for market orders")
+			}
+		}
+	}
+
+	if o.Iceberg < 0 {
+		errs.add("iceberg", "must not be negative")
+	}
+	errs.checkText("text", o.Text)
+	errs.checkStpAct(o.StpAct)
+
+	return errs.err()
+}
+
+func (p *AmendOrderParam) Validate() error {
+	var errs ValidationErrors
+
+	errs.checkRequired("order_id", p.OrderId)
+	errs.checkRequired("currency_pair", p.CurrencyPair)
+	switch {
+	case p.Amount == "" && p.Price == "":
+		errs.add("amount", "one of amount and price is required")
+	case p.Amount != "" && p.Price != "":
+		errs.add("amount", "only one of amount and price can be amended")
+	case p.Amount != "":
+		errs.checkDecimal("amount", p.Amount, false)
+	default:
+		errs.checkDecimal("price", p.Price, false)
+	}
+
+	return errs.err()
+}
+
+func (p *CancelOrderParam) Validate() error {
+	var errs ValidationErrors
+
+	errs.checkRequired("order_id", p.OrderId)
+	errs.checkRequired("currency_pair", p.CurrencyPair)
+
+	return errs.err()
+}
+
+func (p *CancelOrderWithCpParam) Validate() error {
+	var errs ValidationErrors
+
+	errs.checkRequired("currency_pair", p.CurrencyPair)
+	if p.Side != "" && !p.Side.Valid() {
+		errs.add("side", "must be buy or sell")
+	}
+
+	return errs.err()
+}
+
+func (p *StatusOrderParam) Validate() error {
+	var errs ValidationErrors
+
+	errs.checkRequired("order_id", p.OrderId)
+	errs.checkRequired("currency_pair", p.CurrencyPair)
+
+	return errs.err()
+}
+
+func (p *AmendFuturesOrder) Validate() error {
+	var errs ValidationErrors
+
+	errs.checkRequired("order_id", p.OrderId)
+	if p.Price == "" && p.Size == 0 {
+		errs.add("price", "one of price and size is required")
+	}
+	if p.Price != "" {
+		errs.checkDecimal("price", p.Price, false)
+	}
+
+	return errs.err()
+}
+
+func (p *CancelFuturesOrder) Validate() error {
+	var errs ValidationErrors
+
+	errs.checkRequired("order_id", p.OrderId)
+
+	return errs.err()
+}
+
+func (p *CancelFuturesCpOrder) Validate() error {
+	var errs ValidationErrors
+
+	errs.checkRequired("contract", p.Contract)
+	if p.Side != "" && !p.Side.Valid() {
+		errs.add("side", "must be ask or bid")
+	}
+
+	return errs.err()
+}
+
+func (p *StatusFuturesOrder) Validate() error {
+	var errs ValidationErrors
+
+	errs.checkRequired("order_id", p.OrderId)
+
+	return errs.err()
+}
+
+func (p *CancelFuturesOrderIds) Validate() error {
+	var errs ValidationErrors
+
+	if len(p.OrderIds) == 0 {
+		errs.add("order_ids", "is required")
+	}
+	for i, id := range p.OrderIds {
+		if id == "" {
+			errs.add(fmt.Sprintf("order_ids[%d]", i), "is required")
+		}
+	}
+
+	return errs.err()
+}
+
+func (p *ListFuturesOrders) Validate() error {
+	var errs ValidationErrors
+
+	if p.Status != OrderStatusOpen && p.Status != OrderStatusFinished {
+		errs.add("status", "must be open or finished")
+	}
+	if p.Limit < 0 {
+		errs.add("limit", "must not be negative")
+	}
+	if p.Offset < 0 {
+		errs.add("offset", "must not be negative")
+	}
+
+	return errs.err()
+}
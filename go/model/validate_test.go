@@ -0,0 +1,100 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateText(t *testing.T) {
+	for text, ok := range map[string]bool{
+		"t-my-custom_id.1":             true,
+		"my-id":                        false,
+		"t-":                           false,
+		"t-" + strings.Repeat("a", 28): true,
+		"t-" + strings.Repeat("a", 29): false,
+		"t-has space":                  false,
+	} {
+		if err := ValidateText(text); (err == nil) != ok {
+			t.Errorf("%q: unexpected result %v", text, err)
+		}
+	}
+}
+
+func TestOrderValidate(t *testing.T) {
+	order := &Order{CurrencyPair: "BTC_USDT", Side: SideBuy, Amount: "1", Price: "18000", TimeInForce: TimeInForceGTC, Text: "t-abc"}
+	if err := order.Validate(); err != nil {
+		t.Fatalf("valid order: %v", err)
+	}
+
+	invalid := &Order{
+		CurrencyPair: "BTC_USDT",
+		Side:         "long",
+		Amount:       "-1",
+		Type:         OrderTypeMarket,
+		TimeInForce:  TimeInForceGTC,
+		Text:         "abc",
+		AutoBorrow:   true,
+		AutoRepay:    true,
+	}
+	var errs ValidationErrors
+	if !errors.As(invalid.Validate(), &errs) {
+		t.Fatal("expected ValidationErrors")
+	}
+	for _, field := range []string{"side", "amount", "time_in_force", "text", "auto_repay"} {
+		if !errs.Has(field) {
+			t.Errorf("expected error of %s in %v", field, errs)
+		}
+	}
+	if errs.Has("price") {
+		t.Errorf("price is not required for market orders: %v", errs)
+	}
+}
+
+func TestFuturesOrderValidate(t *testing.T) {
+	cases := []struct {
+		order *FuturesOrder
+		field string
+	}{
+		{&FuturesOrder{Contract: "BTC_USDT", Size: 1, Price: "30000"}, ""},
+		{&FuturesOrder{Contract: "BTC_USDT", Size: -1, Price: "0", Tif: TimeInForceIOC}, ""},
+		{&FuturesOrder{Contract: "BTC_USDT", Close: true, Price: "0", Tif: TimeInForceIOC}, ""},
+		{&FuturesOrder{Contract: "BTC_USDT", Size: 1, Price: "0"}, "tif"},
+		{&FuturesOrder{Contract: "BTC_USDT", Size: 1, Close: true, Price: "1"}, "size"},
+		{&FuturesOrder{Contract: "BTC_USDT", Price: "1"}, "size"},
+		{&FuturesOrder{Contract: "BTC_USDT", Size: 1}, "price"},
+		{&FuturesOrder{Size: 1, Price: "1"}, "contract"},
+		{&FuturesOrder{Contract: "BTC_USDT", Size: 1, Price: "1", StpAct: "xx"}, "stp_act"},
+	}
+	for i, c := range cases {
+		err := c.order.Validate()
+		if c.field == "" {
+			if err != nil {
+				t.Errorf("case %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		var errs ValidationErrors
+		if !errors.As(err, &errs) || !errs.Has(c.field) {
+			t.Errorf("case %d: expected error of %s, got %v", i, c.field, err)
+		}
+	}
+}
+
+func TestParamsValidate(t *testing.T) {
+	if err := (&AmendOrderParam{OrderId: "1", CurrencyPair: "BTC_USDT", Price: "1", Amount: "1"}).Validate(); err == nil {
+		t.Error("amount and price can't be both amended")
+	}
+	if err := (&AmendFuturesOrder{OrderId: "1", Size: 10}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (&CancelOrderParam{OrderId: "1"}).Validate(); err == nil {
+		t.Error("currency_pair is required")
+	}
+	if err := (&CancelFuturesCpOrder{Contract: "BTC_USDT", Side: "buy"}).Validate(); err == nil {
+		t.Error("side of futures cancel_cp is ask or bid")
+	}
+	if err := (&CancelFuturesOrderIds{}).Validate(); err == nil {
+		t.Error("order_ids is required")
+	}
+}
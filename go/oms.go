@@ -0,0 +1,560 @@
+package gatews
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+	"github.com/gateio/gatews/go/resp"
+)
+
+// OrderState lifecycle state of an order tracked by OrderManager
+type OrderState int
+
+const (
+	OrderStatePendingNew OrderState = iota
+	OrderStateOpen
+	OrderStatePartiallyFilled
+	OrderStatePendingCancel
+	OrderStateFilled
+	OrderStateCancelled
+	OrderStateRejected
+)
+
+func (s OrderState) String() string {
+	switch s {
+	case OrderStatePendingNew:
+		return "pending_new"
+	case OrderStateOpen:
+		return "open"
+	case OrderStatePartiallyFilled:
+		return "partially_filled"
+	case OrderStatePendingCancel:
+		return "pending_cancel"
+	case OrderStateFilled:
+		return "filled"
+	case OrderStateCancelled:
+		return "cancelled"
+	case OrderStateRejected:
+		return "rejected"
+	}
+	return "unknown"
+}
+
+// Terminal reports whether the order can't change any more
+func (s OrderState) Terminal() bool {
+	return s >= OrderStateFilled
+}
+
+// ManagedOrder merged state of a spot or futures order
+type ManagedOrder struct {
+	// Id exchange order id, empty before the order is accepted
+	Id      string
+	Text    string
+	Market  string
+	Futures bool
+	Side    model.Side
+	// Price 0 for market orders
+	Price decimal.Decimal
+	// Amount is always positive, in base currency for spot and contracts for futures
+	Amount decimal.Decimal
+	Filled decimal.Decimal
+	Left   decimal.Decimal
+	// AvgPrice average fill price
+	AvgPrice   decimal.Decimal
+	State      OrderState
+	FinishAs   model.FinishAs
+	Err        string
+	CreateTime time.Time
+	// UpdateTime exchange time of the latest applied update
+	UpdateTime time.Time
+}
+
+// OrderTransition is notified when an order changes state
+type OrderTransition struct {
+	From  OrderState
+	To    OrderState
+	Order ManagedOrder
+}
+
+type orderKey struct {
+	futures bool
+	value   string
+}
+
+// OrderManager merges order responses of api channels and updates of spot.orders and futures.orders
+// into one state per order. Updates older than the current state, judged by filled amount and update time,
+// are dropped, and terminal states are never left
+type OrderManager struct {
+	mu       sync.Mutex
+	byId     map[orderKey]*ManagedOrder
+	byText   map[orderKey]*ManagedOrder
+	onChange func(OrderTransition)
+	// state to restore when a cancel request fails
+	beforeCancel map[*ManagedOrder]OrderState
+}
+
+// NewOrderManager onChange is called on every state transition, outside the lock of the manager, may be nil
+func NewOrderManager(onChange func(OrderTransition)) *OrderManager {
+	return &OrderManager{
+		byId:         make(map[orderKey]*ManagedOrder),
+		byText:       make(map[orderKey]*ManagedOrder),
+		onChange:     onChange,
+		beforeCancel: make(map[*ManagedOrder]OrderState),
+	}
+}
+
+// orderUpdate an order response or update in a common shape
+type orderUpdate struct {
+	futures    bool
+	id         string
+	text       string
+	market     string
+	side       model.Side
+	price      decimal.Decimal
+	amount     decimal.Decimal
+	left       decimal.Decimal
+	avgPrice   decimal.Decimal
+	finished   bool
+	finishAs   model.FinishAs
+	createTime time.Time
+	updateTime time.Time
+}
+
+func (u *orderUpdate) filled() decimal.Decimal {
+	return u.amount.Sub(u.left)
+}
+
+func (u *orderUpdate) state() OrderState {
+	filled := u.filled()
+	switch {
+	case u.finished && (u.left.IsZero() || u.finishAs == model.FinishAsFilled):
+		return OrderStateFilled
+	case u.finished:
+		return OrderStateCancelled
+	case filled.IsPositive():
+		return OrderStatePartiallyFilled
+	}
+	return OrderStateOpen
+}
+
+// Submit tracks order to place as pending new by its text, which is required
+func (m *OrderManager) Submit(order *model.Order) {
+	if order.Text == "" {
+		return
+	}
+	price, _ := decimal.NewFromString(order.Price)
+	amount, _ := decimal.NewFromString(order.Amount)
+	m.submit(&ManagedOrder{
+		Text:   order.Text,
+		Market: order.CurrencyPair,
+		Side:   order.Side,
+		Price:  price,
+		Amount: amount,
+		Left:   amount,
+	})
+}
+
+// SubmitFutures tracks futures order to place as pending new by its text, which is required
+func (m *OrderManager) SubmitFutures(order *model.FuturesOrder) {
+	if order.Text == "" {
+		return
+	}
+	price, _ := decimal.NewFromString(order.Price)
+	amount := decimal.NewFromInt(order.Size).Abs()
+	m.submit(&ManagedOrder{
+		Text:    order.Text,
+		Market:  order.Contract,
+		Futures: true,
+		Side:    sizeSide(order.Size),
+		Price:   price,
+		Amount:  amount,
+		Left:    amount,
+	})
+}
+
+func (m *OrderManager) submit(o *ManagedOrder) {
+	o.State = OrderStatePendingNew
+
+	m.mu.Lock()
+	key := orderKey{o.Futures, o.Text}
+	if _, ok := m.byText[key]; ok {
+		m.mu.Unlock()
+		return
+	}
+	m.byText[key] = o
+	order := *o
+	m.mu.Unlock()
+
+	m.notify(OrderStatePendingNew, order)
+}
+
+// Reject marks the pending order placed with text as rejected
+func (m *OrderManager) Reject(futures bool, text, reason string) {
+	m.mu.Lock()
+	o, ok := m.byText[orderKey{futures, text}]
+	if !ok || o.State != OrderStatePendingNew {
+		m.mu.Unlock()
+		return
+	}
+	o.State, o.Err = OrderStateRejected, reason
+	order := *o
+	m.mu.Unlock()
+
+	m.notify(OrderStatePendingNew, order)
+}
+
+// RequestCancel marks the order as pending cancel. Use CancelRejected if the cancel request fails
+func (m *OrderManager) RequestCancel(futures bool, id string) {
+	m.mu.Lock()
+	o, ok := m.byId[orderKey{futures, id}]
+	if !ok || o.State.Terminal() || o.State == OrderStatePendingCancel {
+		m.mu.Unlock()
+		return
+	}
+	from := o.State
+	m.beforeCancel[o] = from
+	o.State = OrderStatePendingCancel
+	order := *o
+	m.mu.Unlock()
+
+	m.notify(from, order)
+}
+
+// CancelRejected restores the state of an order pending cancel
+func (m *OrderManager) CancelRejected(futures bool, id string) {
+	m.mu.Lock()
+	o, ok := m.byId[orderKey{futures, id}]
+	if !ok || o.State != OrderStatePendingCancel {
+		m.mu.Unlock()
+		return
+	}
+	o.State = m.beforeCancel[o]
+	if o.State == OrderStateOpen && o.Filled.IsPositive() {
+		o.State = OrderStatePartiallyFilled
+	}
+	delete(m.beforeCancel, o)
+	order := *o
+	m.mu.Unlock()
+
+	m.notify(OrderStatePendingCancel, order)
+}
+
+// Order returns the order by exchange order id
+func (m *OrderManager) Order(futures bool, id string) (ManagedOrder, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.byId[orderKey{futures, id}]
+	if !ok {
+		return ManagedOrder{}, false
+	}
+	return *o, true
+}
+
+// OrderByText returns the order by its user defined text
+func (m *OrderManager) OrderByText(futures bool, text string) (ManagedOrder, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o, ok := m.byText[orderKey{futures, text}]
+	if !ok {
+		return ManagedOrder{}, false
+	}
+	return *o, true
+}
+
+// Orders returns orders which are not in a terminal state
+func (m *OrderManager) Orders() []ManagedOrder {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var orders []ManagedOrder
+	seen := make(map[*ManagedOrder]bool)
+	for _, index := range []map[orderKey]*ManagedOrder{m.byId, m.byText} {
+		for _, o := range index {
+			if !seen[o] && !o.State.Terminal() {
+				seen[o] = true
+				orders = append(orders, *o)
+			}
+		}
+	}
+	return orders
+}
+
+// Forget stops tracking orders in a terminal state
+func (m *OrderManager) Forget() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, index := range []map[orderKey]*ManagedOrder{m.byId, m.byText} {
+		for k, o := range index {
+			if o.State.Terminal() {
+				delete(index, k)
+			}
+		}
+	}
+}
+
+// ApplySpotOrder applies an update of spot.orders
+func (m *OrderManager) ApplySpotOrder(msg *SpotOrderMsg) {
+	m.apply(&orderUpdate{
+		id:         msg.Id,
+		text:       msg.Text,
+		market:     msg.CurrencyPair,
+		side:       msg.Side,
+		price:      parseDecimal(msg.Price),
+		amount:     parseDecimal(msg.Amount),
+		left:       parseDecimal(msg.Left),
+		avgPrice:   parseDecimal(msg.AvgDealPrice),
+		finished:   msg.Event == model.OrderEventFinish || msg.FinishAs.Finished(),
+		finishAs:   msg.FinishAs,
+		createTime: tradeTime(msg.CreateTimeMs, msg.CreateTime),
+		updateTime: tradeTime(msg.UpdateTimeMs, msg.UpdateTime),
+	})
+}
+
+// ApplySpotResponse applies a spot order returned by api channels
+func (m *OrderManager) ApplySpotResponse(order *resp.SpotOrder) {
+	m.apply(&orderUpdate{
+		id:         order.Id,
+		text:       order.Text,
+		market:     order.CurrencyPair,
+		side:       order.Side,
+		price:      parseDecimal(order.Price),
+		amount:     parseDecimal(order.Amount),
+		left:       parseDecimal(order.Left),
+		avgPrice:   parseDecimal(order.AvgDealPrice),
+		finished:   order.Status == model.OrderStatusClosed || order.Status == model.OrderStatusCancelled || order.FinishAs.Finished(),
+		finishAs:   order.FinishAs,
+		createTime: tradeTime(order.CreateTimeMs, order.CreateTime),
+		updateTime: tradeTime(order.UpdateTimeMs, order.UpdateTime),
+	})
+}
+
+// ApplyFuturesOrder applies an update of futures.orders
+func (m *OrderManager) ApplyFuturesOrder(msg *FuturesOrder) {
+	finished := msg.FinishAs.Finished()
+	updateTime := tradeTime(msg.CreateTimeMs, msg.CreateTime)
+	if finished {
+		updateTime = tradeTime(msg.FinishTimeMs, msg.FinishTime)
+	}
+	m.apply(&orderUpdate{
+		futures:    true,
+		id:         strconv.FormatInt(msg.Id, 10),
+		text:       msg.Text,
+		market:     msg.Contract,
+		side:       sizeSide(msg.Size),
+		price:      decimal.NewFromFloat(msg.Price),
+		amount:     decimal.NewFromInt(msg.Size).Abs(),
+		left:       decimal.NewFromInt(msg.Left).Abs(),
+		avgPrice:   decimal.NewFromFloat(msg.FillPrice),
+		finished:   finished,
+		finishAs:   msg.FinishAs,
+		createTime: tradeTime(msg.CreateTimeMs, msg.CreateTime),
+		updateTime: updateTime,
+	})
+}
+
+// ApplyFuturesResponse applies a futures order returned by api channels
+func (m *OrderManager) ApplyFuturesResponse(order *resp.FutureOrder) {
+	if order.Id == 0 {
+		return
+	}
+	finished := order.Status == model.OrderStatusFinished || order.FinishAs.Finished()
+	updateTime := tradeTime(0, order.CreateTime)
+	if finished {
+		updateTime = tradeTime(0, order.FinishTime)
+	}
+	m.apply(&orderUpdate{
+		futures:    true,
+		id:         strconv.FormatInt(order.Id, 10),
+		text:       order.Text,
+		market:     order.Contract,
+		side:       sizeSide(order.Size),
+		price:      parseDecimal(order.Price),
+		amount:     decimal.NewFromInt(order.Size).Abs(),
+		left:       decimal.NewFromInt(order.Left).Abs(),
+		avgPrice:   parseDecimal(order.FillPrice),
+		finished:   finished,
+		finishAs:   order.FinishAs,
+		createTime: tradeTime(0, order.CreateTime),
+		updateTime: updateTime,
+	})
+}
+
+func (m *OrderManager) apply(u *orderUpdate) {
+	if u.id == "" {
+		return
+	}
+
+	m.mu.Lock()
+	idKey, textKey := orderKey{u.futures, u.id}, orderKey{u.futures, u.text}
+	o, ok := m.byId[idKey]
+	if !ok && u.text != "" {
+		o, ok = m.byText[textKey]
+	}
+	if !ok {
+		o = &ManagedOrder{Futures: u.futures, State: OrderStatePendingNew}
+	}
+	m.byId[idKey] = o
+	if u.text != "" {
+		m.byText[textKey] = o
+	}
+
+	from := o.State
+	if !o.merge(u) {
+		m.mu.Unlock()
+		return
+	}
+	to := u.state()
+	// a cancel request in flight isn't resolved by updates of fills
+	if from == OrderStatePendingCancel && !to.Terminal() {
+		to = from
+	}
+	o.State = to
+	if to.Terminal() {
+		delete(m.beforeCancel, o)
+	}
+	order := *o
+	m.mu.Unlock()
+
+	if from != to {
+		m.notify(from, order)
+	}
+}
+
+// merge applies u to o unless u is stale, reports whether u is applied
+func (o *ManagedOrder) merge(u *orderUpdate) bool {
+	if o.Id != "" {
+		filled := u.filled()
+		switch {
+		case o.State.Terminal() && !u.finished:
+			return false
+		case o.State.Terminal() && filled.LessThanOrEqual(o.Filled):
+			return false
+		case filled.LessThan(o.Filled):
+			return false
+		case filled.Equal(o.Filled) && !u.finished && u.updateTime.Before(o.UpdateTime):
+			return false
+		}
+	}
+
+	o.Id, o.Market, o.Side = u.id, u.market, u.side
+	if u.text != "" {
+		o.Text = u.text
+	}
+	o.Price, o.Amount, o.Left, o.Filled = u.price, u.amount, u.left, u.filled()
+	if !u.avgPrice.IsZero() {
+		o.AvgPrice = u.avgPrice
+	}
+	if u.finishAs != "" && u.finishAs != model.FinishAsNew && u.finishAs != model.FinishAsUpdate {
+		o.FinishAs = u.finishAs
+	}
+	if !u.createTime.IsZero() {
+		o.CreateTime = u.createTime
+	}
+	if u.updateTime.After(o.UpdateTime) {
+		o.UpdateTime = u.updateTime
+	}
+	return true
+}
+
+func (m *OrderManager) notify(from OrderState, order ManagedOrder) {
+	if m.onChange != nil {
+		m.onChange(OrderTransition{From: from, To: order.State, Order: order})
+	}
+}
+
+// CallBack returns callback to apply spot.orders and futures.orders updates and responses of order api channels.
+// Set it on both kinds of channels
+func (m *OrderManager) CallBack() CallBack {
+	return NewCallBack(func(msg *UpdateMsg) {
+		channel := msg.GetChannel()
+		switch channel {
+		case ChannelSpotOrder:
+			if msg.Event != model.EventUpdate {
+				return
+			}
+			var orders []SpotOrderMsg
+			if err := json.Unmarshal(msg.Result, &orders); err != nil {
+				return
+			}
+			for i := range orders {
+				m.ApplySpotOrder(&orders[i])
+			}
+		case ChannelFutureOrder:
+			if msg.Event != model.EventUpdate {
+				return
+			}
+			var orders []FuturesOrder
+			if err := json.Unmarshal(msg.Result, &orders); err != nil {
+				return
+			}
+			for i := range orders {
+				m.ApplyFuturesOrder(&orders[i])
+			}
+		case ChannelSpotOrderPlace, ChannelSpotOrderAmend, ChannelSpotOrderCancel, ChannelSpotOrderCancelCp, ChannelSpotOrderStatus:
+			m.applyResponse(msg, false)
+		case ChannelFutureOrderPlace, ChannelFutureOrderBatchPlace, ChannelFutureOrderAmend, ChannelFutureOrderCancel,
+			ChannelFutureOrderCancelCp, ChannelFutureOrderStatus, ChannelFutureOrderList:
+			m.applyResponse(msg, true)
+		}
+	})
+}
+
+func (m *OrderManager) applyResponse(msg *UpdateMsg, futures bool) {
+	if msg.Ack {
+		return
+	}
+	channel := msg.GetChannel()
+	placing := channel == ChannelSpotOrderPlace || channel == ChannelFutureOrderPlace || channel == ChannelFutureOrderBatchPlace
+	if msg.Data.Errs != nil {
+		// the request id is the text of the first order when it is generated by ClientOrderIDGenerator
+		if placing && msg.RequestId != "" {
+			m.Reject(futures, msg.RequestId, msg.Data.Errs.Label+": "+msg.Data.Errs.Message)
+		}
+		return
+	}
+
+	var results []json.RawMessage
+	if err := json.Unmarshal(msg.Data.Result, &results); err != nil {
+		results = []json.RawMessage{msg.Data.Result}
+	}
+	for _, raw := range results {
+		var result apiOrderResult
+		if err := json.Unmarshal(raw, &result); err == nil && result.Succeeded != nil && !*result.Succeeded {
+			if placing && result.Text != "" {
+				m.Reject(futures, result.Text, result.Label+": "+result.Message)
+			}
+			continue
+		}
+
+		if futures {
+			var order resp.FutureOrder
+			if err := json.Unmarshal(raw, &order); err == nil {
+				m.ApplyFuturesResponse(&order)
+			}
+		} else {
+			var order resp.SpotOrder
+			if err := json.Unmarshal(raw, &order); err == nil {
+				m.ApplySpotResponse(&order)
+			}
+		}
+	}
+}
+
+func sizeSide(size int64) model.Side {
+	if size < 0 {
+		return model.SideSell
+	}
+	return model.SideBuy
+}
+
+func parseDecimal(s string) decimal.Decimal {
+	d, _ := decimal.NewFromString(s)
+	return d
+}
@@ -0,0 +1,92 @@
+package gatews
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gateio/gatews/go/model"
+)
+
+func TestOrderManagerSpot(t *testing.T) {
+	var transitions []OrderTransition
+	m := NewOrderManager(func(tr OrderTransition) { transitions = append(transitions, tr) })
+	call := m.CallBack()
+
+	m.Submit(&model.Order{Text: "t-1", CurrencyPair: "BTC_USDT", Side: model.SideBuy, Amount: "2", Price: "100"})
+
+	// the place response arrives after the first fill pushed
+	call(&UpdateMsg{
+		Channel: ChannelSpotOrder,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"id":"9","text":"t-1","currency_pair":"BTC_USDT","side":"buy","amount":"2","price":"100","left":"1.5","event":"update","update_time_ms":"1700000000002.0"}]`),
+	})
+	placed := &UpdateMsg{Header: ResponseHeader{Channel: ChannelSpotOrderPlace}}
+	placed.Data.Result = json.RawMessage(`{"id":"9","text":"t-1","currency_pair":"BTC_USDT","side":"buy","amount":"2","price":"100","left":"2","status":"open","update_time_ms":1700000000001}`)
+	call(placed)
+
+	o, ok := m.Order(false, "9")
+	if !ok || o.State != OrderStatePartiallyFilled || o.Filled.String() != "0.5" {
+		t.Fatalf("unexpected order %+v", o)
+	}
+
+	m.RequestCancel(false, "9")
+	call(&UpdateMsg{
+		Channel: ChannelSpotOrder,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"id":"9","text":"t-1","currency_pair":"BTC_USDT","side":"buy","amount":"2","price":"100","left":"1","event":"finish","finish_as":"cancelled","update_time_ms":"1700000000004.0"}]`),
+	})
+	// stale update after the order is finished
+	call(&UpdateMsg{
+		Channel: ChannelSpotOrder,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"id":"9","text":"t-1","currency_pair":"BTC_USDT","side":"buy","amount":"2","price":"100","left":"1","event":"update","update_time_ms":"1700000000003.0"}]`),
+	})
+
+	want := []OrderState{OrderStatePendingNew, OrderStatePartiallyFilled, OrderStatePendingCancel, OrderStateCancelled}
+	if len(transitions) != len(want) {
+		t.Fatalf("unexpected transitions %+v", transitions)
+	}
+	for i, tr := range transitions {
+		if tr.To != want[i] {
+			t.Fatalf("transition %d: got %s, want %s", i, tr.To, want[i])
+		}
+	}
+	if o, _ := m.OrderByText(false, "t-1"); o.Filled.String() != "1" || o.FinishAs != model.FinishAsCancelled {
+		t.Fatalf("unexpected order %+v", o)
+	}
+	if len(m.Orders()) != 0 {
+		t.Fatal("unexpected open orders")
+	}
+}
+
+func TestOrderManagerFutures(t *testing.T) {
+	m := NewOrderManager(nil)
+	call := m.CallBack()
+
+	m.SubmitFutures(&model.FuturesOrder{Text: "t-a", Contract: "BTC_USDT", Size: -10, Price: "30000"})
+	m.SubmitFutures(&model.FuturesOrder{Text: "t-b", Contract: "BTC_USDT", Size: 10, Price: "30000"})
+
+	batch := &UpdateMsg{RequestId: "t-a", Header: ResponseHeader{Channel: ChannelFutureOrderBatchPlace}}
+	batch.Data.Result = json.RawMessage(`[{"id":1,"text":"t-a","contract":"BTC_USDT","size":-10,"left":-10,"price":"30000","status":"open","succeeded":true},{"text":"t-b","succeeded":false,"label":"BALANCE_NOT_ENOUGH","message":"no"}]`)
+	call(batch)
+
+	if o, _ := m.OrderByText(true, "t-b"); o.State != OrderStateRejected || o.Err == "" {
+		t.Fatalf("unexpected order %+v", o)
+	}
+
+	call(&UpdateMsg{
+		Channel: ChannelFutureOrder,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"id":1,"text":"t-a","contract":"BTC_USDT","size":-10,"left":0,"price":30000,"fill_price":30000,"finish_as":"filled","finish_time_ms":1700000000000}]`),
+	})
+	call(&UpdateMsg{
+		Channel: ChannelFutureOrder,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"id":1,"text":"t-a","contract":"BTC_USDT","size":-10,"left":-4,"price":30000,"finish_as":"_update","create_time_ms":1699999999000}]`),
+	})
+
+	o, _ := m.Order(true, "1")
+	if o.State != OrderStateFilled || o.Side != model.SideSell || o.Filled.String() != "10" || o.AvgPrice.String() != "30000" {
+		t.Fatalf("unexpected order %+v", o)
+	}
+}
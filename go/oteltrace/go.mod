@@ -0,0 +1,20 @@
+module github.com/gateio/gatews/go/oteltrace
+
+go 1.18
+
+require (
+	github.com/gateio/gatews/go v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/sdk v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+)
+
+require (
+	github.com/deckarep/golang-set v1.7.1 // indirect
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/gorilla/websocket v1.4.2 // indirect
+	golang.org/x/sys v0.5.0 // indirect
+)
+
+replace github.com/gateio/gatews/go => ../
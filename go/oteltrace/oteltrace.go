@@ -0,0 +1,90 @@
+// Package oteltrace implements gatews.Tracer with OpenTelemetry spans
+package oteltrace
+
+import (
+	"context"
+
+	gatews "github.com/gateio/gatews/go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/gateio/gatews/go/oteltrace"
+
+// attributes of spans
+const (
+	AttrChannel    = attribute.Key("gate.channel")
+	AttrReqID      = attribute.Key("gate.req_id")
+	AttrStatus     = attribute.Key("gate.status")
+	AttrErrLabel   = attribute.Key("gate.err.label")
+	AttrErrMessage = attribute.Key("gate.err.message")
+	AttrRespTimeMs = attribute.Key("gate.resp_time_ms")
+)
+
+// Tracer starts a client span for each api request, ended by its response
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ gatews.Tracer = (*Tracer)(nil)
+
+// New returns a Tracer of provider, the global provider if nil
+func New(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+// StartRequest starts a span named after channel, e.g. "spot.order_place"
+func (t *Tracer) StartRequest(ctx context.Context, channel, reqID string) gatews.RequestSpan {
+	_, span := t.tracer.Start(ctx, channel,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(AttrChannel.String(channel), AttrReqID.String(reqID)))
+	return requestSpan{span}
+}
+
+// Event adds an event to the span of ctx. Without a recording span in ctx,
+// a span named "gatews.<name>" is started to hold it
+func (t *Tracer) Event(ctx context.Context, name string, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		_, span = t.tracer.Start(ctx, "gatews."+name)
+		defer span.End()
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	if err != nil {
+		span.RecordError(err, trace.WithAttributes(attribute.String("gate.event", name)))
+		return
+	}
+	span.AddEvent(name)
+}
+
+type requestSpan struct {
+	span trace.Span
+}
+
+func (s requestSpan) End(resp *gatews.UpdateMsg, err error) {
+	defer s.span.End()
+
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	if resp == nil {
+		return
+	}
+	s.span.SetAttributes(AttrStatus.String(resp.Header.Status))
+	if !resp.Header.ResponseTime.IsZero() {
+		s.span.SetAttributes(AttrRespTimeMs.Int64(resp.Header.ResponseTime.UnixMilli()))
+	}
+	if errs := resp.Data.Errs; errs != nil {
+		s.span.SetAttributes(AttrErrLabel.String(errs.Label), AttrErrMessage.String(errs.Message))
+		s.span.SetStatus(codes.Error, errs.Label)
+	}
+}
@@ -0,0 +1,60 @@
+package oteltrace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	gatews "github.com/gateio/gatews/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := New(provider)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "strategy")
+	tracer.Event(ctx, gatews.EventLogin, nil)
+	span := tracer.StartRequest(ctx, gatews.ChannelSpotOrderPlace, "42")
+
+	var msg gatews.UpdateMsg
+	raw := `{"request_id":"42","header":{"channel":"spot.order_place","status":"400","response_time":"1700000000123"},"data":{"errs":{"label":"BALANCE_NOT_ENOUGH","message":"balance not enough"}}}`
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatal(err)
+	}
+	span.End(&msg, nil)
+	parent.End()
+	tracer.Event(context.Background(), gatews.EventReconnect, errors.New("dial failed"))
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("%d spans", len(spans))
+	}
+	req := spans[0]
+	if req.Name() != gatews.ChannelSpotOrderPlace || req.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("unexpected request span %s", req.Name())
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range req.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs[AttrReqID].AsString() != "42" || attrs[AttrStatus].AsString() != "400" ||
+		attrs[AttrErrLabel].AsString() != "BALANCE_NOT_ENOUGH" || attrs[AttrRespTimeMs].AsInt64() != 1700000000123 {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+	if req.Status().Code != codes.Error {
+		t.Fatalf("status %v", req.Status())
+	}
+
+	if events := spans[1].Events(); len(events) != 1 || events[0].Name != gatews.EventLogin {
+		t.Fatalf("parent events %v", events)
+	}
+	if reconnect := spans[2]; reconnect.Name() != "gatews.reconnect" || reconnect.Status().Code != codes.Error {
+		t.Fatalf("unexpected reconnect span %s %v", reconnect.Name(), reconnect.Status())
+	}
+}
@@ -0,0 +1,405 @@
+package gatews
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+// Position futures position of a contract in single position mode.
+// PnL and margin are in the settle currency
+type Position struct {
+	Contract string
+	// Size positive for long and negative for short
+	Size          int64
+	EntryPrice    decimal.Decimal
+	MarkPrice     decimal.Decimal
+	RealisedPnl   decimal.Decimal
+	UnrealisedPnl decimal.Decimal
+	// Fee sum of fees of tracked fills, included in RealisedPnl
+	Fee             decimal.Decimal
+	Margin          decimal.Decimal
+	Leverage        decimal.Decimal
+	LiqPrice        decimal.Decimal
+	RiskLimit       decimal.Decimal
+	MaintenanceRate decimal.Decimal
+	UpdateTime      time.Time
+}
+
+// PositionEventType kind of PositionEvent
+type PositionEventType string
+
+const (
+	// PositionEventReconciled a positions snapshot disagrees with the size or entry price tracked from fills
+	PositionEventReconciled      PositionEventType = "reconciled"
+	PositionEventClosed          PositionEventType = "closed"
+	PositionEventAutoDeleveraged PositionEventType = "auto_deleveraged"
+	PositionEventLiquidated      PositionEventType = "liquidated"
+	PositionEventReduceRiskLimit PositionEventType = "reduce_risk_limit"
+)
+
+// PositionEvent is raised by PositionTracker with the position after the event applied.
+// Update is the message causing the event, e.g. *FuturesAutoDeleverages
+type PositionEvent struct {
+	Type     PositionEventType
+	Position Position
+	Update   any
+}
+
+// PositionTracker maintains futures positions from futures.usertrades and futures.tickers,
+// reconciled against futures.positions snapshots. The last trade ids are kept to ignore trades
+// applied again, e.g. after reconnecting, and trades older than the ones forgotten are ignored too
+type PositionTracker struct {
+	mu          sync.Mutex
+	positions   map[string]*Position
+	seen        map[string]*recentIDs // by contract
+	instruments *Instruments
+	onEvent     func(PositionEvent)
+}
+
+// NewPositionTracker instruments provides multipliers of contracts, contracts not found are linear with multiplier 1.
+// onEvent may be nil
+func NewPositionTracker(instruments *Instruments, onEvent func(PositionEvent)) *PositionTracker {
+	return &PositionTracker{
+		positions:   make(map[string]*Position),
+		seen:        make(map[string]*recentIDs),
+		instruments: instruments,
+		onEvent:     onEvent,
+	}
+}
+
+func (t *PositionTracker) contract(name string) (multiplier decimal.Decimal, inverse bool) {
+	if t.instruments == nil {
+		return decimal.One, false
+	}
+	c, ok := t.instruments.Contract(name)
+	if !ok {
+		return decimal.One, false
+	}
+	return c.multiplier(), c.inverse()
+}
+
+func (t *PositionTracker) position(contract string) *Position {
+	p, ok := t.positions[contract]
+	if !ok {
+		p = &Position{Contract: contract}
+		t.positions[contract] = p
+	}
+	return p
+}
+
+// pnl of size contracts opened at entry and closed at price
+func (t *PositionTracker) pnl(contract string, size int64, entry, price decimal.Decimal) decimal.Decimal {
+	if size == 0 || entry.IsZero() || price.IsZero() {
+		return decimal.Zero
+	}
+	multiplier, inverse := t.contract(contract)
+	value := decimal.NewFromInt(size).Mul(multiplier)
+	if inverse {
+		return value.Mul(decimal.One.Div(entry).Sub(decimal.One.Div(price)))
+	}
+	return value.Mul(price.Sub(entry))
+}
+
+func (t *PositionTracker) updateUnrealised(p *Position) {
+	p.UnrealisedPnl = t.pnl(p.Contract, p.Size, p.EntryPrice, p.MarkPrice)
+}
+
+// ApplyTrade applies a fill of futures.usertrades, trades already applied are ignored
+func (t *PositionTracker) ApplyTrade(trade *FuturesUserTrade) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if trade.Id != "" && !t.see(trade.Contract, trade.Id) {
+		return false
+	}
+
+	p := t.position(trade.Contract)
+	price := parseDecimal(trade.Price)
+	// reduce the position first, the rest opens or adds to it
+	size := t.reduce(p, trade.Size, price)
+	if size != 0 {
+		p.EntryPrice = t.entryPrice(p.Contract, p.Size, p.EntryPrice, size, price)
+		p.Size += size
+	}
+
+	fee := decimal.NewFromFloat(trade.Fee)
+	p.Fee = p.Fee.Add(fee)
+	p.RealisedPnl = p.RealisedPnl.Sub(fee)
+	if ts := tradeTime(trade.CreateTimeMs, trade.CreateTime); ts.After(p.UpdateTime) {
+		p.UpdateTime = ts
+	}
+	t.updateUnrealised(p)
+	return true
+}
+
+// see records a trade id of contract, reports false if it's seen before or older than the ids forgotten
+func (t *PositionTracker) see(contract, id string) bool {
+	ids, ok := t.seen[contract]
+	if !ok {
+		ids = newRecentIDs(maxSeenTrades)
+		t.seen[contract] = ids
+	}
+	return ids.add(id)
+}
+
+// reduce closes the position by size at price up to its size, realising PnL, and returns the size left
+// to open or add to the position
+func (t *PositionTracker) reduce(p *Position, size int64, price decimal.Decimal) int64 {
+	if p.Size == 0 || (p.Size > 0) == (size > 0) {
+		return size
+	}
+	closed := size
+	if abs(closed) > abs(p.Size) {
+		closed = -p.Size
+	}
+	p.RealisedPnl = p.RealisedPnl.Add(t.pnl(p.Contract, -closed, p.EntryPrice, price))
+	p.Size += closed
+	if p.Size == 0 {
+		p.EntryPrice = decimal.Zero
+	}
+	return size - closed
+}
+
+// entryPrice average entry price after adding size at price, harmonic for inverse contracts
+func (t *PositionTracker) entryPrice(contract string, size int64, entry decimal.Decimal, added int64, price decimal.Decimal) decimal.Decimal {
+	if size == 0 || entry.IsZero() {
+		return price
+	}
+	total, held, add := decimal.NewFromInt(size+added), decimal.NewFromInt(size), decimal.NewFromInt(added)
+	if _, inverse := t.contract(contract); inverse {
+		return total.Div(held.Div(entry).Add(add.Div(price)))
+	}
+	return held.Mul(entry).Add(add.Mul(price)).Div(total)
+}
+
+// ApplyTicker updates mark price and unrealised PnL
+func (t *PositionTracker) ApplyTicker(ticker *FuturesTicker) {
+	mark := parseDecimal(ticker.MarkPrice)
+	if mark.IsZero() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.positions[ticker.Contract]
+	if !ok {
+		return
+	}
+	p.MarkPrice = mark
+	t.updateUnrealised(p)
+}
+
+// ApplyPositions reconciles the position with a futures.positions snapshot, which is authoritative
+func (t *PositionTracker) ApplyPositions(snapshot *FuturesPositions) {
+	t.mu.Lock()
+	p := t.position(snapshot.Contract)
+	ts := tradeTime(snapshot.TimeMs, snapshot.Time)
+	if ts.Before(p.UpdateTime) {
+		t.mu.Unlock()
+		return
+	}
+
+	entry := decimal.NewFromFloat(snapshot.EntryPrice)
+	drifted := p.Size != snapshot.Size || (p.Size != 0 && !approxEqual(p.EntryPrice, entry))
+	p.Size = snapshot.Size
+	p.EntryPrice = entry
+	p.RealisedPnl = decimal.NewFromFloat(snapshot.RealisedPnl)
+	p.Margin = decimal.NewFromFloat(snapshot.Margin)
+	p.Leverage = decimal.NewFromFloat(snapshot.Leverage)
+	p.LiqPrice = decimal.NewFromFloat(snapshot.LiqPrice)
+	p.RiskLimit = decimal.NewFromFloat(snapshot.RiskLimit)
+	p.MaintenanceRate = decimal.NewFromFloat(snapshot.MaintenanceRate)
+	p.UpdateTime = ts
+	t.updateUnrealised(p)
+	position := *p
+	t.mu.Unlock()
+
+	if drifted {
+		t.notify(PositionEventReconciled, position, snapshot)
+	}
+}
+
+// ApplyPositionClose applies a futures.position_closes update, closes older than the position are ignored
+func (t *PositionTracker) ApplyPositionClose(closes *FuturesPositionCloses) {
+	t.mu.Lock()
+	p := t.position(closes.Contract)
+	ts := tradeTime(closes.TimeMs, closes.Time)
+	if ts.Before(p.UpdateTime) {
+		t.mu.Unlock()
+		return
+	}
+	p.Size, p.EntryPrice, p.UnrealisedPnl = 0, decimal.Zero, decimal.Zero
+	p.UpdateTime = ts
+	position := *p
+	t.mu.Unlock()
+
+	t.notify(PositionEventClosed, position, closes)
+}
+
+// ApplyAutoDeleverage applies a futures.auto_deleverages update, position_size is the size before
+// deleveraging and trade_size the size closed at fill_price, signed or not
+func (t *PositionTracker) ApplyAutoDeleverage(adl *FuturesAutoDeleverages) {
+	t.mu.Lock()
+	p := t.position(adl.Contract)
+	if adl.PositionSize != 0 {
+		p.Size = adl.PositionSize
+		if p.EntryPrice.IsZero() {
+			p.EntryPrice = decimal.NewFromFloat(adl.EntryPrice)
+		}
+	}
+	closed := abs(adl.TradeSize)
+	if p.Size > 0 {
+		closed = -closed
+	}
+	t.reduce(p, closed, decimal.NewFromFloat(adl.FillPrice))
+	t.updateUnrealised(p)
+	position := *p
+	t.mu.Unlock()
+
+	t.notify(PositionEventAutoDeleveraged, position, adl)
+}
+
+// ApplyLiquidate applies a futures.liquidates update, the position of size is closed at fill_price
+// except left, the size not filled by the liquidation order
+func (t *PositionTracker) ApplyLiquidate(liq *FuturesLiquidate) {
+	t.mu.Lock()
+	p := t.position(liq.Contract)
+	if liq.Size != 0 {
+		p.Size = liq.Size
+		if p.EntryPrice.IsZero() {
+			p.EntryPrice = decimal.NewFromFloat(liq.EntryPrice)
+		}
+	}
+	left := abs(liq.Left)
+	if left > abs(p.Size) {
+		left = abs(p.Size)
+	}
+	closed := abs(p.Size) - left
+	if p.Size > 0 {
+		closed = -closed
+	}
+	price := decimal.NewFromFloat(liq.FillPrice)
+	if price.IsZero() {
+		price = decimal.NewFromFloat(liq.OrderPrice)
+	}
+	t.reduce(p, closed, price)
+	if liq.LiqPrice != 0 {
+		p.LiqPrice = decimal.NewFromFloat(liq.LiqPrice)
+	}
+	if liq.MarkPrice != 0 {
+		p.MarkPrice = decimal.NewFromFloat(liq.MarkPrice)
+	}
+	t.updateUnrealised(p)
+	position := *p
+	t.mu.Unlock()
+
+	t.notify(PositionEventLiquidated, position, liq)
+}
+
+// ApplyReduceRiskLimit applies a futures.reduce_risk_limits update
+func (t *PositionTracker) ApplyReduceRiskLimit(limit *FuturesReduceRiskLimits) {
+	t.mu.Lock()
+	p := t.position(limit.Contract)
+	p.LiqPrice = decimal.NewFromFloat(limit.LiqPrice)
+	p.RiskLimit = decimal.NewFromFloat(limit.RiskLimit)
+	p.MaintenanceRate = decimal.NewFromFloat(limit.MaintenanceRate)
+	position := *p
+	t.mu.Unlock()
+
+	t.notify(PositionEventReduceRiskLimit, position, limit)
+}
+
+func (t *PositionTracker) notify(typ PositionEventType, position Position, update any) {
+	if t.onEvent != nil {
+		t.onEvent(PositionEvent{Type: typ, Position: position, Update: update})
+	}
+}
+
+// Position returns the position of contract
+func (t *PositionTracker) Position(contract string) (Position, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.positions[contract]
+	if !ok {
+		return Position{}, false
+	}
+	return *p, true
+}
+
+// Positions returns all tracked positions ordered by contract
+func (t *PositionTracker) Positions() []Position {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	positions := make([]Position, 0, len(t.positions))
+	for _, p := range t.positions {
+		positions = append(positions, *p)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].Contract < positions[j].Contract
+	})
+	return positions
+}
+
+// CallBack returns callback to set on futures.usertrades, futures.tickers, futures.positions, futures.position_closes,
+// futures.auto_deleverages, futures.liquidates and futures.reduce_risk_limits
+func (t *PositionTracker) CallBack() CallBack {
+	return NewCallBack(func(msg *UpdateMsg) {
+		if msg.Event != model.EventUpdate && msg.Event != model.EventAll {
+			return
+		}
+
+		switch msg.GetChannel() {
+		case ChannelFutureUserTrade:
+			decodeEach(msg.Result, func(v *FuturesUserTrade) { t.ApplyTrade(v) })
+		case ChannelFutureTicker:
+			decodeEach(msg.Result, t.ApplyTicker)
+		case ChannelFuturePositions:
+			decodeEach(msg.Result, t.ApplyPositions)
+		case ChannelFuturePositionCloses:
+			decodeEach(msg.Result, t.ApplyPositionClose)
+		case ChannelFutureAutoDeleverages:
+			decodeEach(msg.Result, t.ApplyAutoDeleverage)
+		case ChannelFutureLiquidates:
+			decodeEach(msg.Result, t.ApplyLiquidate)
+		case ChannelFutureReduceRiskLimits:
+			decodeEach(msg.Result, t.ApplyReduceRiskLimit)
+		}
+	})
+}
+
+// decodeEach decodes result as a list of T, or a single T, and calls f on each
+func decodeEach[T any](result json.RawMessage, f func(*T)) {
+	var list []T
+	if err := json.Unmarshal(result, &list); err != nil {
+		var v T
+		if err := json.Unmarshal(result, &v); err != nil {
+			return
+		}
+		list = append(list, v)
+	}
+	for i := range list {
+		f(&list[i])
+	}
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// approxEqual reports whether a and b differ by at most 1e-9 relatively, entry prices tracked are rounded
+// by division differently from the server's
+func approxEqual(a, b decimal.Decimal) bool {
+	tolerance := decimal.New(1, -9).Mul(decimal.One.Add(a.Abs()).Add(b.Abs()))
+	return a.Sub(b).Abs().LessThanOrEqual(tolerance)
+}
@@ -0,0 +1,125 @@
+package gatews
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+func TestPositionTrackerFills(t *testing.T) {
+	instruments := NewInstruments()
+	instruments.Set(nil, []ContractInfo{{Name: "BTC_USDT", QuantoMultiplier: decimal.RequireFromString("0.001")}})
+	tracker := NewPositionTracker(instruments, nil)
+
+	tracker.ApplyTrade(&FuturesUserTrade{Id: "1", Contract: "BTC_USDT", Size: 10, Price: "30000", Fee: 0.1})
+	tracker.ApplyTrade(&FuturesUserTrade{Id: "2", Contract: "BTC_USDT", Size: 10, Price: "31000"})
+	tracker.ApplyTrade(&FuturesUserTrade{Id: "2", Contract: "BTC_USDT", Size: 10, Price: "31000"})
+	// close 20 and open 5 short
+	tracker.ApplyTrade(&FuturesUserTrade{Id: "3", Contract: "BTC_USDT", Size: -25, Price: "32000"})
+	tracker.ApplyTicker(&FuturesTicker{Contract: "BTC_USDT", MarkPrice: "31000"})
+
+	p, _ := tracker.Position("BTC_USDT")
+	if p.Size != -5 || !p.EntryPrice.Equal(decimal.NewFromInt(32000)) {
+		t.Fatalf("unexpected position %+v", p)
+	}
+	// 20 * 0.001 * (32000 - 30500) - 0.1
+	if !p.RealisedPnl.Equal(decimal.RequireFromString("29.9")) || !p.UnrealisedPnl.Equal(decimal.NewFromInt(5)) {
+		t.Fatalf("unexpected pnl %+v", p)
+	}
+}
+
+func TestPositionTrackerLiquidate(t *testing.T) {
+	tracker := NewPositionTracker(nil, nil)
+	tracker.ApplyTrade(&FuturesUserTrade{Id: "1", Contract: "BTC_USDT", Size: -10, Price: "100"})
+
+	// 6 of 10 short contracts closed at 110, 4 left
+	tracker.ApplyLiquidate(&FuturesLiquidate{Contract: "BTC_USDT", Size: -10, Left: -4, FillPrice: 110, LiqPrice: 111})
+	p, _ := tracker.Position("BTC_USDT")
+	if p.Size != -4 || !p.RealisedPnl.Equal(decimal.NewFromInt(-60)) || !p.LiqPrice.Equal(decimal.NewFromInt(111)) {
+		t.Fatalf("unexpected position %+v", p)
+	}
+
+	tracker.ApplyLiquidate(&FuturesLiquidate{Contract: "BTC_USDT", Size: -4, FillPrice: 120})
+	p, _ = tracker.Position("BTC_USDT")
+	if p.Size != 0 || !p.EntryPrice.IsZero() || !p.RealisedPnl.Equal(decimal.NewFromInt(-140)) {
+		t.Fatalf("unexpected position %+v", p)
+	}
+}
+
+func TestPositionTrackerSeenBounded(t *testing.T) {
+	tracker := NewPositionTracker(nil, nil)
+	for i := 1; i <= maxSeenTrades+10; i++ {
+		tracker.ApplyTrade(&FuturesUserTrade{Id: strconv.Itoa(i), Contract: "BTC_USDT", Size: 1, Price: "100"})
+	}
+	if n := len(tracker.seen["BTC_USDT"].seen); n != maxSeenTrades {
+		t.Fatalf("%d trade ids kept", n)
+	}
+	// evicted and kept ids are both ignored when applied again
+	if tracker.ApplyTrade(&FuturesUserTrade{Id: "1", Contract: "BTC_USDT", Size: 1, Price: "100"}) ||
+		tracker.ApplyTrade(&FuturesUserTrade{Id: strconv.Itoa(maxSeenTrades), Contract: "BTC_USDT", Size: 1, Price: "100"}) {
+		t.Fatal("trade applied again")
+	}
+	if p, _ := tracker.Position("BTC_USDT"); p.Size != maxSeenTrades+10 {
+		t.Fatalf("size %d", p.Size)
+	}
+}
+
+func TestPositionTrackerEvents(t *testing.T) {
+	var events []PositionEventType
+	tracker := NewPositionTracker(nil, func(e PositionEvent) { events = append(events, e.Type) })
+	call := tracker.CallBack()
+
+	call(&UpdateMsg{
+		Channel: ChannelFutureUserTrade,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"id":"1","contract":"BTC_USD","size":5,"price":"100","create_time_ms":1700000000000}]`),
+	})
+	call(&UpdateMsg{
+		Channel: ChannelFuturePositions,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"contract":"BTC_USD","size":4,"entry_price":100,"margin":10,"liq_price":50,"time_ms":1700000001000}]`),
+	})
+	call(&UpdateMsg{
+		Channel: ChannelFutureAutoDeleverages,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"contract":"BTC_USD","position_size":4,"trade_size":-1,"fill_price":120}]`),
+	})
+	call(&UpdateMsg{
+		Channel: ChannelFutureReduceRiskLimits,
+		Event:   model.EventUpdate,
+		Result:  json.RawMessage(`[{"contract":"BTC_USD","liq_price":60,"risk_limit":100}]`),
+	})
+
+	p, _ := tracker.Position("BTC_USD")
+	// 1 of 4 long contracts deleveraged at 120
+	if p.Size != 3 || !p.RealisedPnl.Equal(decimal.NewFromInt(20)) || !p.Margin.Equal(decimal.NewFromInt(10)) ||
+		!p.LiqPrice.Equal(decimal.NewFromInt(60)) || !p.RiskLimit.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("unexpected position %+v", p)
+	}
+	want := []PositionEventType{PositionEventReconciled, PositionEventAutoDeleveraged, PositionEventReduceRiskLimit}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events %v", events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("unexpected events %v", events)
+		}
+	}
+}
+
+func TestPositionTrackerLateClose(t *testing.T) {
+	tracker := NewPositionTracker(nil, nil)
+	tracker.ApplyTrade(&FuturesUserTrade{Id: "1", Contract: "BTC_USDT", Size: 2, Price: "100", CreateTimeMs: model.TimestampFromMilli(1700000000000)})
+	tracker.ApplyPositionClose(&FuturesPositionCloses{Contract: "BTC_USDT", TimeMs: model.TimestampFromMilli(1700000001000)})
+	// reopened after the close, which is delivered again
+	tracker.ApplyTrade(&FuturesUserTrade{Id: "2", Contract: "BTC_USDT", Size: 3, Price: "110", CreateTimeMs: model.TimestampFromMilli(1700000002000)})
+	tracker.ApplyPositionClose(&FuturesPositionCloses{Contract: "BTC_USDT", TimeMs: model.TimestampFromMilli(1700000001000)})
+
+	p, _ := tracker.Position("BTC_USDT")
+	if p.Size != 3 || !p.EntryPrice.Equal(decimal.NewFromInt(110)) {
+		t.Fatalf("reopened position wiped %+v", p)
+	}
+}
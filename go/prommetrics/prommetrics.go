@@ -0,0 +1,123 @@
+// Package prommetrics implements gatews.Metrics with Prometheus collectors
+package prommetrics
+
+import (
+	"time"
+
+	gatews "github.com/gateio/gatews/go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics collectors of a gatews client, register it to a prometheus.Registerer and pass it
+// as ConnConf.Metrics
+type Metrics struct {
+	messages            *prometheus.CounterVec
+	bytes               *prometheus.CounterVec
+	decodeErrors        *prometheus.CounterVec
+	callbackDuration    *prometheus.HistogramVec
+	reconnects          prometheus.Counter
+	resubscribeFailures *prometheus.CounterVec
+	apiLatency          *prometheus.HistogramVec
+	outstanding         prometheus.Gauge
+}
+
+var _ gatews.Metrics = (*Metrics)(nil)
+var _ prometheus.Collector = (*Metrics)(nil)
+
+// New creates collectors named with namespace, e.g. namespace_messages_received_total
+func New(namespace string) *Metrics {
+	return &Metrics{
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_received_total",
+			Help:      "Messages received by channel.",
+		}, []string{"channel"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "received_bytes_total",
+			Help:      "Bytes of messages received by channel.",
+		}, []string{"channel"}),
+		decodeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "decode_errors_total",
+			Help:      "Messages that can't be decoded by channel, empty if unknown.",
+		}, []string{"channel"}),
+		callbackDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "callback_duration_seconds",
+			Help:      "Duration of callbacks by channel.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"channel"}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconnects_total",
+			Help:      "Restored connections.",
+		}),
+		resubscribeFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "resubscribe_failures_total",
+			Help:      "Failed resubscriptions after reconnect by channel.",
+		}, []string{"channel"}),
+		apiLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "api_request_duration_seconds",
+			Help:      "Latency of api requests by channel.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+		}, []string{"channel"}),
+		outstanding: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "api_requests_outstanding",
+			Help:      "Api requests waiting for responses.",
+		}),
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.messages, m.bytes, m.decodeErrors, m.callbackDuration,
+		m.reconnects, m.resubscribeFailures, m.apiLatency, m.outstanding,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (m *Metrics) MessageReceived(channel string, bytes int) {
+	m.messages.WithLabelValues(channel).Inc()
+	m.bytes.WithLabelValues(channel).Add(float64(bytes))
+}
+
+func (m *Metrics) DecodeError(channel string) {
+	m.decodeErrors.WithLabelValues(channel).Inc()
+}
+
+func (m *Metrics) CallbackDuration(channel string, d time.Duration) {
+	m.callbackDuration.WithLabelValues(channel).Observe(d.Seconds())
+}
+
+func (m *Metrics) Reconnect() {
+	m.reconnects.Inc()
+}
+
+func (m *Metrics) ResubscribeFailure(channel string) {
+	m.resubscribeFailures.WithLabelValues(channel).Inc()
+}
+
+func (m *Metrics) APIRequestLatency(channel string, d time.Duration) {
+	m.apiLatency.WithLabelValues(channel).Observe(d.Seconds())
+}
+
+func (m *Metrics) OutstandingRequests(n int) {
+	m.outstanding.Set(float64(n))
+}
@@ -0,0 +1,43 @@
+package prommetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New("gatews")
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(m); err != nil {
+		t.Fatal(err)
+	}
+
+	m.MessageReceived("spot.trades", 100)
+	m.MessageReceived("spot.trades", 50)
+	m.CallbackDuration("spot.trades", time.Millisecond)
+	m.Reconnect()
+	m.OutstandingRequests(3)
+
+	expected := `
+# HELP gatews_received_bytes_total Bytes of messages received by channel.
+# TYPE gatews_received_bytes_total counter
+gatews_received_bytes_total{channel="spot.trades"} 150
+# HELP gatews_reconnects_total Restored connections.
+# TYPE gatews_reconnects_total counter
+gatews_reconnects_total 1
+# HELP gatews_api_requests_outstanding Api requests waiting for responses.
+# TYPE gatews_api_requests_outstanding gauge
+gatews_api_requests_outstanding 3
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"gatews_received_bytes_total", "gatews_reconnects_total", "gatews_api_requests_outstanding"); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(m, "gatews_callback_duration_seconds"); n != 1 {
+		t.Fatalf("callback histograms = %d", n)
+	}
+}
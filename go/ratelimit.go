@@ -0,0 +1,255 @@
+package gatews
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit allows Rate requests per second on average with bursts up to Burst requests
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerInterval returns a limit of n requests per interval
+func PerInterval(n int, interval time.Duration) RateLimit {
+	return RateLimit{Rate: float64(n) / interval.Seconds(), Burst: n}
+}
+
+// DefaultRateLimits limits of api channels documented by Gate API v4, per connection
+func DefaultRateLimits() map[string]RateLimit {
+	return map[string]RateLimit{
+		ChannelSpotOrderPlace:     PerInterval(10, time.Second),
+		ChannelSpotOrderAmend:     PerInterval(10, time.Second),
+		ChannelSpotOrderCancel:    PerInterval(200, time.Second),
+		ChannelSpotOrderCancelIds: PerInterval(200, time.Second),
+		ChannelSpotOrderCancelCp:  PerInterval(200, time.Second),
+		ChannelSpotOrderStatus:    PerInterval(200, time.Second),
+
+		ChannelFutureOrderPlace:      PerInterval(100, time.Second),
+		ChannelFutureOrderBatchPlace: PerInterval(100, time.Second),
+		ChannelFutureOrderAmend:      PerInterval(100, time.Second),
+		ChannelFutureOrderCancel:     PerInterval(200, time.Second),
+		ChannelFutureOrderCancelCp:   PerInterval(200, time.Second),
+		ChannelFutureOrderStatus:     PerInterval(200, time.Second),
+		ChannelFutureOrderList:       PerInterval(200, time.Second),
+	}
+}
+
+// RateLimitError is returned by APIRequest when the limit of channel is reached and RateLimitFailFast is set
+type RateLimitError struct {
+	Channel    string
+	RetryAfter time.Duration
+}
+
+func (e RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit of %s reached, retry after %s", e.Channel, e.RetryAfter)
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	limit  float64 // burst set by SetLimit
+	tokens float64
+	last   time.Time
+	// the server reported no remaining requests until then
+	blockedUntil time.Time
+	// burst lowered by the server until then
+	burstUntil time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if !b.burstUntil.IsZero() && !now.Before(b.burstUntil) {
+		b.burst, b.burstUntil = b.limit, time.Time{}
+	}
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// take takes a token, or returns how long to wait for one
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	b.refill(now)
+	if now.Before(b.blockedUntil) {
+		return b.blockedUntil.Sub(now)
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// RateLimiter token buckets of api channels, channels without a limit are not limited.
+// Limits are adapted to the rate limit headers of api responses
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	clock   Clock
+}
+
+// NewRateLimiter limits by channel, e.g. DefaultRateLimits()
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	l := &RateLimiter{
+		buckets: make(map[string]*tokenBucket, len(limits)),
+		clock:   realClock{},
+	}
+	for channel, limit := range limits {
+		l.SetLimit(channel, limit)
+	}
+	return l
+}
+
+// SetClock sets the clock of the limiter, the real clock by default, buckets are refilled by clock from now.
+// NewWsService sets ConfOptions.Clock if set
+func (l *RateLimiter) SetClock(clock Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clock = clock
+	now := clock.Now()
+	for _, b := range l.buckets {
+		b.last, b.blockedUntil, b.burstUntil = now, time.Time{}, time.Time{}
+	}
+}
+
+func (l *RateLimiter) now() time.Time {
+	return l.clock.Now()
+}
+
+// SetLimit sets limit of channel, starting with a full bucket
+func (l *RateLimiter) SetLimit(channel string, limit RateLimit) {
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buckets[channel] = &tokenBucket{
+		rate:   limit.Rate,
+		burst:  float64(limit.Burst),
+		limit:  float64(limit.Burst),
+		tokens: float64(limit.Burst),
+		last:   l.now(),
+	}
+}
+
+func (l *RateLimiter) reserve(channel string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[channel]
+	if !ok {
+		return 0
+	}
+	return b.take(l.now())
+}
+
+// Allow takes a request of channel if available
+func (l *RateLimiter) Allow(channel string) bool {
+	return l.reserve(channel) == 0
+}
+
+// Wait blocks until a request of channel is available or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context, channel string) error {
+	return l.wait(ctx, nil, channel)
+}
+
+// wait is Wait also returning context.Canceled once closed is closed
+func (l *RateLimiter) wait(ctx context.Context, closed <-chan struct{}, channel string) error {
+	for {
+		wait := l.reserve(channel)
+		if wait == 0 {
+			return nil
+		}
+
+		l.mu.Lock()
+		timer := l.clock.NewTimer(wait)
+		l.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-closed:
+			timer.Stop()
+			return context.Canceled
+		case <-timer.C():
+		}
+	}
+}
+
+// Remaining returns requests of channel available now, false if channel is not limited
+func (l *RateLimiter) Remaining(channel string) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[channel]
+	if !ok {
+		return 0, false
+	}
+	now := l.now()
+	if now.Before(b.blockedUntil) {
+		return 0, true
+	}
+	b.refill(now)
+	return int(b.tokens), true
+}
+
+// Observe adapts the bucket of channel to the rate limit reported by the server: remaining requests
+// until reset, and limit per window. A limit lower than the one set applies until reset.
+// Zero limit means the server doesn't report it
+func (l *RateLimiter) Observe(channel string, remaining, limit int, reset time.Time) {
+	if limit <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[channel]
+	if !ok {
+		return
+	}
+	now := l.now()
+	b.refill(now)
+	b.burst, b.burstUntil = math.Min(b.limit, float64(limit)), time.Time{}
+	if b.burst < b.limit && reset.After(now) {
+		b.burstUntil = reset
+	}
+	if float64(remaining) < b.tokens {
+		b.tokens = float64(remaining)
+	}
+	if remaining <= 0 && reset.After(now) {
+		b.blockedUntil = reset
+	}
+}
+
+// observe adapts to the rate limit headers of an api response
+func (l *RateLimiter) observe(msg *UpdateMsg) {
+	h := &msg.Header
+	if h.RateLimitLimit == 0 {
+		return
+	}
+	l.Observe(h.Channel, h.RateLimitRemain, h.RateLimitLimit, h.RateLimitReset.Time())
+}
+
+// waitRateLimit takes a request of channel from the configured limiter, waiting until ctx is done
+// or the service is closed
+func (ws *WsService) waitRateLimit(ctx context.Context, channel string) error {
+	limiter := ws.conf.RateLimiter
+	if limiter == nil {
+		return nil
+	}
+	if !ws.conf.RateLimitFailFast {
+		return limiter.wait(ctx, ws.Ctx.Done(), channel)
+	}
+	if wait := limiter.reserve(channel); wait > 0 {
+		return RateLimitError{Channel: channel, RetryAfter: wait}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package gatews
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewRateLimiter(nil)
+	limiter.SetClock(clock)
+	limiter.SetLimit(ChannelSpotOrderPlace, PerInterval(2, time.Second))
+
+	if !limiter.Allow(ChannelSpotOrderPlace) || !limiter.Allow(ChannelSpotOrderPlace) || limiter.Allow(ChannelSpotOrderPlace) {
+		t.Fatal("unexpected burst")
+	}
+	if !limiter.Allow(ChannelSpotOrderStatus) {
+		t.Fatal("channel without limit is limited")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if n, _ := limiter.Remaining(ChannelSpotOrderPlace); n != 1 {
+		t.Fatalf("remaining = %d", n)
+	}
+
+	// the server reports no remaining requests until reset
+	var msg UpdateMsg
+	header := `{"header":{"channel":"spot.order_place","x_gate_ratelimit_requests_remain":0,"x_gate_ratelimit_limit":10,"x_gat_ratelimit_reset_timestamp":1700000002000}}`
+	if err := json.Unmarshal([]byte(header), &msg); err != nil {
+		t.Fatal(err)
+	}
+	limiter.observe(&msg)
+	if limiter.Allow(ChannelSpotOrderPlace) {
+		t.Fatal("allowed before reset")
+	}
+	if wait := limiter.reserve(ChannelSpotOrderPlace); wait != 1500*time.Millisecond {
+		t.Fatalf("wait = %s", wait)
+	}
+	clock.Advance(1500 * time.Millisecond)
+	if !limiter.Allow(ChannelSpotOrderPlace) {
+		t.Fatal("not allowed after reset")
+	}
+}
+
+func TestRateLimiterBurstRestored(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewRateLimiter(nil)
+	limiter.SetClock(clock)
+	limiter.SetLimit(ChannelSpotOrderPlace, PerInterval(10, time.Second))
+
+	limiter.Observe(ChannelSpotOrderPlace, 2, 2, clock.Now().Add(5*time.Second))
+	clock.Advance(2 * time.Second)
+	if n, _ := limiter.Remaining(ChannelSpotOrderPlace); n != 2 {
+		t.Fatalf("remaining = %d before reset", n)
+	}
+	clock.Advance(4 * time.Second)
+	if n, _ := limiter.Remaining(ChannelSpotOrderPlace); n != 10 {
+		t.Fatalf("remaining = %d after reset", n)
+	}
+}
+
+func TestRateLimiterWaitClock(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	limiter := NewRateLimiter(map[string]RateLimit{ChannelFutureOrderPlace: PerInterval(1, time.Minute)})
+	ws := &WsService{Ctx: context.Background(), conf: &ConnConf{RateLimiter: limiter}}
+	limiter.SetClock(clock)
+
+	limiter.Allow(ChannelFutureOrderPlace)
+	done := make(chan error, 1)
+	go func() { done <- ws.waitRateLimit(context.Background(), ChannelFutureOrderPlace) }()
+	if !clock.WaitTimers(1, testTimeout) {
+		t.Fatal("not waiting")
+	}
+	clock.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// the context of the request stops waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- ws.waitRateLimit(ctx, ChannelFutureOrderPlace) }()
+	if !clock.WaitTimers(1, testTimeout) {
+		t.Fatal("not waiting")
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestRateLimiterFailFast(t *testing.T) {
+	limiter := NewRateLimiter(map[string]RateLimit{ChannelFutureOrderPlace: PerInterval(1, time.Hour)})
+	ws := &WsService{Ctx: context.Background(), conf: &ConnConf{RateLimiter: limiter, RateLimitFailFast: true}}
+
+	if err := ws.waitRateLimit(context.Background(), ChannelFutureOrderPlace); err != nil {
+		t.Fatal(err)
+	}
+	var rateErr RateLimitError
+	if err := ws.waitRateLimit(context.Background(), ChannelFutureOrderPlace); !errors.As(err, &rateErr) || rateErr.RetryAfter <= 0 {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, ChannelFutureOrderPlace); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
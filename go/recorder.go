@@ -0,0 +1,189 @@
+package gatews
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// directions of recorded frames
+const (
+	FrameIn  = "in"
+	FrameOut = "out"
+)
+
+// RecordedFrame a websocket message read from or written to the server
+type RecordedFrame struct {
+	Time time.Time `json:"time"`
+	Dir  string    `json:"dir"`
+	// Data is the message if it's valid JSON, Text otherwise
+	Data json.RawMessage `json:"data,omitempty"`
+	Text string          `json:"text,omitempty"`
+}
+
+// Message returns the raw message of the frame
+func (f *RecordedFrame) Message() []byte {
+	if len(f.Data) > 0 {
+		return f.Data
+	}
+	return []byte(f.Text)
+}
+
+// Recorder writes frames of a WsService as JSON lines, set by ConfOptions.Recorder
+type Recorder struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	buf    *bufio.Writer
+	closer []io.Closer
+	err    error
+}
+
+// NewRecorder records frames to w
+func NewRecorder(w io.Writer) *Recorder {
+	buf := bufio.NewWriter(w)
+	return &Recorder{enc: json.NewEncoder(buf), buf: buf}
+}
+
+// CreateRecorder records frames to the file of path, gzipped if path ends with .gz
+func CreateRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		r := NewRecorder(f)
+		r.closer = []io.Closer{f}
+		return r, nil
+	}
+	gz := gzip.NewWriter(f)
+	r := NewRecorder(gz)
+	r.closer = []io.Closer{gz, f}
+	return r, nil
+}
+
+// redacted replaces credentials in recorded frames
+const redacted = "REDACTED"
+
+// credentialFields keys of api keys and signatures in the auth of subscriptions and the payload of api requests
+var credentialFields = map[string]bool{"KEY": true, "SIGN": true, "api_key": true, "signature": true}
+
+// redact returns raw with non-empty credential fields of its objects replaced, raw itself if there are none
+func redact(raw []byte) []byte {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return raw
+	}
+	changed := false
+	for k, v := range obj {
+		if credentialFields[k] {
+			var s string
+			if json.Unmarshal(v, &s) == nil && s == "" {
+				continue
+			}
+			obj[k], changed = json.RawMessage(`"`+redacted+`"`), true
+		} else if r := redact(v); !bytes.Equal(r, v) {
+			obj[k], changed = r, true
+		}
+	}
+	if !changed {
+		return raw
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// Record writes a frame of raw read or written at t. Api keys and signatures of written frames are redacted
+func (r *Recorder) Record(dir string, t time.Time, raw []byte) {
+	if dir == FrameOut {
+		raw = redact(raw)
+	}
+	frame := RecordedFrame{Time: t, Dir: dir}
+	if json.Valid(raw) {
+		frame.Data = raw
+	} else {
+		frame.Text = string(raw)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(frame)
+}
+
+// Flush writes buffered frames
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+	r.err = r.buf.Flush()
+	return r.err
+}
+
+// Err returns the first error of writing frames
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Close flushes frames and closes the file of CreateRecorder
+func (r *Recorder) Close() error {
+	err := r.Flush()
+	for _, c := range r.closer {
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// ReadRecording reads frames written by a Recorder, gzipped or not
+func ReadRecording(r io.Reader) ([]RecordedFrame, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	var frames []RecordedFrame
+	dec := json.NewDecoder(br)
+	for {
+		var f RecordedFrame
+		if err := dec.Decode(&f); err == io.EOF {
+			return frames, nil
+		} else if err != nil {
+			return frames, err
+		}
+		frames = append(frames, f)
+	}
+}
+
+// OpenRecording reads frames of the file of path
+func OpenRecording(path string) ([]RecordedFrame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadRecording(f)
+}
@@ -0,0 +1,154 @@
+package gatews
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/gatewstest"
+	"github.com/gateio/gatews/go/model"
+)
+
+// session subscribes trades and places an order, collecting messages of callbacks by channel
+func session(t *testing.T, ws *WsService, want int) map[string][]string {
+	t.Helper()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(want)
+	got := make(map[string][]string)
+	call := NewCallBack(func(msg *UpdateMsg) {
+		mu.Lock()
+		defer mu.Unlock()
+		channel := msg.GetChannel()
+		got[channel] = append(got[channel], string(msg.Event)+string(msg.Result)+string(msg.Data.Result))
+		wg.Done()
+	})
+	ws.SetCallBack(ChannelSpotPublicTrade, call)
+	ws.SetCallBack(ChannelSpotOrderPlace, call)
+	ws.SetCallBack(ChannelSpotLogin, call)
+
+	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BTC_USDT"}); err != nil {
+		t.Fatal(err)
+	}
+	order := model.Order{CurrencyPair: "BTC_USDT", Side: model.SideBuy, Amount: "1", Price: "100"}
+	if err := ws.APIRequest(ChannelSpotOrderPlace, order, map[string]any{"req_id": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	waitGroup(t, &wg)
+
+	mu.Lock()
+	defer mu.Unlock()
+	return got
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	srv := gatewstest.NewServer("KEY", "SECRET")
+	defer srv.Close()
+	srv.Handle(ChannelSpotOrderPlace, func(req gatewstest.APIRequest) gatewstest.APIResult {
+		return gatewstest.APIResult{Ack: true, Result: map[string]any{"id": "1", "status": "open"}}
+	})
+
+	path := filepath.Join(t.TempDir(), "session.jsonl.gz")
+	recorder, err := CreateRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := newTestService(t, srv, &ConfOptions{Key: "KEY", Secret: "SECRET", Recorder: recorder})
+	go func() {
+		if srv.WaitSubscribed(ChannelSpotPublicTrade, testTimeout) {
+			for i := 1; i <= 3; i++ {
+				srv.Push(ChannelSpotPublicTrade, map[string]any{"id": i, "currency_pair": "BTC_USDT"})
+				time.Sleep(20 * time.Millisecond)
+			}
+		}
+	}()
+	// subscribed, 3 trades, login, order ack and result
+	recorded := session(t, ws, 7)
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	frames, err := OpenRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := map[string]int{}
+	for _, f := range frames {
+		dirs[f.Dir]++
+		if f.Dir == FrameOut && (bytes.Contains(f.Data, []byte(`"KEY":"KEY"`)) || bytes.Contains(f.Data, []byte(`"api_key":"KEY"`))) {
+			t.Fatalf("credentials recorded %s", f.Data)
+		}
+	}
+	if dirs[FrameOut] != 3 || dirs[FrameIn] != 7 {
+		t.Fatalf("recorded frames %v", dirs)
+	}
+
+	server, err := NewReplayServer(frames, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	transport := NewReplayTransport(frames, 0)
+	defer transport.Close()
+
+	for _, r := range []struct {
+		name   string
+		op     *ConfOptions
+		replay interface{ Err() error }
+	}{
+		{"server", &ConfOptions{URL: server.URL}, server},
+		{"transport", &ConfOptions{Transport: transport}, transport},
+	} {
+		r.op.Key, r.op.Secret = "OTHER", "OTHER"
+		replayed, err := NewWsService(nil, nil, NewConnConfFromOption(r.op))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := session(t, replayed, 7); !reflect.DeepEqual(got, recorded) {
+			t.Fatalf("%s replayed %v\nrecorded %v", r.name, got, recorded)
+		}
+		if err := r.replay.Err(); err != nil {
+			t.Fatal(r.name, err)
+		}
+		replayed.Close()
+	}
+}
+
+func TestRecordingText(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	now := time.Now()
+	r.Record(FrameIn, now, []byte(`{"channel":"spot.pong"}`))
+	r.Record(FrameOut, now, []byte("not json"))
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	frames, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 || string(frames[0].Message()) != `{"channel":"spot.pong"}` ||
+		string(frames[1].Message()) != "not json" || !frames[1].Time.Equal(now) {
+		t.Fatalf("frames %+v", frames)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	for in, want := range map[string]string{
+		`{"channel":"spot.trades","auth":{"method":"api_key","KEY":"k","SIGN":"s"}}`: `{"auth":{"KEY":"REDACTED","SIGN":"REDACTED","method":"api_key"},"channel":"spot.trades"}`,
+		`{"payload":{"api_key":"k","signature":"s","req_id":"1"}}`:                   `{"payload":{"api_key":"REDACTED","req_id":"1","signature":"REDACTED"}}`,
+		`{"channel":"spot.trades", "auth":{"method":"","KEY":"","SIGN":""}}`:         `{"channel":"spot.trades", "auth":{"method":"","KEY":"","SIGN":""}}`,
+		`not json`: `not json`,
+	} {
+		if got := redact([]byte(in)); string(got) != want {
+			t.Errorf("%s: got %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package gatews
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// replay sends inbound frames of a recording paced by their recorded times divided by speed,
+// without delays if speed <= 0. Before replaying responses it waits for the client to send
+// the subscribe, unsubscribe and api requests recorded before them, so callbacks see the
+// same sequence as the recorded session. A recording is replayed to one connection
+type replay struct {
+	frames []RecordedFrame
+	speed  float64
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+func newReplay(frames []RecordedFrame, speed float64) *replay {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &replay{frames: frames, speed: speed, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+}
+
+// start returns false if the recording is replayed to another connection
+func (r *replay) start() bool {
+	first := false
+	r.once.Do(func() { first = true })
+	return first
+}
+
+func (r *replay) finish(err error) {
+	r.err = err
+	close(r.done)
+}
+
+// replayKey identifies requests across sessions, times and signatures differ
+type replayKey struct {
+	Channel string `json:"channel"`
+	Event   string `json:"event"`
+}
+
+func frameKey(raw []byte) (replayKey, bool) {
+	var k replayKey
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return k, false
+	}
+	// pings are sent by timers and not waited for
+	gated := (k.Event == Subscribe || k.Event == UnSubscribe || k.Event == API) && !strings.HasSuffix(k.Channel, ".ping")
+	return k, gated
+}
+
+func (r *replay) run(send func([]byte) error, requests <-chan []byte) error {
+	ctx := r.ctx
+	if len(r.frames) == 0 {
+		return nil
+	}
+	start, base := time.Now(), r.frames[0].Time
+	for _, f := range r.frames {
+		if f.Dir == FrameOut {
+			want, gated := frameKey(f.Message())
+			if !gated {
+				continue
+			}
+			if err := waitRequest(ctx, want, requests); err != nil {
+				return err
+			}
+			// responses are paced from the request
+			start, base = time.Now(), f.Time
+			continue
+		}
+
+		if r.speed > 0 {
+			due := start.Add(time.Duration(float64(f.Time.Sub(base)) / r.speed))
+			if d := time.Until(due); d > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(d):
+				}
+			}
+		}
+		if err := send(f.Message()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func waitRequest(ctx context.Context, want replayKey, requests <-chan []byte) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case raw, ok := <-requests:
+			if !ok {
+				return errReplayClosed
+			}
+			if k, _ := frameKey(raw); k == want {
+				return nil
+			}
+		}
+	}
+}
+
+var errReplayClosed = errors.New("connection closed before the recording is replayed")
+
+var errReplayStarted = errors.New("recording is replayed to another connection")
+
+// ReplayServer serves a recording to the first WsService connected to URL, e.g. ConfOptions.URL,
+// so its callbacks are called with the recorded messages
+type ReplayServer struct {
+	// URL websocket url of the server
+	URL string
+
+	replay   *replay
+	srv      *http.Server
+	upgrader websocket.Upgrader
+}
+
+// NewReplayServer serves frames at speed times the recorded speed, e.g. 1 for the original speed,
+// 10 for 10 times faster, or without delays if speed is 0
+func NewReplayServer(frames []RecordedFrame, speed float64) (*ReplayServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &ReplayServer{
+		URL:    "ws://" + l.Addr().String(),
+		replay: newReplay(frames, speed),
+	}
+	s.srv = &http.Server{Handler: http.HandlerFunc(s.serve)}
+	go s.srv.Serve(l)
+	return s, nil
+}
+
+func (s *ReplayServer) serve(w http.ResponseWriter, r *http.Request) {
+	if !s.replay.start() {
+		http.Error(w, errReplayStarted.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	c, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.replay.finish(err)
+		return
+	}
+	defer c.Close()
+
+	ctx := s.replay.ctx
+	requests := make(chan []byte, 1024)
+	go func() {
+		defer close(requests)
+		for {
+			_, raw, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case requests <- raw:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	s.replay.finish(s.replay.run(func(raw []byte) error {
+		return c.WriteMessage(websocket.TextMessage, raw)
+	}, requests))
+
+	// keep the connection until the client or the server closes it
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-requests:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// Done is closed when all frames are replayed or the replay fails
+func (s *ReplayServer) Done() <-chan struct{} {
+	return s.replay.done
+}
+
+// Err waits until Done is closed and returns the error of the replay
+func (s *ReplayServer) Err() error {
+	<-s.replay.done
+	return s.replay.err
+}
+
+// Close stops the server and the replay
+func (s *ReplayServer) Close() error {
+	s.replay.cancel()
+	return s.srv.Close()
+}
+
+// ReplayTransport replays a recording to the first connection dialed by it in memory, set by ConfOptions.Transport
+type ReplayTransport struct {
+	replay *replay
+}
+
+// NewReplayTransport replays frames at speed times the recorded speed as NewReplayServer
+func NewReplayTransport(frames []RecordedFrame, speed float64) *ReplayTransport {
+	return &ReplayTransport{replay: newReplay(frames, speed)}
+}
+
+func (t *ReplayTransport) Dial(ctx context.Context, url string) (Conn, error) {
+	if !t.replay.start() {
+		return nil, errReplayStarted
+	}
+	c := &replayConn{
+		ctx:      t.replay.ctx,
+		cancel:   t.replay.cancel,
+		messages: make(chan []byte),
+		requests: make(chan []byte, 1024),
+		closed:   make(chan struct{}),
+	}
+	go func() {
+		t.replay.finish(t.replay.run(c.push, c.requests))
+	}()
+	return c, nil
+}
+
+// Done is closed when all frames are replayed or the replay fails
+func (t *ReplayTransport) Done() <-chan struct{} {
+	return t.replay.done
+}
+
+// Err waits until Done is closed and returns the error of the replay
+func (t *ReplayTransport) Err() error {
+	<-t.replay.done
+	return t.replay.err
+}
+
+// Close stops the replay
+func (t *ReplayTransport) Close() error {
+	t.replay.cancel()
+	return nil
+}
+
+// replayConn in memory Conn of ReplayTransport
+type replayConn struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	messages chan []byte // replayed to the client
+	requests chan []byte // written by the client
+
+	mu            sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+	closeOnce     sync.Once
+	closed        chan struct{}
+}
+
+func (c *replayConn) push(raw []byte) error {
+	select {
+	case c.messages <- raw:
+		return nil
+	case <-c.closed:
+		return errReplayClosed
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
+
+// deadline returns a channel fired at t, nil if t is zero
+func deadline(t time.Time) (<-chan time.Time, func() bool) {
+	if t.IsZero() {
+		return nil, func() bool { return false }
+	}
+	timer := time.NewTimer(time.Until(t))
+	return timer.C, timer.Stop
+}
+
+func (c *replayConn) ReadMessage() (int, []byte, error) {
+	c.mu.Lock()
+	timeout, stop := deadline(c.readDeadline)
+	c.mu.Unlock()
+	defer stop()
+
+	select {
+	case raw := <-c.messages:
+		return TextMessage, raw, nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	case <-c.ctx.Done():
+		return 0, nil, c.ctx.Err()
+	case <-timeout:
+		return 0, nil, os.ErrDeadlineExceeded
+	}
+}
+
+func (c *replayConn) WriteMessage(_ int, data []byte) error {
+	c.mu.Lock()
+	timeout, stop := deadline(c.writeDeadline)
+	c.mu.Unlock()
+	defer stop()
+
+	select {
+	case c.requests <- data:
+		return nil
+	case <-c.closed:
+		return net.ErrClosed
+	case <-timeout:
+		return os.ErrDeadlineExceeded
+	}
+}
+
+func (c *replayConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readDeadline = t
+	return nil
+}
+
+func (c *replayConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeDeadline = t
+	return nil
+}
+
+// Close stops the replay
+func (c *replayConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	c.cancel()
+	return nil
+}
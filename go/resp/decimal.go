@@ -0,0 +1,65 @@
+package resp
+
+import (
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+// SpotOrderDec is SpotOrder with prices and amounts as decimal.Decimal
+type SpotOrderDec struct {
+	Left         decimal.Decimal   `json:"left"`
+	UpdateTime   model.Timestamp   `json:"update_time,omitempty"`
+	Amount       decimal.Decimal   `json:"amount"`
+	CreateTime   model.Timestamp   `json:"create_time,omitempty"`
+	Price        decimal.Decimal   `json:"price"`
+	FinishAs     model.FinishAs    `json:"finish_as,omitempty"`
+	StpAct       model.StpAct      `json:"stp_act,omitempty"`
+	TimeInForce  model.TimeInForce `json:"time_in_force,omitempty"`
+	CurrencyPair string            `json:"currency_pair"`
+	Type         model.OrderType   `json:"type,omitempty"`
+	Account      string            `json:"account,omitempty"`
+	Side         model.Side        `json:"side"`
+	AmendText    string            `json:"amend_text,omitempty"`
+	Text         string            `json:"text,omitempty"`
+	Status       model.OrderStatus `json:"status,omitempty"`
+	Iceberg      decimal.Decimal   `json:"iceberg"`
+	AvgDealPrice decimal.Decimal   `json:"avg_deal_price"`
+	FilledTotal  decimal.Decimal   `json:"filled_total"`
+	Id           string            `json:"id,omitempty"`
+	FillPrice    decimal.Decimal   `json:"fill_price"`
+	UpdateTimeMs model.Timestamp   `json:"update_time_ms,omitempty"`
+	CreateTimeMs model.Timestamp   `json:"create_time_ms,omitempty"`
+	StpId        int32             `json:"stp_id,omitempty"`
+	AutoRepay    bool              `json:"auto_repay,omitempty"`
+	AutoBorrow   bool              `json:"auto_borrow,omitempty"`
+	Succeeded    bool              `json:"succeeded"`
+}
+
+// FutureOrderDec is FutureOrder with prices as decimal.Decimal
+type FutureOrderDec struct {
+	Text         string            `json:"text,omitempty"`
+	Price        decimal.Decimal   `json:"price"`
+	BizInfo      string            `json:"biz_info,omitempty"`
+	Tif          model.TimeInForce `json:"tif,omitempty"`
+	AmendText    string            `json:"amend_text,omitempty"`
+	Status       model.OrderStatus `json:"status,omitempty"`
+	Contract     string            `json:"contract"`
+	StpAct       model.StpAct      `json:"stp_act,omitempty"`
+	FinishAs     model.FinishAs    `json:"finish_as,omitempty"`
+	FillPrice    decimal.Decimal   `json:"fill_price"`
+	AutoSize     string            `json:"auto_size,omitempty"`
+	Id           int64             `json:"id,omitempty"`
+	CreateTime   model.Timestamp   `json:"create_time,omitempty"`
+	Iceberg      int64             `json:"iceberg,omitempty"`
+	Size         int64             `json:"size"`
+	FinishTime   model.Timestamp   `json:"finish_time,omitempty"`
+	Left         int64             `json:"left"`
+	Refu         int32             `json:"refu,omitempty"`
+	User         int32             `json:"user,omitempty"`
+	StpId        int32             `json:"stp_id,omitempty"`
+	IsClose      bool              `json:"is_close,omitempty"`
+	Close        bool              `json:"close,omitempty"`
+	IsLiq        bool              `json:"is_liq,omitempty"`
+	IsReduceOnly bool              `json:"is_reduce_only,omitempty"`
+	ReduceOnly   bool              `json:"reduce_only,omitempty"`
+}
@@ -0,0 +1,294 @@
+package gatews
+
+import (
+	"github.com/gateio/gatews/go/decimal"
+	"github.com/gateio/gatews/go/model"
+)
+
+// Variants of the response structs with prices, amounts and fees as decimal.Decimal.
+// They decode the same payloads as their plain counterparts, numbers sent either as JSON strings or numbers.
+
+type SpotBalancesMsgDec struct {
+	Timestamp        model.Timestamp `json:"timestamp"`
+	TimestampInMilli model.Timestamp `json:"timestamp_ms"`
+	User             string          `json:"user"`
+	Asset            string          `json:"currency"`
+	Change           decimal.Decimal `json:"change"`
+	Total            decimal.Decimal `json:"total"`
+	Available        decimal.Decimal `json:"available"`
+	Freeze           decimal.Decimal `json:"freeze"`
+	FreezeChange     decimal.Decimal `json:"freeze_change"`
+	ChangeType       string          `json:"change_type"`
+}
+
+type SpotFundingBalancesMsgDec struct {
+	Timestamp        model.Timestamp `json:"timestamp"`
+	TimestampInMilli model.Timestamp `json:"timestamp_ms"`
+	User             string          `json:"user"`
+	Asset            string          `json:"currency"`
+	Change           decimal.Decimal `json:"change"`
+	Freeze           decimal.Decimal `json:"freeze"`
+	Lent             decimal.Decimal `json:"lent"`
+}
+
+type SpotMarginBalancesMsgDec struct {
+	Timestamp        model.Timestamp `json:"timestamp"`
+	TimestampInMilli model.Timestamp `json:"timestamp_ms"`
+	User             string          `json:"user"`
+	Market           string          `json:"currency_pair"`
+	Asset            string          `json:"currency"`
+	Change           decimal.Decimal `json:"change"`
+	Available        decimal.Decimal `json:"available"`
+	Freeze           decimal.Decimal `json:"freeze"`
+	Borrowed         decimal.Decimal `json:"borrowed"`
+	Interest         decimal.Decimal `json:"interest"`
+}
+
+type SpotBookTickerMsgDec struct {
+	TimeInMilli  model.Timestamp `json:"t"`
+	LastId       int64           `json:"u"`
+	CurrencyPair string          `json:"s"`
+	Bid          decimal.Decimal `json:"b"`
+	BidSize      decimal.Decimal `json:"B"`
+	Ask          decimal.Decimal `json:"a"`
+	AskSize      decimal.Decimal `json:"A"`
+}
+
+type SpotTickerMsgDec struct {
+	CurrencyPair     string          `json:"currency_pair,omitempty"`
+	Last             decimal.Decimal `json:"last"`
+	LowestAsk        decimal.Decimal `json:"lowest_ask"`
+	HighestBid       decimal.Decimal `json:"highest_bid"`
+	ChangePercentage decimal.Decimal `json:"change_percentage"`
+	BaseVolume       decimal.Decimal `json:"base_volume"`
+	QuoteVolume      decimal.Decimal `json:"quote_volume"`
+	High24h          decimal.Decimal `json:"high_24h"`
+	Low24h           decimal.Decimal `json:"low_24h"`
+}
+
+type SpotUserTradesMsgDec struct {
+	Id           uint64          `json:"id"`
+	UserId       uint64          `json:"user_id"`
+	OrderId      string          `json:"order_id"`
+	CurrencyPair string          `json:"currency_pair"`
+	CreateTime   model.Timestamp `json:"create_time"`
+	CreateTimeMs model.Timestamp `json:"create_time_ms"`
+	Side         model.Side      `json:"side"`
+	Amount       decimal.Decimal `json:"amount"`
+	Role         model.Role      `json:"role"`
+	Price        decimal.Decimal `json:"price"`
+	Fee          decimal.Decimal `json:"fee"`
+	FeeCurrency  string          `json:"fee_currency"`
+	PointFee     decimal.Decimal `json:"point_fee"`
+	GtFee        decimal.Decimal `json:"gt_fee"`
+	Text         string          `json:"text"`
+	AmendText    string          `json:"amend_text"`
+	BizInfo      string          `json:"biz_info"`
+}
+
+type SpotTradeMsgDec struct {
+	Id           uint64          `json:"id"`
+	CreateTime   model.Timestamp `json:"create_time"`
+	CreateTimeMs model.Timestamp `json:"create_time_ms"`
+	Side         model.Side      `json:"side"`
+	CurrencyPair string          `json:"currency_pair"`
+	Amount       decimal.Decimal `json:"amount"`
+	Price        decimal.Decimal `json:"price"`
+}
+
+type OrderMsgDec struct {
+	Id                 string            `json:"id,omitempty"`
+	Text               string            `json:"text,omitempty"`
+	CreateTime         model.Timestamp   `json:"create_time,omitempty"`
+	UpdateTime         model.Timestamp   `json:"update_time,omitempty"`
+	CurrencyPair       string            `json:"currency_pair"`
+	Type               model.OrderType   `json:"type,omitempty"`
+	Account            string            `json:"account,omitempty"`
+	Side               model.Side        `json:"side"`
+	Amount             decimal.Decimal   `json:"amount"`
+	Price              decimal.Decimal   `json:"price"`
+	TimeInForce        model.TimeInForce `json:"time_in_force,omitempty"`
+	Iceberg            decimal.Decimal   `json:"iceberg"`
+	AutoBorrow         bool              `json:"auto_borrow,omitempty"`
+	Left               decimal.Decimal   `json:"left"`
+	FillPrice          decimal.Decimal   `json:"fill_price"`
+	FilledTotal        decimal.Decimal   `json:"filled_total"`
+	AvgDealPrice       decimal.Decimal   `json:"avg_deal_price"`
+	Fee                decimal.Decimal   `json:"fee"`
+	FeeCurrency        string            `json:"fee_currency,omitempty"`
+	PointFee           decimal.Decimal   `json:"point_fee"`
+	GtFee              decimal.Decimal   `json:"gt_fee"`
+	GtDiscount         bool              `json:"gt_discount,omitempty"`
+	RebatedFee         decimal.Decimal   `json:"rebated_fee"`
+	RebatedFeeCurrency string            `json:"rebated_fee_currency,omitempty"`
+	StpId              int64             `json:"stp_id,omitempty"`
+	StpAct             model.StpAct      `json:"stp_act,omitempty"`
+	FinishAs           model.FinishAs    `json:"finish_as,omitempty"`
+	BizInfo            string            `json:"biz_info,omitempty"`
+	AmendText          string            `json:"amend_text,omitempty"`
+}
+
+type SpotOrderMsgDec struct {
+	OrderMsgDec
+	CreateTimeMs model.Timestamp  `json:"create_time_ms,omitempty"`
+	UpdateTimeMs model.Timestamp  `json:"update_time_ms,omitempty"`
+	User         int64            `json:"user"`
+	Event        model.OrderEvent `json:"event"`
+}
+
+type FuturesTickerDec struct {
+	Contract              string          `json:"contract,omitempty"`
+	Last                  decimal.Decimal `json:"last"`
+	ChangePercentage      decimal.Decimal `json:"change_percentage"`
+	TotalSize             decimal.Decimal `json:"total_size"`
+	Volume24h             decimal.Decimal `json:"volume_24h"`
+	Volume24hBase         decimal.Decimal `json:"volume_24h_base"`
+	Volume24hQuote        decimal.Decimal `json:"volume_24h_quote"`
+	Volume24hSettle       decimal.Decimal `json:"volume_24h_settle"`
+	Volume24Usd           decimal.Decimal `json:"volume_24_usd"`
+	Volume24Btc           decimal.Decimal `json:"volume_24_btc"`
+	MarkPrice             decimal.Decimal `json:"mark_price"`
+	FundingRate           decimal.Decimal `json:"funding_rate"`
+	FundingRateIndicative decimal.Decimal `json:"funding_rate_indicative"`
+	IndexPrice            decimal.Decimal `json:"index_price"`
+	QuantoBaseRate        decimal.Decimal `json:"quanto_base_rate"`
+	Low24h                decimal.Decimal `json:"low_24h"`
+	High24h               decimal.Decimal `json:"high_24h"`
+}
+
+type FuturesTradeDec struct {
+	Id           int64           `json:"id,omitempty"`
+	CreateTime   model.Timestamp `json:"create_time,omitempty"`
+	CreateTimeMs model.Timestamp `json:"create_time_ms,omitempty"`
+	Contract     string          `json:"contract,omitempty"`
+	Size         int64           `json:"size,omitempty"`
+	Price        decimal.Decimal `json:"price"`
+}
+
+type FuturesOrderDec struct {
+	Id              int64             `json:"id,omitempty"`
+	User            string            `json:"user,omitempty"`
+	CreateTime      model.Timestamp   `json:"create_time,omitempty"`
+	CreateTimeMs    model.Timestamp   `json:"create_time_ms,omitempty"`
+	FinishTime      model.Timestamp   `json:"finish_time,omitempty"`
+	FinishTimeMs    model.Timestamp   `json:"finish_time_ms,omitempty"`
+	FinishAs        model.FinishAs    `json:"finish_as,omitempty"`
+	Contract        string            `json:"contract"`
+	Size            int64             `json:"size"`
+	Iceberg         int64             `json:"iceberg,omitempty"`
+	Price           decimal.Decimal   `json:"price"`
+	IsClose         bool              `json:"is_close,omitempty"`
+	IsReduceOnly    bool              `json:"is_reduce_only,omitempty"`
+	IsLiq           bool              `json:"is_liq,omitempty"`
+	Tif             model.TimeInForce `json:"tif,omitempty"`
+	Left            int64             `json:"left,omitempty"`
+	FillPrice       decimal.Decimal   `json:"fill_price"`
+	Text            string            `json:"text,omitempty"`
+	Tkfr            decimal.Decimal   `json:"tkfr"`
+	Mkfr            decimal.Decimal   `json:"mkfr"`
+	Refu            int32             `json:"refu,omitempty"`
+	Refr            decimal.Decimal   `json:"refr"`
+	StopProfitPrice decimal.Decimal   `json:"stop_profit_price"`
+	StopLossPrice   decimal.Decimal   `json:"stop_loss_price"`
+	StpId           int64             `json:"stp_id,omitempty"`
+	StpAct          model.StpAct      `json:"stp_act,omitempty"`
+	BizInfo         string            `json:"biz_info,omitempty"`
+	AmendText       string            `json:"amend_text,omitempty"`
+}
+
+type FuturesUserTradeDec struct {
+	Contract     string          `json:"contract"`
+	CreateTime   model.Timestamp `json:"create_time,omitempty"`
+	CreateTimeMs model.Timestamp `json:"create_time_ms,omitempty"`
+	Id           string          `json:"id"`
+	OrderId      string          `json:"order_id"`
+	Price        decimal.Decimal `json:"price"`
+	Size         int64           `json:"size"`
+	Role         model.Role      `json:"role"`
+	Text         string          `json:"text"`
+	Fee          decimal.Decimal `json:"fee"`
+	PointFee     decimal.Decimal `json:"point_fee"`
+}
+
+type FuturesLiquidateDec struct {
+	Time       model.Timestamp `json:"time,omitempty"`
+	TimeMs     model.Timestamp `json:"time_ms"`
+	Contract   string          `json:"contract,omitempty"`
+	Leverage   decimal.Decimal `json:"leverage"`
+	Size       int64           `json:"size,omitempty"`
+	Margin     decimal.Decimal `json:"margin"`
+	EntryPrice decimal.Decimal `json:"entry_price"`
+	LiqPrice   decimal.Decimal `json:"liq_price"`
+	MarkPrice  decimal.Decimal `json:"mark_price"`
+	OrderId    int64           `json:"order_id,omitempty"`
+	OrderPrice decimal.Decimal `json:"order_price"`
+	FillPrice  decimal.Decimal `json:"fill_price"`
+	Left       int64           `json:"left,omitempty"`
+	User       string          `json:"user"`
+}
+
+type FuturesAutoDeleveragesDec struct {
+	EntryPrice   decimal.Decimal `json:"entry_price"`
+	FillPrice    decimal.Decimal `json:"fill_price"`
+	PositionSize int64           `json:"position_size"`
+	TradeSize    int64           `json:"trade_size"`
+	Time         model.Timestamp `json:"time"`
+	TimeMs       model.Timestamp `json:"time_ms"`
+	Contract     string          `json:"contract"`
+	User         string          `json:"user"`
+}
+
+type FuturesPositionClosesDec struct {
+	Contract string          `json:"contract"`
+	Pnl      decimal.Decimal `json:"pnl"`
+	Side     string          `json:"side"`
+	Text     string          `json:"text"`
+	Time     model.Timestamp `json:"time"`
+	TimeMs   model.Timestamp `json:"time_ms"`
+	User     string          `json:"user"`
+}
+
+type FuturesBalanceDec struct {
+	Balance  decimal.Decimal `json:"balance"`
+	Change   decimal.Decimal `json:"change"`
+	Text     string          `json:"text"`
+	Time     model.Timestamp `json:"time"`
+	TimeMs   model.Timestamp `json:"time_ms"`
+	User     string          `json:"user"`
+	Type     string          `json:"type"`
+	Currency string          `json:"currency"`
+}
+
+type FuturesReduceRiskLimitsDec struct {
+	CancelOrders    int64           `json:"cancel_orders"`
+	Contract        string          `json:"contract"`
+	LeverageMax     decimal.Decimal `json:"leverage_max"`
+	LiqPrice        decimal.Decimal `json:"liq_price"`
+	MaintenanceRate decimal.Decimal `json:"maintenance_rate"`
+	RiskLimit       decimal.Decimal `json:"risk_limit"`
+	Time            model.Timestamp `json:"time"`
+	TimeMs          model.Timestamp `json:"time_ms"`
+	User            string          `json:"user"`
+}
+
+type FuturesPositionsDec struct {
+	Contract           string          `json:"contract"`
+	CrossLeverageLimit decimal.Decimal `json:"cross_leverage_limit"`
+	EntryPrice         decimal.Decimal `json:"entry_price"`
+	HistoryPnl         decimal.Decimal `json:"history_pnl"`
+	HistoryPoint       decimal.Decimal `json:"history_point"`
+	LastClosePnl       decimal.Decimal `json:"last_close_pnl"`
+	Leverage           decimal.Decimal `json:"leverage"`
+	LeverageMax        decimal.Decimal `json:"leverage_max"`
+	LiqPrice           decimal.Decimal `json:"liq_price"`
+	MaintenanceRate    decimal.Decimal `json:"maintenance_rate"`
+	Margin             decimal.Decimal `json:"margin"`
+	Mode               string          `json:"mode"`
+	RealisedPnl        decimal.Decimal `json:"realised_pnl"`
+	RealisedPoint      decimal.Decimal `json:"realised_point"`
+	RiskLimit          decimal.Decimal `json:"risk_limit"`
+	Size               int64           `json:"size"`
+	Time               model.Timestamp `json:"time"`
+	TimeMs             model.Timestamp `json:"time_ms"`
+	User               string          `json:"user"`
+}
@@ -0,0 +1,26 @@
+package gatews
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecimalResponses(t *testing.T) {
+	var positions []FuturesPositionsDec
+	payload := `[{"contract":"BTC_USD","entry_price":40000.123456789,"margin":0.1,"realised_pnl":-0.000123,"size":10,"user":"1"}]`
+	if err := json.Unmarshal([]byte(payload), &positions); err != nil {
+		t.Fatal(err)
+	}
+	if p := positions[0]; p.EntryPrice.String() != "40000.123456789" || p.Margin.String() != "0.1" || p.RealisedPnl.String() != "-0.000123" {
+		t.Fatalf("unexpected position: %+v", p)
+	}
+
+	var ticker SpotTickerMsgDec
+	payload = `{"currency_pair":"BTC_USDT","last":"19106.55","lowest_ask":"19108","highest_bid":"","change_percentage":"-0.1"}`
+	if err := json.Unmarshal([]byte(payload), &ticker); err != nil {
+		t.Fatal(err)
+	}
+	if ticker.Last.String() != "19106.55" || !ticker.HighestBid.IsZero() || !ticker.ChangePercentage.IsNegative() {
+		t.Fatalf("unexpected ticker: %+v", ticker)
+	}
+}
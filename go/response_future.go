@@ -1,5 +1,7 @@
 package gatews
 
+import "github.com/gateio/gatews/go/model"
+
 type FuturesTicker struct {
 	// Futures contract
 	Contract string `json:"contract,omitempty"`
@@ -37,9 +39,9 @@ type FuturesTrade struct {
 	// Trade ID
 	Id int64 `json:"id,omitempty"`
 	// Trading time
-	CreateTime int64 `json:"create_time,omitempty"`
+	CreateTime model.Timestamp `json:"create_time,omitempty"`
 	// Trading time, with milliseconds set to 3 decimal places.
-	CreateTimeMs int64 `json:"create_time_ms,omitempty"`
+	CreateTimeMs model.Timestamp `json:"create_time_ms,omitempty"`
 	// Futures contract
 	Contract string `json:"contract,omitempty"`
 	// Trading size
@@ -57,9 +59,9 @@ type FuturesOrderBookItem struct {
 
 type FuturesOrderBook struct {
 	// Order Book ID. Increase by 1 on every order book change. Set `with_id=true` to include this field in response
-	Id       int64  `json:"id,omitempty"`
-	Contract string `json:"contract"`
-	Time     int64  `json:"t"`
+	Id       int64           `json:"id,omitempty"`
+	Contract string          `json:"contract"`
+	Time     model.Timestamp `json:"t"`
 	// Asks order depth
 	Asks []FuturesOrderBookItem `json:"asks"`
 	// Bids order depth
@@ -74,20 +76,20 @@ type FuturesOrderBookAll struct {
 }
 
 type FuturesBookTicker struct {
-	TimeMillis   int64  `json:"t"`
-	Contract     string `json:"s"`
-	UpdateId     int64  `json:"u"`
-	BestBidPrice string `json:"b"`
-	BestBidSize  int64  `json:"B"`
-	BestAskPrice string `json:"a"`
-	BestAskSize  int64  `json:"A"`
+	TimeMillis   model.Timestamp `json:"t"`
+	Contract     string          `json:"s"`
+	UpdateId     int64           `json:"u"`
+	BestBidPrice string          `json:"b"`
+	BestBidSize  int64           `json:"B"`
+	BestAskPrice string          `json:"a"`
+	BestAskSize  int64           `json:"A"`
 }
 
 type FuturesOrderBookUpdate struct {
-	TimeMillis int64  `json:"t"`
-	Contract   string `json:"s"`
-	FirstId    int64  `json:"U"`
-	LastId     int64  `json:"u"`
+	TimeMillis model.Timestamp `json:"t"`
+	Contract   string          `json:"s"`
+	FirstId    int64           `json:"U"`
+	LastId     int64           `json:"u"`
 	// Asks order depth
 	Asks []FuturesOrderBookItem `json:"a"`
 	// Bids order depth
@@ -96,7 +98,7 @@ type FuturesOrderBookUpdate struct {
 
 type FuturesCandlestick struct {
 	// Unix timestamp in seconds
-	T int64 `json:"t,omitempty"`
+	T model.Timestamp `json:"t,omitempty"`
 	// size volume. Only returned if `contract` is not prefixed
 	V int64 `json:"v,omitempty"`
 	// Close price
@@ -118,11 +120,11 @@ type FuturesOrder struct {
 	// User ID
 	User string `json:"user,omitempty"`
 	// Order creation time
-	CreateTime   int64 `json:"create_time,omitempty"`
-	CreateTimeMs int64 `json:"create_time_ms,omitempty"`
+	CreateTime   model.Timestamp `json:"create_time,omitempty"`
+	CreateTimeMs model.Timestamp `json:"create_time_ms,omitempty"`
 	// Order finished time. Not returned if order is open
-	FinishTime   int64 `json:"finish_time,omitempty"`
-	FinishTimeMs int64 `json:"finish_time_ms,omitempty"`
+	FinishTime   model.Timestamp `json:"finish_time,omitempty"`
+	FinishTimeMs model.Timestamp `json:"finish_time_ms,omitempty"`
 	// FinishAs indicates how the order was completed:
 	// - filled: all filled
 	// - cancelled: manually cancelled
@@ -135,7 +137,7 @@ type FuturesOrder struct {
 	// - _new: order created
 	// - _update: order filled, partially filled, or updated
 	// - reduce_out: only reduce position, excluding pending orders hard to execute
-	FinishAs string `json:"finish_as,omitempty"`
+	FinishAs model.FinishAs `json:"finish_as,omitempty"`
 	// Futures contract
 	Contract string `json:"contract"`
 	// Order size. Specify positive number to make a bid, and negative number to ask
@@ -151,7 +153,7 @@ type FuturesOrder struct {
 	// Is the order for liquidation
 	IsLiq bool `json:"is_liq,omitempty"`
 	// Time in force  - gtc: GoodTillCancelled - ioc: ImmediateOrCancelled, taker only - poc: PendingOrCancelled, reduce-only
-	Tif string `json:"tif,omitempty"`
+	Tif model.TimeInForce `json:"tif,omitempty"`
 	// Size left to be traded
 	Left int64 `json:"left,omitempty"`
 	// Fill price of the order
@@ -175,7 +177,7 @@ type FuturesOrder struct {
 	// - co: Cancel oldest (keep new orders)
 	// - cb: Cancel both (cancel both old and new orders)
 	// If not provided, defaults to 'cn'. Requires STP group membership; otherwise, an error is returned.
-	StpAct string `json:"stp_act,omitempty"`
+	StpAct model.StpAct `json:"stp_act,omitempty"`
 	// BizInfo represents business-specific information related to the order. The exact content and format can vary depending on the use case.
 	BizInfo string `json:"biz_info,omitempty"`
 	// AmendText provides the custom data that the user remarked when amending the order
@@ -185,24 +187,24 @@ type FuturesOrder struct {
 type FuturesUserTrade struct {
 	Contract string `json:"contract"`
 	// Trading time
-	CreateTime int64 `json:"create_time,omitempty"`
+	CreateTime model.Timestamp `json:"create_time,omitempty"`
 	// Trading time, with milliseconds set to 3 decimal places.
-	CreateTimeMs int64   `json:"create_time_ms,omitempty"`
-	Id           string  `json:"id"`
-	OrderId      string  `json:"order_id"`
-	Price        string  `json:"price"`
-	Size         int64   `json:"size"`
-	Role         string  `json:"role"`
-	Text         string  `json:"text"`
-	Fee          float64 `json:"fee"`
-	PointFee     float64 `json:"point_fee"`
+	CreateTimeMs model.Timestamp `json:"create_time_ms,omitempty"`
+	Id           string          `json:"id"`
+	OrderId      string          `json:"order_id"`
+	Price        string          `json:"price"`
+	Size         int64           `json:"size"`
+	Role         model.Role      `json:"role"`
+	Text         string          `json:"text"`
+	Fee          float64         `json:"fee"`
+	PointFee     float64         `json:"point_fee"`
 }
 
 type FuturesLiquidate struct {
 	// Liquidation time
-	Time int64 `json:"time,omitempty"`
+	Time model.Timestamp `json:"time,omitempty"`
 	// time in milliseconds
-	TimeMs int64 `json:"time_ms"`
+	TimeMs model.Timestamp `json:"time_ms"`
 	// Futures contract
 	Contract string `json:"contract,omitempty"`
 	// Position leverage. Not returned in public endpoints.
@@ -230,69 +232,69 @@ type FuturesLiquidate struct {
 }
 
 type FuturesAutoDeleverages struct {
-	EntryPrice   float64 `json:"entry_price"`
-	FillPrice    float64 `json:"fill_price"`
-	PositionSize int64   `json:"position_size"`
-	TradeSize    int64   `json:"trade_size"`
-	Time         int64   `json:"time"`
-	TimeMs       int64   `json:"time_ms"`
-	Contract     string  `json:"contract"`
-	User         string  `json:"user"`
+	EntryPrice   float64         `json:"entry_price"`
+	FillPrice    float64         `json:"fill_price"`
+	PositionSize int64           `json:"position_size"`
+	TradeSize    int64           `json:"trade_size"`
+	Time         model.Timestamp `json:"time"`
+	TimeMs       model.Timestamp `json:"time_ms"`
+	Contract     string          `json:"contract"`
+	User         string          `json:"user"`
 }
 
 type FuturesPositionCloses struct {
-	Contract string  `json:"contract"`
-	Pnl      float64 `json:"pnl"`
-	Side     string  `json:"side"`
-	Text     string  `json:"text"`
-	Time     int64   `json:"time"`
-	TimeMs   int64   `json:"time_ms"`
-	User     string  `json:"user"`
+	Contract string          `json:"contract"`
+	Pnl      float64         `json:"pnl"`
+	Side     string          `json:"side"`
+	Text     string          `json:"text"`
+	Time     model.Timestamp `json:"time"`
+	TimeMs   model.Timestamp `json:"time_ms"`
+	User     string          `json:"user"`
 }
 
 type FuturesBalance struct {
-	Balance  float64 `json:"balance"`
-	Change   float64 `json:"change"`
-	Text     string  `json:"text"`
-	Time     int64   `json:"time"`
-	TimeMs   int64   `json:"time_ms"`
-	User     string  `json:"user"`
-	Type     string  `json:"type"`
-	Currency string  `json:"currency"`
+	Balance  float64         `json:"balance"`
+	Change   float64         `json:"change"`
+	Text     string          `json:"text"`
+	Time     model.Timestamp `json:"time"`
+	TimeMs   model.Timestamp `json:"time_ms"`
+	User     string          `json:"user"`
+	Type     string          `json:"type"`
+	Currency string          `json:"currency"`
 }
 
 type FuturesReduceRiskLimits struct {
-	CancelOrders    int64   `json:"cancel_orders"`
-	Contract        string  `json:"contract"`
-	LeverageMax     float64 `json:"leverage_max"`
-	LiqPrice        float64 `json:"liq_price"`
-	MaintenanceRate float64 `json:"maintenance_rate"`
-	RiskLimit       float64 `json:"risk_limit"`
-	Time            int64   `json:"time"`
-	TimeMs          int64   `json:"time_ms"`
-	User            string  `json:"user"`
+	CancelOrders    int64           `json:"cancel_orders"`
+	Contract        string          `json:"contract"`
+	LeverageMax     float64         `json:"leverage_max"`
+	LiqPrice        float64         `json:"liq_price"`
+	MaintenanceRate float64         `json:"maintenance_rate"`
+	RiskLimit       float64         `json:"risk_limit"`
+	Time            model.Timestamp `json:"time"`
+	TimeMs          model.Timestamp `json:"time_ms"`
+	User            string          `json:"user"`
 }
 
 type FuturesPositions struct {
-	Contract           string  `json:"contract"`
-	CrossLeverageLimit float64 `json:"cross_leverage_limit"`
-	EntryPrice         float64 `json:"entry_price"`
-	HistoryPnl         float64 `json:"history_pnl"`
-	HistoryPoint       float64 `json:"history_point"`
-	LastClosePnl       float64 `json:"last_close_pnl"`
-	Leverage           float64 `json:"leverage"`
-	LeverageMax        float64 `json:"leverage_max"`
-	LiqPrice           float64 `json:"liq_price"`
-	MaintenanceRate    float64 `json:"maintenance_rate"`
-	Margin             float64 `json:"margin"`
-	Mode               string  `json:"mode"`
-	RealisedPnl        float64 `json:"realised_pnl"`
-	RealisedPoint      float64 `json:"realised_point"`
-	RiskLimit          float64 `json:"risk_limit"`
-	Size               int64   `json:"size"`
-	Time               int64   `json:"time"`
-	TimeMs             int64   `json:"time_ms"`
-	User               string  `json:"user"`
+	Contract           string          `json:"contract"`
+	CrossLeverageLimit float64         `json:"cross_leverage_limit"`
+	EntryPrice         float64         `json:"entry_price"`
+	HistoryPnl         float64         `json:"history_pnl"`
+	HistoryPoint       float64         `json:"history_point"`
+	LastClosePnl       float64         `json:"last_close_pnl"`
+	Leverage           float64         `json:"leverage"`
+	LeverageMax        float64         `json:"leverage_max"`
+	LiqPrice           float64         `json:"liq_price"`
+	MaintenanceRate    float64         `json:"maintenance_rate"`
+	Margin             float64         `json:"margin"`
+	Mode               string          `json:"mode"`
+	RealisedPnl        float64         `json:"realised_pnl"`
+	RealisedPoint      float64         `json:"realised_point"`
+	RiskLimit          float64         `json:"risk_limit"`
+	Size               int64           `json:"size"`
+	Time               model.Timestamp `json:"time"`
+	TimeMs             model.Timestamp `json:"time_ms"`
+	User               string          `json:"user"`
 }
 
 type FuturesAutoOrder struct {
@@ -304,9 +306,9 @@ type FuturesAutoOrder struct {
 	// User ID
 	User int64 `json:"user,omitempty"`
 	// Creation time
-	CreateTime int64 `json:"create_time,omitempty"`
+	CreateTime model.Timestamp `json:"create_time,omitempty"`
 	// Finished time
-	FinishTime int64 `json:"finish_time,omitempty"`
+	FinishTime model.Timestamp `json:"finish_time,omitempty"`
 	// ID of the newly created order on condition triggered
 	TradeId int64 `json:"trade_id,omitempty"`
 	// Order status.
@@ -347,7 +349,7 @@ type FuturesInitialOrder struct {
 	// Order price. Set to 0 to use market price
 	Price string `json:"price"`
 	// Time in force. If using market price, only `ioc` is supported.  - gtc: GoodTillCancelled - ioc: ImmediateOrCancelled
-	Tif string `json:"tif,omitempty"`
+	Tif model.TimeInForce `json:"tif,omitempty"`
 	// How the order is created. Possible values are: web, api and app
 	Text    string `json:"text,omitempty"`
 	Iceberg int64  `json:"iceberg"`
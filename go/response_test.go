@@ -0,0 +1,226 @@
+package gatews
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/gatewstest"
+	"github.com/gateio/gatews/go/resp"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata/golden")
+
+func newOf[T any]() any { return new(T) }
+
+// decoders types the result of each channel in testdata/channels is decoded into
+var decoders = map[string][]func() any{
+	ChannelSpotBalance:            {newOf[[]SpotBalancesMsg], newOf[[]SpotBalancesMsgDec]},
+	ChannelSpotCandleStick:        {newOf[SpotCandleUpdateMsg]},
+	ChannelSpotOrder:              {newOf[[]SpotOrderMsg], newOf[[]SpotOrderMsgDec]},
+	ChannelSpotOrderBook:          {newOf[SpotUpdateAllDepthMsg]},
+	ChannelSpotBookTicker:         {newOf[SpotBookTickerMsg], newOf[SpotBookTickerMsgDec]},
+	ChannelSpotOrderBookUpdate:    {newOf[SpotUpdateDepthMsg]},
+	ChannelSpotTicker:             {newOf[SpotTickerMsg], newOf[SpotTickerMsgDec]},
+	ChannelSpotUserTrade:          {newOf[[]SpotUserTradesMsg], newOf[[]SpotUserTradesMsgDec]},
+	ChannelSpotPublicTrade:        {newOf[SpotTradeMsg], newOf[SpotTradeMsgDec]},
+	ChannelSpotFundingBalance:     {newOf[[]SpotFundingBalancesMsg], newOf[[]SpotFundingBalancesMsgDec]},
+	ChannelSpotMarginBalance:      {newOf[[]SpotMarginBalancesMsg], newOf[[]SpotMarginBalancesMsgDec]},
+	ChannelSpotCrossBalance:       {newOf[[]SpotBalancesMsg], newOf[[]SpotBalancesMsgDec]},
+	ChannelSpotOrderPlace:         {newOf[resp.SpotOrder], newOf[resp.SpotOrderDec]},
+	ChannelFutureTicker:           {newOf[[]FuturesTicker], newOf[[]FuturesTickerDec]},
+	ChannelFutureTrade:            {newOf[[]FuturesTrade], newOf[[]FuturesTradeDec]},
+	ChannelFutureOrderBook:        {newOf[FuturesOrderBook]},
+	ChannelFutureBookTicker:       {newOf[FuturesBookTicker]},
+	ChannelFutureOrderBookUpdate:  {newOf[FuturesOrderBookUpdate]},
+	ChannelFutureCandleStick:      {newOf[[]FuturesCandlestick]},
+	ChannelFutureOrder:            {newOf[[]FuturesOrder], newOf[[]FuturesOrderDec]},
+	ChannelFutureUserTrade:        {newOf[[]FuturesUserTrade], newOf[[]FuturesUserTradeDec]},
+	ChannelFutureLiquidates:       {newOf[[]FuturesLiquidate], newOf[[]FuturesLiquidateDec]},
+	ChannelFutureAutoDeleverages:  {newOf[[]FuturesAutoDeleverages], newOf[[]FuturesAutoDeleveragesDec]},
+	ChannelFuturePositionCloses:   {newOf[[]FuturesPositionCloses], newOf[[]FuturesPositionClosesDec]},
+	ChannelFutureBalance:          {newOf[[]FuturesBalance], newOf[[]FuturesBalanceDec]},
+	ChannelFutureReduceRiskLimits: {newOf[[]FuturesReduceRiskLimits], newOf[[]FuturesReduceRiskLimitsDec]},
+	ChannelFuturePositions:        {newOf[[]FuturesPositions], newOf[[]FuturesPositionsDec]},
+	ChannelFutureAutoOrders:       {newOf[[]FuturesAutoOrder]},
+	ChannelFutureOrderPlace:       {newOf[resp.FutureOrder], newOf[resp.FutureOrderDec]},
+	ChannelFutureOrderList:        {newOf[[]resp.FutureOrder], newOf[[]resp.FutureOrderDec]},
+}
+
+// corpus returns the sample frames of testdata/channels by channel
+func corpus(tb testing.TB) map[string][]byte {
+	tb.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "channels", "*.json"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	frames := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		frames[strings.TrimSuffix(filepath.Base(path), ".json")] = data
+	}
+	return frames
+}
+
+// resultOf returns result of pushes, or data.result of api responses
+func resultOf(msg *UpdateMsg) json.RawMessage {
+	if len(msg.Result) > 0 {
+		return msg.Result
+	}
+	return msg.Data.Result
+}
+
+func TestDecodeGolden(t *testing.T) {
+	frames := corpus(t)
+	for channel := range decoders {
+		if _, ok := frames[channel]; !ok {
+			t.Errorf("no sample of %s in testdata/channels", channel)
+		}
+	}
+
+	for channel, frame := range frames {
+		t.Run(channel, func(t *testing.T) {
+			news, ok := decoders[channel]
+			if !ok {
+				t.Fatal("no decoder")
+			}
+			var msg UpdateMsg
+			if err := json.Unmarshal(frame, &msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg.GetChannel() != channel || msg.ExchangeTime().IsZero() {
+				t.Fatalf("channel %q at %s", msg.GetChannel(), msg.ExchangeTime())
+			}
+
+			decoded := make([]any, 0, len(news))
+			for _, newV := range news {
+				v := newV()
+				if err := json.Unmarshal(resultOf(&msg), v); err != nil {
+					t.Fatalf("%T: %v", v, err)
+				}
+				decoded = append(decoded, v)
+			}
+			got, err := json.MarshalIndent(decoded, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", "golden", channel+".json")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("decoded\n%s\ngolden\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestMalformedFrames(t *testing.T) {
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	ws := newTestService(t, srv, nil)
+	received := make(chan *UpdateMsg, 1)
+	ws.SetCallBack(ChannelSpotPublicTrade, NewCallBack(func(msg *UpdateMsg) {
+		if msg.Event == "update" {
+			received <- msg
+		}
+	}))
+	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BTC_USDT"}); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.WaitSubscribed(ChannelSpotPublicTrade, testTimeout) {
+		t.Fatal("not subscribed")
+	}
+
+	for _, raw := range []string{`not json`, `{}`, `[1,2]`, `{"channel":1}`, `{"time":"x","channel":"spot.trades"}`} {
+		srv.Broadcast([]byte(raw))
+	}
+	srv.Push(ChannelSpotPublicTrade, map[string]any{"id": 1, "currency_pair": "BTC_USDT"})
+	select {
+	case msg := <-received:
+		var trade SpotTradeMsg
+		if err := json.Unmarshal(msg.Result, &trade); err != nil || trade.Id != 1 {
+			t.Fatalf("trade %+v, %v", trade, err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("reader stopped by malformed frames")
+	}
+}
+
+func FuzzUpdateMsg(f *testing.F) {
+	for _, frame := range corpus(f) {
+		f.Add(frame)
+	}
+	f.Add([]byte(`{"time":"9999999999999999999","time_ms":-1,"header":{"response_time":"1e400"}}`))
+	f.Add([]byte(`{"id":null,"error":{"code":"1"},"data":{"errs":{}}}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var msg UpdateMsg
+		if json.Unmarshal(data, &msg) != nil {
+			return
+		}
+		msg.GetChannel()
+		msg.Latency(time.Now())
+		if msg.Error != nil {
+			_ = msg.Error.Error()
+		}
+	})
+}
+
+// FuzzDecoders decodes results into every type of decoders, and feeds frames to callbacks decoding them
+func FuzzDecoders(f *testing.F) {
+	for channel, frame := range corpus(f) {
+		var msg UpdateMsg
+		if err := json.Unmarshal(frame, &msg); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(channel, []byte(resultOf(&msg)))
+	}
+
+	f.Fuzz(func(t *testing.T, channel string, result []byte) {
+		for _, news := range decoders {
+			for _, newV := range news {
+				_ = json.Unmarshal(result, newV())
+			}
+		}
+
+		instruments := NewInstruments()
+		calls := []CallBack{
+			NewTradeTape(8).CallBack(),
+			NewPositionTracker(instruments, nil).CallBack(),
+			NewOrderManager(nil).CallBack(),
+			NewLedger(8).CallBack(),
+			NewAccounting(CostFIFO, instruments).CallBack(),
+		}
+		api := &UpdateMsg{Header: ResponseHeader{Channel: channel, Status: "200"}, RequestId: "1"}
+		api.Data.Result = result
+		for _, msg := range []*UpdateMsg{
+			{Channel: channel, Event: "update", Result: result},
+			{Channel: channel, Event: "all", Result: result},
+			api,
+		} {
+			for _, call := range calls {
+				call(msg)
+			}
+		}
+	})
+}
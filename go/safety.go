@@ -0,0 +1,314 @@
+package gatews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gateio/gatews/go/model"
+)
+
+const (
+	DefaultCancelOnDisconnectGrace   = 30 * time.Second
+	DefaultCancelOnDisconnectTimeout = 10 * time.Second
+
+	// cancelAllMaxRetryConn connect attempts of the connection cancelling all orders, within CancelOnDisconnectTimeout
+	cancelAllMaxRetryConn = 3
+)
+
+// CancelAllResult result of cancelling all orders of a market
+type CancelAllResult struct {
+	Market string
+	Err    error
+}
+
+// CancelAllReport results of cancelling all orders of tracked markets, and why
+type CancelAllReport struct {
+	Reason  string
+	Results []CancelAllResult
+}
+
+// Err returns errors of failed markets joined, nil if all succeeded
+func (r CancelAllReport) Err() error {
+	var errs []string
+	for _, res := range r.Results {
+		if res.Err != nil {
+			errs = append(errs, res.Market+": "+res.Err.Error())
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New("cancel all orders failed: " + strings.Join(errs, "; "))
+}
+
+// safetyState state of cancel-all-on-disconnect
+type safetyState struct {
+	mu      sync.Mutex
+	markets map[string]bool
+	// a disconnect is being watched
+	watching bool
+	// orders are cancelled since the disconnect, until reconnected
+	cancelled bool
+}
+
+func (s *safetyState) reconnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cancelled = false
+}
+
+// cancelAllOnce cancels all orders unless they are cancelled since the disconnect
+func (ws *WsService) cancelAllOnce(reason string) CancelAllReport {
+	ws.safety.mu.Lock()
+	if ws.safety.cancelled {
+		ws.safety.mu.Unlock()
+		return CancelAllReport{Reason: reason}
+	}
+	ws.safety.cancelled = true
+	ws.safety.mu.Unlock()
+
+	return ws.CancelAll(reason)
+}
+
+func newSafetyState() *safetyState {
+	return &safetyState{markets: make(map[string]bool)}
+}
+
+// trackMarkets remembers markets of orders placed by payload
+func (ws *WsService) trackMarkets(channel string, payload any) {
+	if channel != ChannelSpotOrderPlace && channel != ChannelFutureOrderPlace && channel != ChannelFutureOrderBatchPlace {
+		return
+	}
+
+	var markets []string
+	switch p := payload.(type) {
+	case *model.Order:
+		markets = append(markets, p.CurrencyPair)
+	case model.Order:
+		markets = append(markets, p.CurrencyPair)
+	case *model.FuturesOrder:
+		markets = append(markets, p.Contract)
+	case model.FuturesOrder:
+		markets = append(markets, p.Contract)
+	case []*model.Order:
+		for _, o := range p {
+			markets = append(markets, o.CurrencyPair)
+		}
+	case []model.Order:
+		for _, o := range p {
+			markets = append(markets, o.CurrencyPair)
+		}
+	case []*model.FuturesOrder:
+		for _, o := range p {
+			markets = append(markets, o.Contract)
+		}
+	case []model.FuturesOrder:
+		for _, o := range p {
+			markets = append(markets, o.Contract)
+		}
+	}
+
+	ws.safety.mu.Lock()
+	defer ws.safety.mu.Unlock()
+
+	for _, m := range markets {
+		if m != "" {
+			ws.safety.markets[m] = true
+		}
+	}
+}
+
+// TrackedMarkets returns markets orders are placed in by this connection, which CancelAll cancels
+func (ws *WsService) TrackedMarkets() []string {
+	ws.safety.mu.Lock()
+	defer ws.safety.mu.Unlock()
+
+	markets := make([]string, 0, len(ws.safety.markets))
+	for m := range ws.safety.markets {
+		markets = append(markets, m)
+	}
+	sort.Strings(markets)
+	return markets
+}
+
+// onDisconnect cancels all orders if the connection isn't restored within the grace period
+func (ws *WsService) onDisconnect() {
+	if !ws.conf.CancelOnDisconnect {
+		return
+	}
+
+	ws.safety.mu.Lock()
+	if ws.safety.watching {
+		ws.safety.mu.Unlock()
+		return
+	}
+	ws.safety.watching = true
+	ws.safety.mu.Unlock()
+
+	grace := ws.conf.CancelOnDisconnectGrace
+	if grace <= 0 {
+		grace = DefaultCancelOnDisconnectGrace
+	}
+
+	go func() {
+		defer func() {
+			ws.safety.mu.Lock()
+			ws.safety.watching = false
+			ws.safety.mu.Unlock()
+		}()
+
+		timer := ws.clock().NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-ws.Ctx.Done():
+			return
+		case <-timer.C():
+		}
+		if ws.getStatus() != connected {
+			ws.cancelAllOnce(fmt.Sprintf("connection not restored in %s", grace))
+		}
+	}()
+}
+
+// CancelAll cancels all orders of tracked markets by spot.order_cancel_cp or futures.order_cancel_cp.
+// The requests are sent over a new connection, so they don't depend on the state of this one.
+// The report is also passed to OnCancelAll if set
+func (ws *WsService) CancelAll(reason string) CancelAllReport {
+	report := CancelAllReport{Reason: reason}
+	markets := ws.TrackedMarkets()
+	if len(markets) > 0 {
+		report.Results = ws.cancelAll(markets)
+	}
+
+	if err := report.Err(); err != nil {
+		ws.log().Error("cancel all orders failed", "reason", reason, "err", err)
+	} else {
+		ws.log().Info("cancelled all orders", "reason", reason, "markets", len(markets))
+	}
+	if ws.conf.OnCancelAll != nil {
+		ws.conf.OnCancelAll(report)
+	}
+	return report
+}
+
+func (ws *WsService) cancelAll(markets []string) []CancelAllResult {
+	results := make([]CancelAllResult, len(markets))
+	fail := func(err error) []CancelAllResult {
+		for i, m := range markets {
+			results[i] = CancelAllResult{Market: m, Err: err}
+		}
+		return results
+	}
+
+	timeout := ws.conf.CancelOnDisconnectTimeout
+	if timeout <= 0 {
+		timeout = DefaultCancelOnDisconnectTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// a recording is replayed to a single connection
+	if _, ok := ws.conf.Transport.(*ReplayTransport); ok {
+		return fail(errReplayStarted)
+	}
+
+	// the fallback connection isn't recorded, measured or traced as this one
+	conf := *ws.conf
+	conf.subscribeMsg = new(sync.Map)
+	if conf.MaxRetryConn > cancelAllMaxRetryConn {
+		conf.MaxRetryConn = cancelAllMaxRetryConn
+	}
+	conf.SkipValidation = true
+	conf.ClientOrderIDs = nil
+	conf.RateLimiter = nil
+	conf.CancelOnDisconnect = false
+	conf.OnCancelAll = nil
+	conf.Recorder = nil
+	conf.Metrics = nil
+	conf.Tracer = nil
+	conf.Latency = nil
+	fallback, err := NewWsService(ctx, ws.Logger, &conf)
+	if err != nil {
+		return fail(err)
+	}
+	defer fallback.Close()
+
+	channel := ChannelSpotOrderCancelCp
+	if ws.conf.App == "futures" {
+		channel = ChannelFutureOrderCancelCp
+	}
+
+	responses := make(chan *UpdateMsg, len(markets))
+	fallback.SetCallBack(channel, func(msg *UpdateMsg) {
+		// the first response is an ack if requested, results have no ack flag
+		if msg.Ack {
+			return
+		}
+		select {
+		case responses <- msg:
+		default:
+		}
+	})
+
+	pending := make(map[string]int, len(markets))
+	for i, m := range markets {
+		results[i].Market = m
+		var payload any = &model.CancelOrderWithCpParam{CurrencyPair: m}
+		if channel == ChannelFutureOrderCancelCp {
+			payload = &model.CancelFuturesCpOrder{Contract: m}
+		}
+		if err := fallback.APIRequest(channel, payload, map[string]any{"req_id": m}); err != nil {
+			results[i].Err = err
+			continue
+		}
+		pending[m] = i
+	}
+
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			for _, i := range pending {
+				results[i].Err = fmt.Errorf("no response: %w", ctx.Err())
+			}
+			return results
+		case msg := <-responses:
+			i, ok := pending[msg.RequestId]
+			if !ok {
+				continue
+			}
+			delete(pending, msg.RequestId)
+			if msg.Data.Errs != nil {
+				results[i].Err = fmt.Errorf("%s: %s", msg.Data.Errs.Label, msg.Data.Errs.Message)
+			}
+		}
+	}
+	return results
+}
+
+// Close closes the connection and stops its goroutines. If CancelOnDisconnect is set, orders of
+// tracked markets are cancelled first unless cancelled since a disconnect, failures are returned
+func (ws *WsService) Close() error {
+	var err error
+	if ws.conf.CancelOnDisconnect {
+		err = ws.cancelAllOnce("close").Err()
+	}
+	if ws.cancel != nil {
+		ws.cancel()
+	}
+	ws.endOutstandingRequests(errClosed)
+	if e := ws.GetConn().Close(); err == nil {
+		err = e
+	}
+	if ws.conf.Recorder != nil {
+		if e := ws.conf.Recorder.Flush(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
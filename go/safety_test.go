@@ -0,0 +1,160 @@
+package gatews
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/gatewstest"
+	"github.com/gateio/gatews/go/model"
+	"github.com/gorilla/websocket"
+)
+
+// newCancelCpServer replies to order_cancel_cp requests, failing for ETH_USDT
+func newCancelCpServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var req struct {
+				Channel string `json:"channel"`
+				Payload APIReq `json:"payload"`
+			}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			if !strings.HasSuffix(req.Channel, "order_cancel_cp") {
+				continue
+			}
+			data := `{"result":[]}`
+			if req.Payload.ReqId == "ETH_USDT" {
+				data = `{"errs":{"label":"INVALID_CURRENCY_PAIR","message":"invalid"}}`
+			}
+			resp := fmt.Sprintf(`{"request_id":%q,"header":{"channel":%q,"status":"200"},"data":%s}`, req.Payload.ReqId, req.Channel, data)
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(resp)); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestCancelAllOnClose(t *testing.T) {
+	srv := newCancelCpServer(t)
+	defer srv.Close()
+
+	var reported CancelAllReport
+	var recording bytes.Buffer
+	ws, err := NewWsService(context.Background(), nil, NewConnConfFromOption(&ConfOptions{
+		URL:                       "ws" + strings.TrimPrefix(srv.URL, "http"),
+		Key:                       "key",
+		Secret:                    "secret",
+		CancelOnDisconnect:        true,
+		CancelOnDisconnectTimeout: 5 * time.Second,
+		OnCancelAll:               func(r CancelAllReport) { reported = r },
+		Recorder:                  NewRecorder(&recording),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws.trackMarkets(ChannelSpotOrderPlace, []model.Order{{CurrencyPair: "BTC_USDT"}, {CurrencyPair: "ETH_USDT"}})
+	if markets := ws.TrackedMarkets(); len(markets) != 2 {
+		t.Fatalf("unexpected markets %v", markets)
+	}
+
+	if err := ws.Close(); err == nil || !strings.Contains(err.Error(), "INVALID_CURRENCY_PAIR") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if reported.Reason != "close" || len(reported.Results) != 2 || reported.Results[0].Err != nil || reported.Results[1].Err == nil {
+		t.Fatalf("unexpected report %+v", reported)
+	}
+	if ws.Ctx.Err() == nil {
+		t.Fatal("context is not canceled by close")
+	}
+	// requests of the fallback connection are not in the recording of this one
+	if strings.Contains(recording.String(), "order_cancel_cp") {
+		t.Fatalf("fallback recorded:\n%s", recording.String())
+	}
+}
+
+func TestCancelAllNetworkDown(t *testing.T) {
+	srv := gatewstest.NewServer("key", "secret")
+	defer srv.Close()
+
+	reported := make(chan CancelAllReport, 1)
+	ws := newTestService(t, srv, &ConfOptions{
+		Key:                       "key",
+		Secret:                    "secret",
+		MaxRetryConn:              math.MaxInt64,
+		CancelOnDisconnectTimeout: 200 * time.Millisecond,
+		OnCancelAll:               func(r CancelAllReport) { reported <- r },
+	})
+	ws.trackMarkets(ChannelSpotOrderPlace, []model.Order{{CurrencyPair: "BTC_USDT"}})
+	srv.RejectConnections(true)
+
+	done := make(chan CancelAllReport, 1)
+	go func() { done <- ws.CancelAll("test") }()
+	select {
+	case report := <-done:
+		if len(report.Results) != 1 || report.Err() == nil {
+			t.Fatalf("unexpected report %+v", report)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("cancel all doesn't return")
+	}
+	select {
+	case <-reported:
+	default:
+		t.Fatal("OnCancelAll not called")
+	}
+}
+
+func TestCancelAllOncePerDisconnect(t *testing.T) {
+	srv := gatewstest.NewServer("key", "secret")
+	defer srv.Close()
+
+	reported := make(chan CancelAllReport, 4)
+	ws, err := NewWsService(context.Background(), nil, NewConnConfFromOption(&ConfOptions{
+		URL:                       srv.URL,
+		Key:                       "key",
+		Secret:                    "secret",
+		MaxRetryConn:              1,
+		CancelOnDisconnect:        true,
+		CancelOnDisconnectGrace:   time.Second,
+		CancelOnDisconnectTimeout: 100 * time.Millisecond,
+		OnCancelAll:               func(r CancelAllReport) { reported <- r },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.trackMarkets(ChannelSpotOrderPlace, []model.Order{{CurrencyPair: "BTC_USDT"}})
+	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BTC_USDT"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// reconnect fails before the grace period ends, which must not cancel again
+	srv.RejectConnections(true)
+	srv.Disconnect()
+	select {
+	case r := <-reported:
+		if r.Reason != "reconnect failed" {
+			t.Fatalf("unexpected report %+v", r)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("orders not cancelled")
+	}
+	time.Sleep(time.Second)
+	ws.Close()
+	if len(reported) != 0 {
+		t.Fatalf("cancelled %d more times", len(reported))
+	}
+}
@@ -0,0 +1,259 @@
+package gatews
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gateio/gatews/go/model"
+)
+
+const DefaultTapeCapacity = 10000
+
+// TapeTrade is a public trade from spot.trades or futures.trades in a common shape
+type TapeTrade struct {
+	Id     int64
+	Market string
+	Time   time.Time
+	Price  float64
+	// Amount is always positive, in base currency for spot and contracts for futures
+	Amount float64
+	// Side is the taker side
+	Side model.Side
+}
+
+// TapeStats rolling statistics of a market over a time window
+type TapeStats struct {
+	Market     string
+	Window     time.Duration
+	Count      int
+	Volume     float64
+	BuyVolume  float64
+	SellVolume float64
+	// Turnover sum of price * amount
+	Turnover float64
+	VWAP     float64
+	High     float64
+	Low      float64
+	Last     float64
+}
+
+// TradeTape keeps a bounded history of public trades per market
+// and deduplicates them by trade id, e.g. when trades are pushed again after reconnecting.
+// Trade ids increase per market, trades with ids not above the ones evicted are rejected
+type TradeTape struct {
+	mu       sync.RWMutex
+	capacity int
+	markets  map[string]*marketTape
+	now      func() time.Time
+}
+
+type marketTape struct {
+	trades []TapeTrade // ring buffer
+	start  int
+	ids    *recentIDs
+}
+
+// NewTradeTape capacity is the max trades kept per market, DefaultTapeCapacity is used if capacity <= 0
+func NewTradeTape(capacity int) *TradeTape {
+	if capacity <= 0 {
+		capacity = DefaultTapeCapacity
+	}
+	return &TradeTape{
+		capacity: capacity,
+		markets:  make(map[string]*marketTape),
+		now:      time.Now,
+	}
+}
+
+// Add adds a trade to the tape, returns false if the trade has been seen before
+func (t *TradeTape) Add(trade TapeTrade) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m, ok := t.markets[trade.Market]
+	if !ok {
+		m = &marketTape{ids: newRecentIDs(t.capacity)}
+		t.markets[trade.Market] = m
+	}
+
+	if !m.ids.add(strconv.FormatInt(trade.Id, 10)) {
+		return false
+	}
+
+	if len(m.trades) < t.capacity {
+		m.trades = append(m.trades, trade)
+		return true
+	}
+
+	// overwrite the oldest one
+	m.trades[m.start] = trade
+	m.start = (m.start + 1) % len(m.trades)
+	return true
+}
+
+// AddSpotTrade adds a trade pushed by spot.trades
+func (t *TradeTape) AddSpotTrade(trade *SpotTradeMsg) bool {
+	return t.Add(TapeTrade{
+		Id:     int64(trade.Id),
+		Market: trade.CurrencyPair,
+		Time:   tradeTime(trade.CreateTimeMs, trade.CreateTime),
+		Price:  parseFloat(trade.Price),
+		Amount: parseFloat(trade.Amount),
+		Side:   trade.Side,
+	})
+}
+
+// AddFuturesTrade adds a trade pushed by futures.trades, negative size means a sell
+func (t *TradeTape) AddFuturesTrade(trade *FuturesTrade) bool {
+	side, size := model.SideBuy, trade.Size
+	if size < 0 {
+		side, size = model.SideSell, -size
+	}
+
+	return t.Add(TapeTrade{
+		Id:     trade.Id,
+		Market: trade.Contract,
+		Time:   tradeTime(trade.CreateTimeMs, trade.CreateTime),
+		Price:  parseFloat(trade.Price),
+		Amount: float64(size),
+		Side:   side,
+	})
+}
+
+// CallBack returns a callback which feeds spot.trades and futures.trades updates into the tape
+func (t *TradeTape) CallBack() CallBack {
+	return NewCallBack(func(msg *UpdateMsg) {
+		if msg.Event != model.EventUpdate {
+			return
+		}
+
+		switch msg.GetChannel() {
+		case ChannelSpotPublicTrade:
+			var trade SpotTradeMsg
+			if err := json.Unmarshal(msg.Result, &trade); err != nil {
+				return
+			}
+			t.AddSpotTrade(&trade)
+		case ChannelFutureTrade:
+			var trades []FuturesTrade
+			if err := json.Unmarshal(msg.Result, &trades); err != nil {
+				return
+			}
+			for i := range trades {
+				t.AddFuturesTrade(&trades[i])
+			}
+		}
+	})
+}
+
+// Markets returns markets which have trades on the tape
+func (t *TradeTape) Markets() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	markets := make([]string, 0, len(t.markets))
+	for market := range t.markets {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+	return markets
+}
+
+// Trades returns at most n recent trades of market, oldest first. n <= 0 returns all
+func (t *TradeTape) Trades(market string, n int) []TapeTrade {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	m, ok := t.markets[market]
+	if !ok {
+		return nil
+	}
+
+	trades := m.ordered()
+	if n > 0 && n < len(trades) {
+		trades = trades[len(trades)-n:]
+	}
+	return trades
+}
+
+// Stats returns statistics of trades of market within window up to now.
+// window <= 0 means all trades on the tape
+func (t *TradeTape) Stats(market string, window time.Duration) TapeStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	stats := TapeStats{Market: market, Window: window}
+	m, ok := t.markets[market]
+	if !ok {
+		return stats
+	}
+
+	var since time.Time
+	if window > 0 {
+		since = t.now().Add(-window)
+	}
+
+	var last time.Time
+	for _, trade := range m.trades {
+		if trade.Time.Before(since) {
+			continue
+		}
+
+		stats.Count++
+		stats.Volume += trade.Amount
+		stats.Turnover += trade.Price * trade.Amount
+		if trade.Side == model.SideSell {
+			stats.SellVolume += trade.Amount
+		} else {
+			stats.BuyVolume += trade.Amount
+		}
+		if stats.High == 0 || trade.Price > stats.High {
+			stats.High = trade.Price
+		}
+		if stats.Low == 0 || trade.Price < stats.Low {
+			stats.Low = trade.Price
+		}
+		if !trade.Time.Before(last) {
+			last = trade.Time
+			stats.Last = trade.Price
+		}
+	}
+
+	if stats.Volume > 0 {
+		stats.VWAP = stats.Turnover / stats.Volume
+	}
+	return stats
+}
+
+// Reset clears trades of market, or all markets if market is empty
+func (t *TradeTape) Reset(market string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if market == "" {
+		t.markets = make(map[string]*marketTape)
+		return
+	}
+	delete(t.markets, market)
+}
+
+func (m *marketTape) ordered() []TapeTrade {
+	trades := make([]TapeTrade, 0, len(m.trades))
+	trades = append(trades, m.trades[m.start:]...)
+	return append(trades, m.trades[:m.start]...)
+}
+
+func tradeTime(ms, sec model.Timestamp) time.Time {
+	if ms.IsZero() {
+		return sec.Time()
+	}
+	return ms.Time()
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
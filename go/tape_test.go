@@ -0,0 +1,80 @@
+package gatews
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/model"
+)
+
+func TestTradeTapeStats(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tape := NewTradeTape(10)
+	tape.now = func() time.Time { return now }
+
+	tape.AddSpotTrade(&SpotTradeMsg{Id: 1, CurrencyPair: "BTC_USDT", CreateTime: model.NewTimestamp(now.Add(-2 * time.Minute)), Side: "buy", Price: "100", Amount: "1"})
+	tape.AddSpotTrade(&SpotTradeMsg{Id: 2, CurrencyPair: "BTC_USDT", CreateTimeMs: model.TimestampFromMilli(1699999990000), Side: "sell", Price: "110", Amount: "2"})
+	tape.AddSpotTrade(&SpotTradeMsg{Id: 3, CurrencyPair: "BTC_USDT", CreateTime: model.NewTimestamp(now), Side: "buy", Price: "120", Amount: "1"})
+
+	stats := tape.Stats("BTC_USDT", time.Minute)
+	if stats.Count != 2 || stats.Volume != 3 || stats.BuyVolume != 1 || stats.SellVolume != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if math.Abs(stats.VWAP-340.0/3) > 1e-9 {
+		t.Fatalf("vwap = %v", stats.VWAP)
+	}
+	if stats.High != 120 || stats.Low != 110 || stats.Last != 120 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	all := tape.Stats("BTC_USDT", 0)
+	if all.Count != 3 || all.VWAP != 110 {
+		t.Fatalf("unexpected stats: %+v", all)
+	}
+}
+
+func TestTradeTapeDedupAndCapacity(t *testing.T) {
+	tape := NewTradeTape(2)
+
+	for _, id := range []int64{1, 2, 2, 3, 1} {
+		tape.AddFuturesTrade(&FuturesTrade{Id: id, Contract: "BTC_USDT", CreateTimeMs: model.TimestampFromMilli(id), Size: -id, Price: "10"})
+	}
+
+	// 1 is evicted by 3 and rejected when replayed
+	trades := tape.Trades("BTC_USDT", 0)
+	if len(trades) != 2 || trades[0].Id != 2 || trades[1].Id != 3 {
+		t.Fatalf("unexpected trades: %+v", trades)
+	}
+	if trades[1].Side != "sell" || trades[1].Amount != 3 {
+		t.Fatalf("unexpected trade: %+v", trades[1])
+	}
+
+	if tape.AddFuturesTrade(&FuturesTrade{Id: 3, Contract: "BTC_USDT"}) {
+		t.Fatal("duplicated trade added")
+	}
+}
+
+func TestTradeTapeCallBack(t *testing.T) {
+	tape := NewTradeTape(0)
+	call := tape.CallBack()
+
+	call(&UpdateMsg{
+		Channel: ChannelFutureTrade,
+		Event:   "update",
+		Result:  json.RawMessage(`[{"id":1,"create_time_ms":1700000000000,"contract":"BTC_USD","size":2,"price":"100"},{"id":2,"create_time_ms":1700000000001,"contract":"BTC_USD","size":-1,"price":"101"}]`),
+	})
+	call(&UpdateMsg{
+		Channel: ChannelSpotPublicTrade,
+		Event:   "update",
+		Result:  json.RawMessage(`{"id":7,"create_time":1700000000,"create_time_ms":"1700000000000.0","side":"buy","currency_pair":"ETH_USDT","amount":"1","price":"2000"}`),
+	})
+
+	if markets := tape.Markets(); len(markets) != 2 {
+		t.Fatalf("unexpected markets: %v", markets)
+	}
+	if stats := tape.Stats("BTC_USD", 0); stats.Count != 2 || stats.BuyVolume != 2 || stats.SellVolume != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,60 @@
+package gatews
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	EventLogin     = "login"
+	EventReconnect = "reconnect"
+)
+
+var (
+	errClosed         = errors.New("connection closed")
+	errConnectionLost = errors.New("connection lost")
+)
+
+// Tracer traces api requests and connection events, implementations must be safe for concurrent use.
+// The oteltrace subpackage implements it with OpenTelemetry spans
+type Tracer interface {
+	// StartRequest starts tracing an api request of channel with req_id,
+	// ctx is the one passed to APIRequestContext
+	StartRequest(ctx context.Context, channel, reqID string) RequestSpan
+	// Event records a connection event, EventLogin or EventReconnect, err is nil if it succeeded
+	Event(ctx context.Context, name string, err error)
+}
+
+// RequestSpan trace of an api request
+type RequestSpan interface {
+	// End is called once with the response, or err if the request failed or got no response
+	End(resp *UpdateMsg, err error)
+}
+
+type nopTracer struct{}
+
+func (nopTracer) StartRequest(context.Context, string, string) RequestSpan { return nopSpan{} }
+func (nopTracer) Event(context.Context, string, error)                     {}
+
+type nopSpan struct{}
+
+func (nopSpan) End(*UpdateMsg, error) {}
+
+func (ws *WsService) tracer() Tracer {
+	if ws.conf != nil && ws.conf.Tracer != nil {
+		return ws.conf.Tracer
+	}
+	return nopTracer{}
+}
+
+// endOutstandingRequests ends spans of requests without responses, which never come once the connection
+// is closed or lost
+func (ws *WsService) endOutstandingRequests(err error) {
+	if ws.apiRequests == nil {
+		return
+	}
+	for _, req := range ws.apiRequests.drain() {
+		req.span.End(nil, err)
+	}
+	ws.metrics().OutstandingRequests(0)
+}
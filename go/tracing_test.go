@@ -0,0 +1,95 @@
+package gatews
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/gatewstest"
+	"github.com/gateio/gatews/go/model"
+)
+
+type recordSpan struct {
+	ended int
+	resp  *UpdateMsg
+	err   error
+}
+
+func (s *recordSpan) End(resp *UpdateMsg, err error) {
+	s.ended++
+	s.resp, s.err = resp, err
+}
+
+// chanTracer sends errors of ended request spans to ended
+type chanTracer struct{ ended chan error }
+
+func (tr chanTracer) StartRequest(context.Context, string, string) RequestSpan { return tr }
+func (tr chanTracer) Event(context.Context, string, error)                     {}
+func (tr chanTracer) End(_ *UpdateMsg, err error)                              { tr.ended <- err }
+
+func TestRequestSpans(t *testing.T) {
+	ws := &WsService{conf: &ConnConf{}, apiRequests: newAPIRequests()}
+	answered, lost := &recordSpan{}, &recordSpan{}
+	ws.observeAPIRequest(ChannelFutureOrderPlace, "1", answered)
+	ws.observeAPIRequest(ChannelFutureOrderPlace, "2", lost)
+
+	var msg UpdateMsg
+	raw := `{"request_id":"1","header":{"channel":"futures.order_place","status":"400","response_time":"1700000000123"},"data":{"errs":{"label":"INVALID_PARAM_VALUE","message":"size"}}}`
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatal(err)
+	}
+	ws.observeAPIResponse(&msg)
+	if answered.ended != 1 || answered.resp != &msg || answered.err != nil {
+		t.Fatalf("answered span %+v", answered)
+	}
+	if lost.ended != 0 {
+		t.Fatal("lost span ended by another response")
+	}
+
+	ws.endOutstandingRequests(errClosed)
+	if lost.ended != 1 || !errors.Is(lost.err, errClosed) {
+		t.Fatalf("lost span %+v", lost)
+	}
+	if answered.ended != 1 {
+		t.Fatal("answered span ended twice")
+	}
+}
+
+func TestRequestSpansConnectionLost(t *testing.T) {
+	srv := gatewstest.NewServer("KEY", "SECRET")
+	defer srv.Close()
+	received, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+	srv.Handle(ChannelSpotOrderPlace, func(req gatewstest.APIRequest) gatewstest.APIResult {
+		received <- struct{}{}
+		<-release
+		return gatewstest.APIResult{Result: map[string]any{"id": "1"}}
+	})
+	tracer := chanTracer{ended: make(chan error, 4)}
+	ws := newTestService(t, srv, &ConfOptions{Key: "KEY", Secret: "SECRET", MaxRetryConn: 10, Tracer: tracer})
+
+	order := &model.Order{CurrencyPair: "BTC_USDT", Side: model.SideBuy, Type: "limit", Price: "30000", Amount: "0.001"}
+	if err := ws.APIRequest(ChannelSpotOrderPlace, order, map[string]any{"req_id": "r1"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(testTimeout):
+		t.Fatal("request not received")
+	}
+
+	// the response never comes on the new connection
+	srv.Disconnect()
+	for {
+		select {
+		case err := <-tracer.ended:
+			if errors.Is(err, errConnectionLost) {
+				return
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("span of the request not ended")
+		}
+	}
+}
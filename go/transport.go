@@ -0,0 +1,79 @@
+package gatews
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// message types of Conn, as defined by RFC 6455
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+)
+
+// Conn websocket connection used by WsService, implemented by *websocket.Conn of gorilla/websocket.
+// ReadMessage is called by one goroutine, and WriteMessage by one goroutine at a time
+type Conn interface {
+	ReadMessage() (messageType int, data []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+// Transport dials connections of WsService, set by ConfOptions.Transport.
+// GorillaTransport is used if not set
+type Transport interface {
+	Dial(ctx context.Context, url string) (Conn, error)
+}
+
+// GorillaTransport dials by gorilla/websocket
+type GorillaTransport struct {
+	// Dialer websocket.DefaultDialer is used if nil
+	Dialer *websocket.Dialer
+}
+
+func (t *GorillaTransport) Dial(ctx context.Context, url string) (Conn, error) {
+	dialer := t.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+	c, _, err := dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// transport returns the Transport of conf, or a GorillaTransport verifying TLS unless SkipTlsVerify
+func (conf *ConnConf) transport() Transport {
+	if conf.Transport != nil {
+		return conf.Transport
+	}
+	dialer := *websocket.DefaultDialer
+	if conf.SkipTlsVerify {
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &GorillaTransport{Dialer: &dialer}
+}
+
+type activeConn struct {
+	conn   Conn
+	client *websocket.Conn
+}
+
+// setConn replaces the connection, Client is set if it's dialed by gorilla/websocket
+func (ws *WsService) setConn(c Conn) {
+	client, _ := c.(*websocket.Conn)
+	ws.conn.Store(activeConn{conn: c, client: client})
+	ws.Client = client
+}
+
+// GetConn returns the connection of any Transport
+func (ws *WsService) GetConn() Conn {
+	active, _ := ws.conn.Load().(activeConn)
+	return active.conn
+}
@@ -0,0 +1,25 @@
+package gatews
+
+import (
+	"testing"
+
+	"github.com/gateio/gatews/go/gatewstest"
+	"github.com/gorilla/websocket"
+)
+
+func TestGorillaTransport(t *testing.T) {
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	ws := newTestService(t, srv, &ConfOptions{SkipTlsVerify: true})
+
+	if ws.GetConn() == nil || ws.GetConnection() == nil || ws.GetConn() != Conn(ws.GetConnection()) {
+		t.Fatal("connection of the default transport is not set")
+	}
+	if websocket.DefaultDialer.TLSClientConfig != nil {
+		t.Fatal("default dialer is changed")
+	}
+	tr := ws.conf.transport().(*GorillaTransport)
+	if !tr.Dialer.TLSClientConfig.InsecureSkipVerify {
+		t.Fatal("tls is verified")
+	}
+}
@@ -0,0 +1,207 @@
+package gatews
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultWriteTimeout = 10 * time.Second
+
+// WritePriority priority of outgoing messages, lower values are written first
+type WritePriority int
+
+const (
+	PriorityCancel WritePriority = iota
+	PriorityPlace
+	PrioritySubscribe
+	PriorityPing
+
+	numPriorities = int(PriorityPing) + 1
+)
+
+func (p WritePriority) String() string {
+	switch p {
+	case PriorityCancel:
+		return "cancel"
+	case PriorityPlace:
+		return "place"
+	case PrioritySubscribe:
+		return "subscribe"
+	case PriorityPing:
+		return "ping"
+	}
+	return "unknown"
+}
+
+// channelPriority priority of messages sent to channel
+func channelPriority(channel string) WritePriority {
+	switch channel {
+	case ChannelSpotOrderCancel, ChannelSpotOrderCancelIds, ChannelSpotOrderCancelCp,
+		ChannelFutureOrderCancel, ChannelFutureOrderCancelCp:
+		return PriorityCancel
+	case ChannelSpotOrderPlace, ChannelSpotOrderAmend, ChannelSpotLogin,
+		ChannelFutureOrderPlace, ChannelFutureOrderBatchPlace, ChannelFutureOrderAmend, ChannelFutureLogin:
+		return PriorityPlace
+	}
+	if strings.HasSuffix(channel, ".ping") {
+		return PriorityPing
+	}
+	return PrioritySubscribe
+}
+
+// WriteStats statistics of the outgoing queue
+type WriteStats struct {
+	// Depth messages waiting by priority
+	Depth  [numPriorities]int
+	Writes uint64
+	Errors uint64
+	// latency from enqueuing to written
+	LastLatency time.Duration
+	MaxLatency  time.Duration
+	AvgLatency  time.Duration
+}
+
+type writeReq struct {
+	data     []byte
+	priority WritePriority
+	enqueued time.Time
+	done     chan error
+}
+
+// writeQueue outgoing messages written by one goroutine in order of priority, FIFO within a priority
+type writeQueue struct {
+	mu     sync.Mutex
+	queues [numPriorities][]*writeReq
+	signal chan struct{}
+
+	stats        WriteStats
+	totalLatency time.Duration
+}
+
+func newWriteQueue() *writeQueue {
+	return &writeQueue{signal: make(chan struct{}, 1)}
+}
+
+func (q *writeQueue) push(req *writeReq) {
+	q.mu.Lock()
+	q.queues[req.priority] = append(q.queues[req.priority], req)
+	q.mu.Unlock()
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (q *writeQueue) pop() *writeReq {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for p := range q.queues {
+		if len(q.queues[p]) > 0 {
+			req := q.queues[p][0]
+			q.queues[p][0] = nil
+			q.queues[p] = q.queues[p][1:]
+			return req
+		}
+	}
+	return nil
+}
+
+func (q *writeQueue) record(req *writeReq, err error) {
+	latency := time.Since(req.enqueued)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.stats.Writes++
+	if err != nil {
+		q.stats.Errors++
+	}
+	q.stats.LastLatency = latency
+	if latency > q.stats.MaxLatency {
+		q.stats.MaxLatency = latency
+	}
+	q.totalLatency += latency
+	q.stats.AvgLatency = q.totalLatency / time.Duration(q.stats.Writes)
+}
+
+func (q *writeQueue) snapshot() WriteStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := q.stats
+	for p := range q.queues {
+		stats.Depth[p] = len(q.queues[p])
+	}
+	return stats
+}
+
+// write queues data and waits until it is written
+func (ws *WsService) write(priority WritePriority, data []byte) error {
+	if ws.writes == nil {
+		return ws.writeMessage(data)
+	}
+
+	req := &writeReq{data: data, priority: priority, enqueued: time.Now(), done: make(chan error, 1)}
+	ws.writes.push(req)
+
+	select {
+	case err := <-req.done:
+		return err
+	case <-ws.Ctx.Done():
+		return ws.Ctx.Err()
+	}
+}
+
+func (ws *WsService) writeMessage(data []byte) error {
+	timeout := ws.conf.WriteTimeout
+	if timeout <= 0 {
+		timeout = DefaultWriteTimeout
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	// the connection may be replaced by reconnect meanwhile, writes fail on the closed one
+	conn := ws.GetConn()
+	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	if err := conn.WriteMessage(TextMessage, data); err != nil {
+		return err
+	}
+	if ws.conf.Recorder != nil {
+		ws.conf.Recorder.Record(FrameOut, time.Now(), data)
+	}
+	return nil
+}
+
+// writeLoop writes queued messages until the context is done
+func (ws *WsService) writeLoop() {
+	for {
+		select {
+		case <-ws.Ctx.Done():
+			for req := ws.writes.pop(); req != nil; req = ws.writes.pop() {
+				req.done <- ws.Ctx.Err()
+			}
+			return
+		case <-ws.writes.signal:
+		}
+
+		for req := ws.writes.pop(); req != nil; req = ws.writes.pop() {
+			err := ws.writeMessage(req.data)
+			ws.writes.record(req, err)
+			req.done <- err
+		}
+	}
+}
+
+// WriteStats returns statistics of the outgoing queue
+func (ws *WsService) WriteStats() WriteStats {
+	if ws.writes == nil {
+		return WriteStats{}
+	}
+	return ws.writes.snapshot()
+}
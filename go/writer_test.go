@@ -0,0 +1,110 @@
+package gatews
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateio/gatews/go/gatewstest"
+	"github.com/gorilla/websocket"
+)
+
+func TestWriteQueuePriority(t *testing.T) {
+	received := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ws := &WsService{mu: new(sync.Mutex), Ctx: ctx, conf: &ConnConf{}, writes: newWriteQueue()}
+	ws.setConn(conn)
+
+	// queue before the writer starts so that all are pending together
+	var wg sync.WaitGroup
+	for _, m := range []struct {
+		priority WritePriority
+		data     string
+	}{{PriorityPing, "ping"}, {PrioritySubscribe, "subscribe"}, {PriorityPlace, "place"}, {PriorityCancel, "cancel"}} {
+		m := m
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := ws.write(m.priority, []byte(m.data)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	for {
+		if stats := ws.WriteStats(); stats.Depth[PriorityCancel]+stats.Depth[PriorityPlace]+stats.Depth[PrioritySubscribe]+stats.Depth[PriorityPing] == 4 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	go ws.writeLoop()
+	wg.Wait()
+
+	for _, want := range []string{"cancel", "place", "subscribe", "ping"} {
+		if got := <-received; got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+	if stats := ws.WriteStats(); stats.Writes != 4 || stats.Errors != 0 || stats.MaxLatency <= 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if channelPriority(ChannelFutureOrderCancel) != PriorityCancel || channelPriority("spot.ping") != PriorityPing {
+		t.Fatal("unexpected channel priority")
+	}
+}
+
+func TestWriteDuringReconnect(t *testing.T) {
+	srv := gatewstest.NewServer("", "")
+	defer srv.Close()
+	ws := newTestService(t, srv, &ConfOptions{MaxRetryConn: 10})
+	if err := ws.Subscribe(ChannelSpotPublicTrade, []string{"BTC_USDT"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				ws.write(PriorityPing, []byte(`{"channel":"spot.ping"}`))
+			}
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		srv.Disconnect()
+		time.Sleep(50 * time.Millisecond)
+	}
+	close(stop)
+	wg.Wait()
+}